@@ -0,0 +1,35 @@
+package main
+
+import (
+	"github.com/go-pg/pg/orm"
+	migrations "github.com/robinjoseph08/go-pg-migrations"
+)
+
+func init() {
+	up := func(db orm.DB) error {
+		_, err := db.Exec(`
+			CREATE TABLE cluster_statuses
+			(
+				id                 BIGSERIAL PRIMARY KEY,
+				cluster_id         TEXT NOT NULL REFERENCES clusters (id) ON DELETE CASCADE,
+				status             TEXT NOT NULL,
+				latency_ms         BIGINT NOT NULL DEFAULT 0,
+				kubernetes_version TEXT,
+				error              TEXT,
+				last_seen          TIMESTAMPTZ,
+				date_checked       TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+			);
+			CREATE INDEX cluster_statuses_cluster_id_date_checked_idx ON cluster_statuses (cluster_id, date_checked DESC);
+		`)
+		return err
+	}
+
+	down := func(db orm.DB) error {
+		_, err := db.Exec("DROP TABLE cluster_statuses")
+		return err
+	}
+
+	opts := migrations.MigrationOptions{}
+
+	migrations.Register("20190715103000_create_cluster_statuses_table", up, down, opts)
+}
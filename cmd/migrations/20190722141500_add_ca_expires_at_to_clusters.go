@@ -0,0 +1,48 @@
+package main
+
+import (
+	"github.com/go-pg/pg/orm"
+	"github.com/lob/pharos/pkg/util/certificate"
+	migrations "github.com/robinjoseph08/go-pg-migrations"
+)
+
+func init() {
+	up := func(db orm.DB) error {
+		_, err := db.Exec("ALTER TABLE clusters ADD COLUMN ca_expires_at TIMESTAMPTZ")
+		if err != nil {
+			return err
+		}
+
+		// Backfill the expiry of existing clusters. Clusters whose authority data
+		// can't be parsed are left without an expiry.
+		var clusters []struct {
+			ID                   string
+			ClusterAuthorityData string
+		}
+		_, err = db.Query(&clusters, "SELECT id, cluster_authority_data FROM clusters")
+		if err != nil {
+			return err
+		}
+
+		for _, cluster := range clusters {
+			expiry, err := certificate.EarliestExpiry(cluster.ClusterAuthorityData)
+			if err != nil {
+				continue
+			}
+			_, err = db.Exec("UPDATE clusters SET ca_expires_at = ? WHERE id = ?", expiry, cluster.ID)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	down := func(db orm.DB) error {
+		_, err := db.Exec("ALTER TABLE clusters DROP COLUMN ca_expires_at")
+		return err
+	}
+
+	opts := migrations.MigrationOptions{}
+
+	migrations.Register("20190722141500_add_ca_expires_at_to_clusters", up, down, opts)
+}
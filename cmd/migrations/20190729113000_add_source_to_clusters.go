@@ -0,0 +1,34 @@
+package main
+
+import (
+	"github.com/go-pg/pg/orm"
+	migrations "github.com/robinjoseph08/go-pg-migrations"
+)
+
+func init() {
+	up := func(db orm.DB) error {
+		_, err := db.Exec(`
+			ALTER TABLE clusters
+				ADD COLUMN source             TEXT NOT NULL DEFAULT 'manual',
+				ADD COLUMN source_ref         TEXT,
+				ADD COLUMN kubernetes_version TEXT,
+				ADD COLUMN missing            BOOLEAN NOT NULL DEFAULT false
+		`)
+		return err
+	}
+
+	down := func(db orm.DB) error {
+		_, err := db.Exec(`
+			ALTER TABLE clusters
+				DROP COLUMN source,
+				DROP COLUMN source_ref,
+				DROP COLUMN kubernetes_version,
+				DROP COLUMN missing
+		`)
+		return err
+	}
+
+	opts := migrations.MigrationOptions{}
+
+	migrations.Register("20190729113000_add_source_to_clusters", up, down, opts)
+}
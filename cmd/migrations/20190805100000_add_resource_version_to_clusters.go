@@ -0,0 +1,50 @@
+package main
+
+import (
+	"github.com/go-pg/pg/orm"
+	migrations "github.com/robinjoseph08/go-pg-migrations"
+)
+
+func init() {
+	up := func(db orm.DB) error {
+		// Every insert and update of a cluster takes the next value of the
+		// sequence, so watchers can ask for the clusters changed since the last
+		// version they saw.
+		_, err := db.Exec(`
+			CREATE SEQUENCE clusters_resource_version_seq;
+
+			ALTER TABLE clusters
+				ADD COLUMN resource_version BIGINT NOT NULL DEFAULT nextval('clusters_resource_version_seq');
+
+			CREATE INDEX clusters_resource_version_idx ON clusters (resource_version);
+
+			CREATE FUNCTION set_clusters_resource_version() RETURNS TRIGGER AS $$
+			BEGIN
+				NEW.resource_version := nextval('clusters_resource_version_seq');
+				RETURN NEW;
+			END;
+			$$ LANGUAGE plpgsql;
+
+			CREATE TRIGGER clusters_resource_version
+				BEFORE UPDATE ON clusters
+				FOR EACH ROW
+				WHEN (OLD.* IS DISTINCT FROM NEW.*)
+				EXECUTE PROCEDURE set_clusters_resource_version();
+		`)
+		return err
+	}
+
+	down := func(db orm.DB) error {
+		_, err := db.Exec(`
+			DROP TRIGGER clusters_resource_version ON clusters;
+			DROP FUNCTION set_clusters_resource_version();
+			ALTER TABLE clusters DROP COLUMN resource_version;
+			DROP SEQUENCE clusters_resource_version_seq;
+		`)
+		return err
+	}
+
+	opts := migrations.MigrationOptions{}
+
+	migrations.Register("20190805100000_add_resource_version_to_clusters", up, down, opts)
+}
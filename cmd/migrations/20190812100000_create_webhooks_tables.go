@@ -0,0 +1,61 @@
+package main
+
+import (
+	"github.com/go-pg/pg/orm"
+	migrations "github.com/robinjoseph08/go-pg-migrations"
+)
+
+func init() {
+	up := func(db orm.DB) error {
+		_, err := db.Exec(`
+			CREATE TABLE webhooks
+			(
+				id           BIGSERIAL PRIMARY KEY,
+				url          TEXT NOT NULL,
+				events       TEXT[] NOT NULL,
+				secret       TEXT NOT NULL,
+				date_created TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+			);
+
+			CREATE TABLE webhook_deliveries
+			(
+				id           BIGSERIAL PRIMARY KEY,
+				webhook_id   BIGINT NOT NULL REFERENCES webhooks (id) ON DELETE CASCADE,
+				event        TEXT NOT NULL,
+				payload      TEXT NOT NULL,
+				attempts     INTEGER NOT NULL DEFAULT 0,
+				last_error   TEXT,
+				next_attempt TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				date_created TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+			);
+			CREATE INDEX webhook_deliveries_next_attempt_idx ON webhook_deliveries (next_attempt);
+
+			CREATE TABLE webhook_dead_letters
+			(
+				id           BIGSERIAL PRIMARY KEY,
+				webhook_id   BIGINT NOT NULL REFERENCES webhooks (id) ON DELETE CASCADE,
+				event        TEXT NOT NULL,
+				payload      TEXT NOT NULL,
+				attempts     INTEGER NOT NULL,
+				last_error   TEXT,
+				date_created TIMESTAMPTZ NOT NULL,
+				date_failed  TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+			);
+			CREATE INDEX webhook_dead_letters_webhook_id_idx ON webhook_dead_letters (webhook_id);
+		`)
+		return err
+	}
+
+	down := func(db orm.DB) error {
+		_, err := db.Exec(`
+			DROP TABLE webhook_dead_letters;
+			DROP TABLE webhook_deliveries;
+			DROP TABLE webhooks;
+		`)
+		return err
+	}
+
+	opts := migrations.MigrationOptions{}
+
+	migrations.Register("20190812100000_create_webhooks_tables", up, down, opts)
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"github.com/go-pg/pg/orm"
+	migrations "github.com/robinjoseph08/go-pg-migrations"
+)
+
+func init() {
+	up := func(db orm.DB) error {
+		// date_modified only had a default at insert, so every update that changes
+		// a cluster now sets it too, however the update is made.
+		_, err := db.Exec(`
+			CREATE FUNCTION set_clusters_date_modified() RETURNS TRIGGER AS $$
+			BEGIN
+				NEW.date_modified := now();
+				RETURN NEW;
+			END;
+			$$ LANGUAGE plpgsql;
+
+			CREATE TRIGGER clusters_date_modified
+				BEFORE UPDATE ON clusters
+				FOR EACH ROW
+				WHEN (OLD.* IS DISTINCT FROM NEW.*)
+				EXECUTE PROCEDURE set_clusters_date_modified();
+
+			CREATE INDEX clusters_date_modified_idx ON clusters (date_modified);
+		`)
+		return err
+	}
+
+	down := func(db orm.DB) error {
+		_, err := db.Exec(`
+			DROP INDEX clusters_date_modified_idx;
+			DROP TRIGGER clusters_date_modified ON clusters;
+			DROP FUNCTION set_clusters_date_modified();
+		`)
+		return err
+	}
+
+	opts := migrations.MigrationOptions{}
+
+	migrations.Register("20190819100000_add_date_modified_trigger_to_clusters", up, down, opts)
+}
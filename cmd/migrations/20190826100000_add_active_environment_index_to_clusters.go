@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-pg/pg/orm"
+	migrations "github.com/robinjoseph08/go-pg-migrations"
+)
+
+func init() {
+	up := func(db orm.DB) error {
+		// The index can't be created while an environment has more than one
+		// active cluster, so point at the environments to fix first.
+		var environments []string
+		_, err := db.Query(&environments, `
+			SELECT environment
+			FROM clusters
+			WHERE active AND NOT deleted
+			GROUP BY environment
+			HAVING count(*) > 1
+			ORDER BY environment
+		`)
+		if err != nil {
+			return err
+		}
+		if len(environments) > 0 {
+			return fmt.Errorf("environments with more than one active cluster: %s (run pharos doctor and deactivate all but one)", strings.Join(environments, ", "))
+		}
+
+		_, err = db.Exec(`
+			CREATE UNIQUE INDEX clusters_active_environment_idx
+				ON clusters (environment)
+				WHERE active AND NOT deleted
+		`)
+		return err
+	}
+
+	down := func(db orm.DB) error {
+		_, err := db.Exec("DROP INDEX clusters_active_environment_idx")
+		return err
+	}
+
+	opts := migrations.MigrationOptions{}
+
+	migrations.Register("20190826100000_add_active_environment_index_to_clusters", up, down, opts)
+}
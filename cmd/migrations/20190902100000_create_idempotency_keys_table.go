@@ -0,0 +1,34 @@
+package main
+
+import (
+	"github.com/go-pg/pg/orm"
+	migrations "github.com/robinjoseph08/go-pg-migrations"
+)
+
+func init() {
+	up := func(db orm.DB) error {
+		_, err := db.Exec(`
+			CREATE TABLE idempotency_keys
+			(
+				caller       TEXT NOT NULL,
+				key          TEXT NOT NULL,
+				request_hash TEXT NOT NULL,
+				status_code  INTEGER NOT NULL,
+				body         TEXT NOT NULL,
+				date_created TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				PRIMARY KEY (caller, key)
+			);
+			CREATE INDEX idempotency_keys_date_created_idx ON idempotency_keys (date_created);
+		`)
+		return err
+	}
+
+	down := func(db orm.DB) error {
+		_, err := db.Exec("DROP TABLE idempotency_keys")
+		return err
+	}
+
+	opts := migrations.MigrationOptions{}
+
+	migrations.Register("20190902100000_create_idempotency_keys_table", up, down, opts)
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"github.com/go-pg/pg/orm"
+	migrations "github.com/robinjoseph08/go-pg-migrations"
+)
+
+func init() {
+	up := func(db orm.DB) error {
+		_, err := db.Exec(`
+			ALTER TABLE clusters ADD COLUMN expires_at TIMESTAMPTZ;
+			CREATE INDEX clusters_expires_at_idx ON clusters (expires_at) WHERE NOT deleted;
+		`)
+		return err
+	}
+
+	down := func(db orm.DB) error {
+		_, err := db.Exec("ALTER TABLE clusters DROP COLUMN expires_at")
+		return err
+	}
+
+	opts := migrations.MigrationOptions{}
+
+	migrations.Register("20190909100000_add_expires_at_to_clusters", up, down, opts)
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"github.com/go-pg/pg/orm"
+	migrations "github.com/robinjoseph08/go-pg-migrations"
+)
+
+func init() {
+	up := func(db orm.DB) error {
+		_, err := db.Exec(`
+			CREATE TABLE audit_entries
+			(
+				id           BIGSERIAL PRIMARY KEY,
+				cluster_id   TEXT NOT NULL,
+				action       TEXT NOT NULL,
+				actor        TEXT NOT NULL,
+				details      TEXT NOT NULL DEFAULT '',
+				date_created TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+			);
+			CREATE INDEX audit_entries_cluster_id_idx ON audit_entries (cluster_id, date_created);
+		`)
+		return err
+	}
+
+	down := func(db orm.DB) error {
+		_, err := db.Exec("DROP TABLE audit_entries")
+		return err
+	}
+
+	opts := migrations.MigrationOptions{}
+
+	migrations.Register("20190909110000_create_audit_entries_table", up, down, opts)
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"github.com/go-pg/pg/orm"
+	migrations "github.com/robinjoseph08/go-pg-migrations"
+)
+
+func init() {
+	up := func(db orm.DB) error {
+		// Sequence values are taken when a statement runs rather than when its
+		// transaction commits, so a transaction could commit a version lower
+		// than one already seen by watchers, who would then never see it. Every
+		// statement that writes clusters first takes a transaction-level
+		// advisory lock, so versions are taken, and become visible, in commit
+		// order. The lock is taken before any row is locked, and transactions
+		// that lock rows with SELECT ... FOR UPDATE before writing them take it
+		// first with lock_clusters_resource_versions(), so that waiting for it
+		// can't deadlock.
+		_, err := db.Exec(`
+			CREATE FUNCTION lock_clusters_resource_versions() RETURNS VOID AS $$
+			BEGIN
+				PERFORM pg_advisory_xact_lock(20190805100000);
+			END;
+			$$ LANGUAGE plpgsql;
+
+			CREATE FUNCTION lock_clusters_resource_versions_trigger() RETURNS TRIGGER AS $$
+			BEGIN
+				PERFORM lock_clusters_resource_versions();
+				RETURN NULL;
+			END;
+			$$ LANGUAGE plpgsql;
+
+			CREATE TRIGGER clusters_resource_version_lock
+				BEFORE INSERT OR UPDATE ON clusters
+				FOR EACH STATEMENT
+				EXECUTE PROCEDURE lock_clusters_resource_versions_trigger();
+
+			CREATE TRIGGER clusters_resource_version_insert
+				BEFORE INSERT ON clusters
+				FOR EACH ROW
+				EXECUTE PROCEDURE set_clusters_resource_version();
+		`)
+		return err
+	}
+
+	down := func(db orm.DB) error {
+		_, err := db.Exec(`
+			DROP TRIGGER clusters_resource_version_insert ON clusters;
+			DROP TRIGGER clusters_resource_version_lock ON clusters;
+			DROP FUNCTION lock_clusters_resource_versions_trigger();
+			DROP FUNCTION lock_clusters_resource_versions();
+		`)
+		return err
+	}
+
+	opts := migrations.MigrationOptions{}
+
+	migrations.Register("20190916100000_serialize_cluster_resource_versions", up, down, opts)
+}
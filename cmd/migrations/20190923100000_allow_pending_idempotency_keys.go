@@ -0,0 +1,32 @@
+package main
+
+import (
+	"github.com/go-pg/pg/orm"
+	migrations "github.com/robinjoseph08/go-pg-migrations"
+)
+
+func init() {
+	up := func(db orm.DB) error {
+		// A key is stored without a response while the request it came with is
+		// being handled, so that concurrent retries of the request wait for it
+		// instead of being handled too.
+		_, err := db.Exec(`
+			ALTER TABLE idempotency_keys ALTER COLUMN status_code DROP NOT NULL;
+			ALTER TABLE idempotency_keys ALTER COLUMN body DROP NOT NULL;
+		`)
+		return err
+	}
+
+	down := func(db orm.DB) error {
+		_, err := db.Exec(`
+			DELETE FROM idempotency_keys WHERE status_code IS NULL OR body IS NULL;
+			ALTER TABLE idempotency_keys ALTER COLUMN body SET NOT NULL;
+			ALTER TABLE idempotency_keys ALTER COLUMN status_code SET NOT NULL;
+		`)
+		return err
+	}
+
+	opts := migrations.MigrationOptions{}
+
+	migrations.Register("20190923100000_allow_pending_idempotency_keys", up, down, opts)
+}
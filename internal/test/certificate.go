@@ -0,0 +1,42 @@
+package test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// NewClusterAuthorityData returns base64 encoded cluster authority data
+// containing a self-signed certificate for each of the given expiry times.
+func NewClusterAuthorityData(t *testing.T, notAfter ...time.Time) string {
+	t.Helper()
+
+	var bundle []byte
+	for i, expiry := range notAfter {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(int64(i + 1)),
+			Subject:      pkix.Name{CommonName: "kubernetes"},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     expiry,
+			IsCA:         true,
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+		require.NoError(t, err)
+
+		bundle = append(bundle, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})...)
+	}
+
+	return base64.StdEncoding.EncodeToString(bundle)
+}
@@ -0,0 +1,33 @@
+package test
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// FieldNames returns the names that the fields of a struct are given by a tag,
+// such as "json" or "query", sorted. Fields of embedded structs are included,
+// and fields without a name for the tag, or named "-", are skipped.
+func FieldNames(v interface{}, tag string) []string {
+	t := reflect.TypeOf(v)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.SplitN(field.Tag.Get(tag), ",", 2)[0]
+		if field.Anonymous && name == "" {
+			names = append(names, FieldNames(reflect.Zero(field.Type).Interface(), tag)...)
+			continue
+		}
+		if name == "" || name == "-" {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
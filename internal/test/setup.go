@@ -14,6 +14,9 @@ func TruncateTables(t *testing.T, db *pg.DB) {
 
 	_, err := db.Exec(`
 		TRUNCATE clusters CASCADE;
+		TRUNCATE webhooks CASCADE;
+		TRUNCATE idempotency_keys;
+		TRUNCATE audit_entries;
 	`)
 	require.NoError(t, err)
 }
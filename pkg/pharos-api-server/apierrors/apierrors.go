@@ -0,0 +1,80 @@
+package apierrors
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/go-pg/pg"
+	"github.com/labstack/echo"
+	"github.com/lob/pharos/pkg/util/model"
+	"github.com/pkg/errors"
+)
+
+// uniqueViolation is the PostgreSQL error code of unique constraint violations.
+const uniqueViolation = "23505"
+
+// New returns an HTTP error with the given status, code and message, rendered
+// as {"error": {"code": ..., "message": ..., "status_code": ...}}.
+func New(status int, code string, message string) *echo.HTTPError {
+	return echo.NewHTTPError(status, &model.Error{Code: code, Message: message, StatusCode: status})
+}
+
+// From returns the API error for an error returned by a handler or middleware.
+// HTTP errors without a code are given the code for their status, and unique
+// constraint violations become conflicts. It returns false for any other
+// error, which is unexpected.
+func From(err error) (*model.Error, bool) {
+	switch cause := errors.Cause(err).(type) {
+	case *echo.HTTPError:
+		if apiErr, ok := cause.Message.(*model.Error); ok {
+			return apiErr, true
+		}
+		return &model.Error{
+			Code:       model.CodeForStatus(cause.Code),
+			Message:    fmt.Sprint(cause.Message),
+			StatusCode: cause.Code,
+		}, true
+	case pg.Error:
+		if cause.Field('C') == uniqueViolation {
+			return &model.Error{
+				Code:       model.CodeConflict,
+				Message:    "a resource with the same key already exists",
+				StatusCode: http.StatusConflict,
+			}, true
+		}
+	}
+	return nil, false
+}
+
+// IsUniqueViolation returns whether an error was caused by a write that
+// violated the given unique constraint or index.
+func IsUniqueViolation(err error, constraint string) bool {
+	pgErr, ok := errors.Cause(err).(pg.Error)
+	return ok && pgErr.Field('C') == uniqueViolation && pgErr.Field('n') == constraint
+}
+
+// RegisterErrorHandler renders the errors that From recognizes consistently,
+// and leaves unexpected errors to the error handler already registered with
+// e, which reports them.
+func RegisterErrorHandler(e *echo.Echo) {
+	next := e.HTTPErrorHandler
+	e.HTTPErrorHandler = func(err error, c echo.Context) {
+		apiErr, ok := From(err)
+		if !ok {
+			next(err, c)
+			return
+		}
+		if c.Response().Committed {
+			return
+		}
+
+		if c.Request().Method == http.MethodHead {
+			err = c.NoContent(apiErr.StatusCode)
+		} else {
+			err = c.JSON(apiErr.StatusCode, map[string]*model.Error{"error": apiErr})
+		}
+		if err != nil {
+			e.Logger.Error(err)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package apierrors
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo"
+	"github.com/lob/pharos/pkg/util/model"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pgError implements pg.Error with the given fields.
+type pgError map[byte]string
+
+func (e pgError) Error() string            { return e['M'] }
+func (e pgError) Field(k byte) string      { return e[k] }
+func (e pgError) IntegrityViolation() bool { return true }
+
+func TestFrom(t *testing.T) {
+	t.Run("returns the error of an HTTP error with a code", func(tt *testing.T) {
+		apiErr, ok := From(errors.WithStack(New(http.StatusNotFound, model.CodeClusterNotFound, "cluster not found")))
+		require.True(tt, ok)
+		assert.Equal(tt, &model.Error{Code: model.CodeClusterNotFound, Message: "cluster not found", StatusCode: http.StatusNotFound}, apiErr)
+	})
+
+	t.Run("gives HTTP errors without a code the code for their status", func(tt *testing.T) {
+		apiErr, ok := From(echo.NewHTTPError(http.StatusUnprocessableEntity, "id is required"))
+		require.True(tt, ok)
+		assert.Equal(tt, &model.Error{Code: model.CodeValidationFailed, Message: "id is required", StatusCode: http.StatusUnprocessableEntity}, apiErr)
+
+		apiErr, ok = From(echo.NewHTTPError(http.StatusForbidden))
+		require.True(tt, ok)
+		assert.Equal(tt, &model.Error{Code: model.CodeForbidden, Message: "Forbidden", StatusCode: http.StatusForbidden}, apiErr)
+	})
+
+	t.Run("maps unique constraint violations to conflicts", func(tt *testing.T) {
+		err := errors.Wrap(pgError{'C': "23505", 'n': "webhooks_pkey"}, "failed to insert")
+
+		apiErr, ok := From(err)
+		require.True(tt, ok)
+		assert.Equal(tt, model.CodeConflict, apiErr.Code)
+		assert.Equal(tt, http.StatusConflict, apiErr.StatusCode)
+
+		assert.True(tt, IsUniqueViolation(err, "webhooks_pkey"))
+		assert.False(tt, IsUniqueViolation(err, "clusters_pkey"))
+	})
+
+	t.Run("doesn't recognize unexpected errors", func(tt *testing.T) {
+		_, ok := From(errors.New("connection refused"))
+		assert.False(tt, ok)
+
+		_, ok = From(pgError{'C': "42P01"})
+		assert.False(tt, ok)
+	})
+}
+
+func TestRegisterErrorHandler(t *testing.T) {
+	e := echo.New()
+	var unexpected error
+	e.HTTPErrorHandler = func(err error, c echo.Context) {
+		unexpected = err
+		_ = c.NoContent(http.StatusInternalServerError)
+	}
+	RegisterErrorHandler(e)
+
+	e.GET("/clusters/:id", func(c echo.Context) error {
+		if c.Param("id") == "broken" {
+			return errors.New("connection refused")
+		}
+		return errors.WithStack(New(http.StatusNotFound, model.CodeClusterNotFound, "cluster not found"))
+	})
+
+	t.Run("renders errors with their code", func(tt *testing.T) {
+		rr := httptest.NewRecorder()
+		e.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/clusters/test-1", nil))
+
+		assert.Equal(tt, http.StatusNotFound, rr.Code)
+		assert.JSONEq(tt, `{"error": {"code": "cluster_not_found", "message": "cluster not found", "status_code": 404}}`, rr.Body.String())
+	})
+
+	t.Run("renders routing errors with their code", func(tt *testing.T) {
+		rr := httptest.NewRecorder()
+		e.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/missing", nil))
+
+		assert.Equal(tt, http.StatusNotFound, rr.Code)
+		assert.JSONEq(tt, `{"error": {"code": "not_found", "message": "Not Found", "status_code": 404}}`, rr.Body.String())
+	})
+
+	t.Run("leaves unexpected errors to the previous handler", func(tt *testing.T) {
+		rr := httptest.NewRecorder()
+		e.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/clusters/broken", nil))
+
+		assert.Equal(tt, http.StatusInternalServerError, rr.Code)
+		require.Error(tt, unexpected)
+		assert.Contains(tt, unexpected.Error(), "connection refused")
+	})
+}
@@ -26,7 +26,7 @@ func Middleware(allowedARNs []string) echo.MiddlewareFunc {
 				}
 			}
 
-			return echo.NewHTTPError(http.StatusUnauthorized)
+			return echo.NewHTTPError(http.StatusForbidden)
 		}
 	}
 }
@@ -29,7 +29,7 @@ func TestMiddleware(t *testing.T) {
 
 		err := m(func(c echo.Context) error { return nil })(c)
 		assert.Error(t, err)
-		assert.Contains(t, err.Error(), "Unauthorized")
+		assert.Contains(t, err.Error(), "Forbidden")
 	})
 
 	t.Run("rejects a request if auth is not set", func(tt *testing.T) {
@@ -72,6 +72,13 @@ func format(err validator.FieldError) string {
 		return fmt.Sprintf("%s must be a valid URL", err.Field())
 	}
 
+	if err.Tag() == "min" && err.Kind() == reflect.Slice {
+		if err.Param() == "1" {
+			return fmt.Sprintf("%s must contain at least one item", err.Field())
+		}
+		return fmt.Sprintf("%s must contain at least %s items", err.Field(), err.Param())
+	}
+
 	if err.Tag() == "base64" {
 		return fmt.Sprintf("%s must be a valid base64 encoded string", err.Field())
 	}
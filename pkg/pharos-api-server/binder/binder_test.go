@@ -49,6 +49,15 @@ func TestBind(t *testing.T) {
 		err := b.Bind(&p, c)
 		assert.Contains(t, err.Error(), "server_url must be a valid URL")
 	})
+
+	t.Run("enforces a minimum number of items", func(tt *testing.T) {
+		c := newContext(tt, echo.GET, strings.NewReader(`{"items": []}`), echo.MIMEApplicationJSON)
+		p := struct {
+			Items []string `json:"items" validate:"required,min=1"`
+		}{}
+		err := b.Bind(&p, c)
+		assert.Contains(tt, err.Error(), "items must contain at least one item")
+	})
 }
 
 // newContext returns a new echo.Context to be used for binder test. We cannot use the
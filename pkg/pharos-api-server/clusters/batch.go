@@ -0,0 +1,175 @@
+package clusters
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-pg/pg"
+	"github.com/labstack/echo"
+	"github.com/lob/pharos/pkg/pharos-api-server/apierrors"
+	"github.com/lob/pharos/pkg/pharos-api-server/database"
+	"github.com/lob/pharos/pkg/pharos-api-server/webhooks"
+	"github.com/lob/pharos/pkg/util/model"
+	"github.com/pkg/errors"
+)
+
+type batchParams struct {
+	Operations []batchOperation `json:"operations" validate:"required,dive"`
+}
+
+// batchOperation is validated, but not trimmed like other params, because mold
+// doesn't modify the structs in a slice.
+type batchOperation struct {
+	Action               string     `json:"action"                 validate:"oneof=create delete activate"`
+	ID                   string     `json:"id"                     validate:"required"`
+	Environment          string     `json:"environment"`
+	ServerURL            string     `json:"server_url"             validate:"omitempty,url"`
+	ClusterAuthorityData string     `json:"cluster_authority_data" validate:"omitempty,base64"`
+	ExpiresAt            *time.Time `json:"expires_at"`
+	Version              int64      `json:"version"`
+}
+
+// errBatchFailed is returned from the transaction of a batch to roll it back
+// once an operation has failed.
+var errBatchFailed = errors.New("batch operation failed")
+
+// batch makes a list of create, delete and activate operations in order, in a
+// single transaction. It stops at the first operation that fails and rolls
+// back the others, and responds with the result of every operation it made,
+// so that callers can tell which one failed and why.
+func (h *handler) batch(c echo.Context) error {
+	params := batchParams{}
+	if err := c.Bind(&params); err != nil {
+		return err
+	}
+	if len(params.Operations) == 0 {
+		return apierrors.New(http.StatusUnprocessableEntity, model.CodeValidationFailed, "operations must contain at least one item")
+	}
+	if len(params.Operations) > model.MaxBatchOperations {
+		return apierrors.New(http.StatusUnprocessableEntity, model.CodeValidationFailed, fmt.Sprintf("operations can't contain more than %d items", model.MaxBatchOperations))
+	}
+	for i, op := range params.Operations {
+		if op.Action == model.BatchCreate && (op.Environment == "" || op.ServerURL == "" || op.ClusterAuthorityData == "") {
+			return apierrors.New(http.StatusUnprocessableEntity, model.CodeValidationFailed, fmt.Sprintf("operation %d needs an environment, server_url and cluster_authority_data to create a cluster", i+1))
+		}
+		if err := checkExpiry(op.ExpiresAt); err != nil {
+			return err
+		}
+	}
+
+	result := model.BatchResult{Results: make([]model.BatchOperationResult, 0, len(params.Operations))}
+
+	err := h.app.DB.RunInTransaction(func(tx *pg.Tx) error {
+		if err := database.LockResourceVersions(tx); err != nil {
+			return err
+		}
+
+		for _, op := range params.Operations {
+			res := model.BatchOperationResult{Action: op.Action, ID: op.ID}
+
+			cluster, status, err := applyBatchOperation(tx, op)
+			if err != nil {
+				apiErr, ok := apierrors.From(err)
+				if !ok {
+					return err
+				}
+				res.StatusCode = apiErr.StatusCode
+				res.Error = apiErr
+				result.Results = append(result.Results, res)
+				return errBatchFailed
+			}
+
+			res.StatusCode = status
+			res.Cluster = &cluster
+			result.Results = append(result.Results, res)
+		}
+		return nil
+	})
+	if err != nil && err != errBatchFailed {
+		return errors.WithStack(err)
+	}
+
+	result.Applied = err == nil
+	return c.JSON(http.StatusOK, result)
+}
+
+// applyBatchOperation makes a single operation of a batch, and returns the
+// changed cluster and the status the equivalent request would respond with.
+// Deleting a cluster that is already deleted, or activating a cluster that is
+// already active, changes nothing.
+func applyBatchOperation(tx *pg.Tx, op batchOperation) (model.Cluster, int, error) {
+	var cluster model.Cluster
+
+	if op.Action == model.BatchCreate {
+		cluster = model.Cluster{
+			ID:                   op.ID,
+			Environment:          op.Environment,
+			ServerURL:            op.ServerURL,
+			ClusterAuthorityData: op.ClusterAuthorityData,
+			CAExpiresAt:          caExpiry(op.ClusterAuthorityData),
+			ExpiresAt:            op.ExpiresAt,
+		}
+		_, err := tx.Model(&cluster).Insert()
+		if apierrors.IsUniqueViolation(err, "clusters_pkey") {
+			return cluster, 0, apierrors.New(http.StatusConflict, model.CodeClusterExists, fmt.Sprintf("cluster %s already exists", cluster.ID))
+		}
+		if err != nil {
+			return cluster, 0, err
+		}
+		return cluster, http.StatusCreated, webhooks.Enqueue(tx, model.EventClusterCreated, cluster)
+	}
+
+	q := tx.Model(&cluster).Where("id = ?", op.ID).For("UPDATE")
+	if op.Action == model.BatchActivate {
+		q = q.Where("deleted = FALSE")
+	}
+	err := q.First()
+	if err == pg.ErrNoRows {
+		return cluster, 0, apierrors.New(http.StatusNotFound, model.CodeClusterNotFound, "cluster not found")
+	}
+	if err != nil {
+		return cluster, 0, err
+	}
+	if op.Version != 0 && op.Version != cluster.ResourceVersion {
+		return cluster, 0, errModified
+	}
+
+	switch op.Action {
+	case model.BatchDelete:
+		if cluster.Deleted {
+			return cluster, http.StatusOK, nil
+		}
+
+		cluster.Deleted = true
+		if err := updateIfMatch(tx, &cluster, 0); err != nil {
+			return cluster, 0, err
+		}
+		return cluster, http.StatusOK, webhooks.Enqueue(tx, model.EventClusterDeleted, cluster)
+	case model.BatchActivate:
+		if cluster.Active {
+			return cluster, http.StatusOK, nil
+		}
+
+		_, err := tx.Model(&model.Cluster{}).
+			Set("active = FALSE").
+			Where("environment = ?", cluster.Environment).
+			Where("id != ?", cluster.ID).
+			Update()
+		if err != nil {
+			return cluster, 0, err
+		}
+
+		cluster.Active = true
+		err = updateIfMatch(tx, &cluster, 0)
+		if isActiveConflict(err) {
+			return cluster, 0, activeConflict(cluster.Environment)
+		}
+		if err != nil {
+			return cluster, 0, err
+		}
+		return cluster, http.StatusOK, webhooks.Enqueue(tx, model.EventClusterActivated, cluster)
+	default:
+		return cluster, 0, fmt.Errorf("unknown batch action %s", op.Action)
+	}
+}
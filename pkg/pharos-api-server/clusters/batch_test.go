@@ -0,0 +1,112 @@
+package clusters
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/lob/pharos/internal/test"
+	"github.com/lob/pharos/pkg/util/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatchHandler(t *testing.T) {
+	h := newHandler(t)
+
+	batch := func(tt *testing.T, payload string) (model.BatchResult, error) {
+		tt.Helper()
+		c, rr := test.NewContext(tt, "POST", "", strings.NewReader(payload), "application/json")
+		var result model.BatchResult
+		if err := h.batch(c); err != nil {
+			return result, err
+		}
+		assert.Equal(tt, http.StatusOK, rr.Code)
+		require.NoError(tt, json.Unmarshal(rr.Body.Bytes(), &result))
+		return result, nil
+	}
+
+	t.Run("applies every operation in order", func(tt *testing.T) {
+		test.TruncateTables(tt, h.app.DB)
+		clusters := []model.Cluster{defaultTestCluster, activeTestCluster}
+		err := h.app.DB.Insert(&clusters)
+		require.NoError(tt, err)
+
+		result, err := batch(tt, `{"operations": [
+			{"action": "create", "id": "test-batch", "environment": "test", "server_url": "http://localhost:6443", "cluster_authority_data": "dGVzdA=="},
+			{"action": "activate", "id": "test-batch"},
+			{"action": "delete", "id": "test-1"}
+		]}`)
+		require.NoError(tt, err)
+		assert.True(tt, result.Applied)
+		require.Len(tt, result.Results, 3)
+		assert.Equal(tt, http.StatusCreated, result.Results[0].StatusCode)
+		assert.Equal(tt, http.StatusOK, result.Results[1].StatusCode)
+		assert.True(tt, result.Results[1].Cluster.Active)
+		assert.True(tt, result.Results[2].Cluster.Deleted)
+
+		var active model.Cluster
+		err = h.app.DB.Model(&active).Where("id = ?", activeTestCluster.ID).First()
+		require.NoError(tt, err)
+		assert.False(tt, active.Active)
+	})
+
+	t.Run("rolls back every operation when one fails", func(tt *testing.T) {
+		test.TruncateTables(tt, h.app.DB)
+		clusters := []model.Cluster{defaultTestCluster}
+		err := h.app.DB.Insert(&clusters)
+		require.NoError(tt, err)
+
+		result, err := batch(tt, `{"operations": [
+			{"action": "delete", "id": "test-1"},
+			{"action": "delete", "id": "test-missing"},
+			{"action": "delete", "id": "test-2"}
+		]}`)
+		require.NoError(tt, err)
+		assert.False(tt, result.Applied)
+		require.Len(tt, result.Results, 2)
+		assert.Equal(tt, http.StatusOK, result.Results[0].StatusCode)
+		assert.Equal(tt, http.StatusNotFound, result.Results[1].StatusCode)
+		require.NotNil(tt, result.Results[1].Error)
+		assert.Equal(tt, model.CodeClusterNotFound, result.Results[1].Error.Code)
+
+		var cluster model.Cluster
+		err = h.app.DB.Model(&cluster).Where("id = ?", defaultTestCluster.ID).First()
+		require.NoError(tt, err)
+		assert.False(tt, cluster.Deleted)
+	})
+
+	t.Run("fails operations on clusters modified since their version", func(tt *testing.T) {
+		test.TruncateTables(tt, h.app.DB)
+		cluster := defaultTestCluster
+		_, err := h.app.DB.Model(&cluster).Returning("*").Insert()
+		require.NoError(tt, err)
+
+		result, err := batch(tt, `{"operations": [{"action": "delete", "id": "test-1", "version": 1}]}`)
+		require.NoError(tt, err)
+		assert.False(tt, result.Applied)
+		require.Len(tt, result.Results, 1)
+		assert.Equal(tt, http.StatusPreconditionFailed, result.Results[0].StatusCode)
+	})
+
+	t.Run("errors with invalid payload", func(tt *testing.T) {
+		test.TruncateTables(tt, h.app.DB)
+
+		cases := []struct {
+			payload, errorMessage string
+		}{
+			{`{}`, "operations is required"},
+			{`{"operations": []}`, "operations must contain at least one item"},
+			{`{"operations": [{"action": "rename", "id": "test-1"}]}`, "action is invalid"},
+			{`{"operations": [{"action": "delete"}]}`, "id is required"},
+			{`{"operations": [{"action": "create", "id": "test-1", "environment": "test"}]}`, "operation 1 needs an environment, server_url and cluster_authority_data"},
+		}
+
+		for _, tc := range cases {
+			_, err := batch(tt, tc.payload)
+			require.Error(tt, err)
+			assert.Contains(tt, err.Error(), tc.errorMessage)
+		}
+	})
+}
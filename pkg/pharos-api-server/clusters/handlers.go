@@ -1,50 +1,168 @@
 package clusters
 
 import (
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-pg/pg"
 	"github.com/labstack/echo"
+	"github.com/lob/pharos/pkg/pharos-api-server/apierrors"
 	"github.com/lob/pharos/pkg/pharos-api-server/application"
+	"github.com/lob/pharos/pkg/pharos-api-server/database"
+	"github.com/lob/pharos/pkg/pharos-api-server/healthcheck"
+	"github.com/lob/pharos/pkg/pharos-api-server/webhooks"
+	"github.com/lob/pharos/pkg/util/certificate"
 	"github.com/lob/pharos/pkg/util/model"
 	"github.com/pkg/errors"
 )
 
+// historyLength is the number of past health checks returned with the status
+// of a cluster.
+const historyLength = 20
+
 type handler struct {
 	app application.App
 }
 
+// watchPollInterval is how often a watch of the cluster list checks for
+// changed clusters.
+var watchPollInterval = time.Second
+
 type listQuery struct {
-	Environment string `query:"environment"`
-	Active      bool   `query:"active"`
+	Environment    string `query:"environment"`
+	Active         bool   `query:"active"`
+	ExpiringWithin string `query:"expiring_within"`
+	Since          string `query:"since"`
+	Watch          bool   `query:"watch"`
+	Timeout        string `query:"timeout"`
+	ModifiedSince  string `query:"modified_since"`
 }
 
 func (h *handler) list(c echo.Context) error {
-	clusters := make([]*model.Cluster, 0)
-
 	query := listQuery{}
 	if err := c.Bind(&query); err != nil {
 		return err
 	}
 
-	q := h.app.DB.
-		Model(&clusters).
-		Where("deleted = FALSE").
-		Order("date_created DESC")
+	var expiresBefore time.Time
+	if query.ExpiringWithin != "" {
+		within, err := parseDuration(query.ExpiringWithin)
+		if err != nil {
+			return apierrors.New(http.StatusUnprocessableEntity, model.CodeValidationFailed, "expiring_within must be a duration such as 30d or 12h")
+		}
+		expiresBefore = time.Now().Add(within)
+	}
 
-	if query.Environment != "" {
-		q = q.Where("environment = ?", query.Environment)
+	// Listing the clusters changed since a resource version includes deleted
+	// clusters, so that watchers find out about deletions.
+	var since int64
+	changes := query.Since != "" || query.Watch
+	if query.Since != "" {
+		var err error
+		since, err = strconv.ParseInt(query.Since, 10, 64)
+		if err != nil || since < 0 {
+			return apierrors.New(http.StatusUnprocessableEntity, model.CodeValidationFailed, "since must be a resource version")
+		}
+	}
+
+	// Listing the clusters modified since a time also includes deleted clusters.
+	var modifiedSince time.Time
+	if query.ModifiedSince != "" {
+		var err error
+		modifiedSince, err = time.Parse(time.RFC3339, query.ModifiedSince)
+		if err != nil {
+			return apierrors.New(http.StatusUnprocessableEntity, model.CodeValidationFailed, "modified_since must be a timestamp such as 2019-08-19T10:00:00Z")
+		}
 	}
 
-	if query.Active {
-		q = q.Where("active = ?", query.Active)
+	timeout := h.app.Config.WatchTimeout
+	if query.Timeout != "" {
+		t, err := parseDuration(query.Timeout)
+		if err != nil || t < 0 {
+			return apierrors.New(http.StatusUnprocessableEntity, model.CodeValidationFailed, "timeout must be a duration such as 30s")
+		}
+		if t < timeout {
+			timeout = t
+		}
+	}
+
+	selectClusters := func() ([]*model.Cluster, error) {
+		clusters := make([]*model.Cluster, 0)
+
+		q := h.app.DB.Model(&clusters)
+
+		switch {
+		case changes:
+			q = q.Where("resource_version > ?", since).Order("resource_version ASC")
+		case !modifiedSince.IsZero():
+			q = q.Order("date_modified ASC")
+		default:
+			q = q.Where("deleted = FALSE").Order("date_created DESC")
+		}
+
+		if !modifiedSince.IsZero() {
+			q = q.Where("date_modified > ?", modifiedSince)
+		}
+
+		if query.Environment != "" {
+			q = q.Where("environment = ?", query.Environment)
+		}
+
+		if query.Active {
+			q = q.Where("active = ?", query.Active)
+		}
+
+		if !expiresBefore.IsZero() {
+			q = q.Where("ca_expires_at <= ?", expiresBefore)
+		}
+
+		return clusters, q.Select()
 	}
 
-	err := q.Select()
+	clusters, err := selectClusters()
 	if err != nil {
 		return err
 	}
 
+	// Watches wait for a cluster to change before responding, or respond with
+	// no clusters once the timeout has passed.
+	if query.Watch && len(clusters) == 0 {
+		ticker := time.NewTicker(watchPollInterval)
+		defer ticker.Stop()
+		deadline := time.NewTimer(timeout)
+		defer deadline.Stop()
+
+	poll:
+		for len(clusters) == 0 {
+			select {
+			case <-ticker.C:
+				clusters, err = selectClusters()
+				if err != nil {
+					return err
+				}
+			case <-deadline.C:
+				break poll
+			case <-c.Request().Context().Done():
+				return c.Request().Context().Err()
+			}
+		}
+	}
+
+	ids := make([]string, len(clusters))
+	for i, cluster := range clusters {
+		ids[i] = cluster.ID
+	}
+	statuses, err := healthcheck.LatestStatuses(h.app.DB, ids)
+	if err != nil {
+		return err
+	}
+	for _, cluster := range clusters {
+		cluster.Status = statuses[cluster.ID]
+	}
+
 	return c.JSON(http.StatusOK, clusters)
 }
 
@@ -56,15 +174,78 @@ func (h *handler) retrieve(c echo.Context) error {
 	err := h.app.DB.Model(&cluster).Where("id = ?", id).First()
 	if err != nil {
 		if err == pg.ErrNoRows {
-			return echo.NewHTTPError(http.StatusNotFound, "cluster not found")
+			return apierrors.New(http.StatusNotFound, model.CodeClusterNotFound, "cluster not found")
 		}
 		return err
 	}
 
+	setETag(c, cluster)
 	return c.JSON(http.StatusOK, cluster)
 }
 
-func (h *handler) delete(c echo.Context) error {
+// resolve returns the cluster that a name given on the command line refers to:
+// the cluster with that ID, or else the active cluster of the environment with
+// that name. Names that match more than one cluster, or an environment without
+// exactly one active cluster, are ambiguous and the candidates are listed in
+// the error.
+func (h *handler) resolve(c echo.Context) error {
+	name := c.Param("name")
+
+	var cluster model.Cluster
+
+	err := h.app.DB.Model(&cluster).Where("id = ?", name).Where("deleted = FALSE").First()
+	if err == nil {
+		setETag(c, cluster)
+		return c.JSON(http.StatusOK, cluster)
+	}
+	if err != pg.ErrNoRows {
+		return err
+	}
+
+	clusters := make([]model.Cluster, 0)
+	err = h.app.DB.
+		Model(&clusters).
+		Where("environment = ?", name).
+		Where("deleted = FALSE").
+		Order("id ASC").
+		Select()
+	if err != nil {
+		return err
+	}
+	if len(clusters) == 0 {
+		return apierrors.New(http.StatusNotFound, model.CodeClusterNotFound, "cluster not found")
+	}
+
+	var active []model.Cluster
+	candidates := make([]string, len(clusters))
+	for i, cluster := range clusters {
+		if cluster.Active {
+			active = append(active, cluster)
+		}
+		candidates[i] = cluster.ID
+	}
+
+	switch len(active) {
+	case 0:
+		return apierrors.New(http.StatusConflict, model.CodeNoActiveCluster, fmt.Sprintf("no active cluster found for environment %s, candidates: %s", name, strings.Join(candidates, ", ")))
+	case 1:
+		setETag(c, active[0])
+		return c.JSON(http.StatusOK, active[0])
+	default:
+		ids := make([]string, len(active))
+		for i, cluster := range active {
+			ids[i] = cluster.ID
+		}
+		return apierrors.New(http.StatusConflict, model.CodeMultipleActiveClusters, fmt.Sprintf("%d active clusters found for environment %s, candidates: %s", len(active), name, strings.Join(ids, ", ")))
+	}
+}
+
+type statusResponse struct {
+	*model.ClusterStatus
+	History []model.ClusterStatus `json:"history"`
+}
+
+func (h *handler) status(c echo.Context) error {
 	id := c.Param("id")
 
 	var cluster model.Cluster
@@ -72,26 +253,78 @@ func (h *handler) delete(c echo.Context) error {
 	err := h.app.DB.Model(&cluster).Where("id = ?", id).First()
 	if err != nil {
 		if err == pg.ErrNoRows {
-			return echo.NewHTTPError(http.StatusNotFound, "cluster not found")
+			return apierrors.New(http.StatusNotFound, model.CodeClusterNotFound, "cluster not found")
 		}
 		return err
 	}
 
-	cluster.Deleted = true
+	history := make([]model.ClusterStatus, 0)
+	err = h.app.DB.Model(&history).
+		Where("cluster_id = ?", id).
+		Order("date_checked DESC").
+		Limit(historyLength).
+		Select()
+	if err != nil {
+		return err
+	}
+	if len(history) == 0 {
+		return apierrors.New(http.StatusNotFound, model.CodeStatusNotFound, "cluster has not been checked yet")
+	}
+
+	return c.JSON(http.StatusOK, statusResponse{&history[0], history})
+}
 
-	_, err = h.app.DB.Model(&cluster).WherePK().Update()
+func (h *handler) delete(c echo.Context) error {
+	id := c.Param("id")
+
+	version, err := ifMatch(c)
 	if err != nil {
 		return err
 	}
 
+	var cluster model.Cluster
+
+	err = h.app.DB.Model(&cluster).Where("id = ?", id).First()
+	if err != nil {
+		if err == pg.ErrNoRows {
+			return apierrors.New(http.StatusNotFound, model.CodeClusterNotFound, "cluster not found")
+		}
+		return err
+	}
+	if version != 0 && version != cluster.ResourceVersion {
+		return errModified
+	}
+
+	wasDeleted := cluster.Deleted
+	cluster.Deleted = true
+
+	err = h.app.DB.RunInTransaction(func(tx *pg.Tx) error {
+		if err := updateIfMatch(tx, &cluster, version); err != nil {
+			return err
+		}
+
+		if wasDeleted {
+			return nil
+		}
+		return webhooks.Enqueue(tx, model.EventClusterDeleted, cluster)
+	})
+	if err == errModified {
+		return err
+	}
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	setETag(c, cluster)
 	return c.JSON(http.StatusOK, cluster)
 }
 
 type createParams struct {
-	ID                   string `json:"id"                     mod:"trim" validate:"required"`
-	Environment          string `json:"environment"            mod:"trim" validate:"required"`
-	ServerURL            string `json:"server_url"             mod:"trim" validate:"required,url"`
-	ClusterAuthorityData string `json:"cluster_authority_data" mod:"trim" validate:"required,base64"`
+	ID                   string     `json:"id"                     mod:"trim" validate:"required"`
+	Environment          string     `json:"environment"            mod:"trim" validate:"required"`
+	ServerURL            string     `json:"server_url"             mod:"trim" validate:"required,url"`
+	ClusterAuthorityData string     `json:"cluster_authority_data" mod:"trim" validate:"required,base64"`
+	ExpiresAt            *time.Time `json:"expires_at"`
 }
 
 func (h *handler) create(c echo.Context) error {
@@ -99,26 +332,155 @@ func (h *handler) create(c echo.Context) error {
 	if err := c.Bind(&params); err != nil {
 		return err
 	}
+	if err := checkExpiry(params.ExpiresAt); err != nil {
+		return err
+	}
 
 	cluster := model.Cluster{
 		ID:                   params.ID,
 		Environment:          params.Environment,
 		ServerURL:            params.ServerURL,
 		ClusterAuthorityData: params.ClusterAuthorityData,
+		CAExpiresAt:          caExpiry(params.ClusterAuthorityData),
+		ExpiresAt:            params.ExpiresAt,
+	}
+
+	err := h.app.DB.RunInTransaction(func(tx *pg.Tx) error {
+		if _, err := tx.Model(&cluster).Insert(); err != nil {
+			return err
+		}
+
+		return webhooks.Enqueue(tx, model.EventClusterCreated, cluster)
+	})
+	if apierrors.IsUniqueViolation(err, "clusters_pkey") {
+		return apierrors.New(http.StatusConflict, model.CodeClusterExists, fmt.Sprintf("cluster %s already exists", cluster.ID))
+	}
+	if isActiveConflict(err) {
+		return activeConflict(cluster.Environment)
+	}
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	setETag(c, cluster)
+	return c.JSON(http.StatusOK, cluster)
+}
+
+type upsertParams struct {
+	ID                   string     `json:"id"                     mod:"trim"`
+	Environment          string     `json:"environment"            mod:"trim" validate:"required"`
+	ServerURL            string     `json:"server_url"             mod:"trim" validate:"required,url"`
+	ClusterAuthorityData string     `json:"cluster_authority_data" mod:"trim" validate:"required,base64"`
+	ExpiresAt            *time.Time `json:"expires_at"`
+}
+
+// upsert creates the cluster with the ID in the path, or replaces the
+// registration of an existing one, so that registering a cluster is safe to
+// repeat. A cluster that was deleted is restored, and a cluster moved to
+// another environment is deactivated. The expiry of an existing cluster is
+// only replaced if one is given. It responds with 201 Created if the cluster
+// was created or restored.
+func (h *handler) upsert(c echo.Context) error {
+	id := c.Param("id")
+
+	params := upsertParams{}
+	if err := c.Bind(&params); err != nil {
+		return err
+	}
+	if params.ID != "" && params.ID != id {
+		return apierrors.New(http.StatusUnprocessableEntity, model.CodeValidationFailed, "id must match the cluster ID in the path")
+	}
+	if err := checkExpiry(params.ExpiresAt); err != nil {
+		return err
 	}
 
-	_, err := h.app.DB.Model(&cluster).Insert()
+	version, err := ifMatch(c)
 	if err != nil {
 		return err
 	}
 
+	var cluster model.Cluster
+	created := false
+
+	err = h.app.DB.RunInTransaction(func(tx *pg.Tx) error {
+		if err := database.LockResourceVersions(tx); err != nil {
+			return err
+		}
+
+		err := tx.Model(&cluster).Where("id = ?", id).For("UPDATE").First()
+		if err == pg.ErrNoRows {
+			if version != 0 {
+				return errModified
+			}
+
+			created = true
+			cluster = model.Cluster{
+				ID:                   id,
+				Environment:          params.Environment,
+				ServerURL:            params.ServerURL,
+				ClusterAuthorityData: params.ClusterAuthorityData,
+				CAExpiresAt:          caExpiry(params.ClusterAuthorityData),
+				ExpiresAt:            params.ExpiresAt,
+			}
+			if _, err := tx.Model(&cluster).Insert(); err != nil {
+				return err
+			}
+			return webhooks.Enqueue(tx, model.EventClusterCreated, cluster)
+		}
+		if err != nil {
+			return err
+		}
+		if version != 0 && version != cluster.ResourceVersion {
+			return errModified
+		}
+
+		created = cluster.Deleted
+		if created || cluster.Environment != params.Environment {
+			cluster.Active = false
+		}
+		cluster.Deleted = false
+		cluster.Environment = params.Environment
+		cluster.ServerURL = params.ServerURL
+		cluster.ClusterAuthorityData = params.ClusterAuthorityData
+		cluster.CAExpiresAt = caExpiry(params.ClusterAuthorityData)
+		if params.ExpiresAt != nil {
+			cluster.ExpiresAt = params.ExpiresAt
+		}
+
+		if err := updateIfMatch(tx, &cluster, 0); err != nil {
+			return err
+		}
+
+		if !created {
+			return nil
+		}
+		return webhooks.Enqueue(tx, model.EventClusterCreated, cluster)
+	})
+	if err == errModified {
+		return err
+	}
+	if apierrors.IsUniqueViolation(err, "clusters_pkey") {
+		// The cluster was created by a concurrent request.
+		return apierrors.New(http.StatusConflict, model.CodeClusterExists, fmt.Sprintf("cluster %s was created at the same time, try again", id))
+	}
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	setETag(c, cluster)
+	if created {
+		return c.JSON(http.StatusCreated, cluster)
+	}
 	return c.JSON(http.StatusOK, cluster)
 }
 
 type updateParams struct {
-	Active bool `json:"active" validate:"required"`
+	Active    *bool      `json:"active"`
+	ExpiresAt *time.Time `json:"expires_at"`
 }
 
+// update activates or deactivates a cluster, or changes when it expires.
+// Activating a cluster deactivates the other clusters of its environment.
 func (h *handler) update(c echo.Context) error {
 	id := c.Param("id")
 
@@ -126,30 +488,206 @@ func (h *handler) update(c echo.Context) error {
 	if err := c.Bind(&params); err != nil {
 		return err
 	}
+	if params.Active == nil && params.ExpiresAt == nil {
+		return apierrors.New(http.StatusUnprocessableEntity, model.CodeValidationFailed, "active or expires_at is required")
+	}
+	if err := checkExpiry(params.ExpiresAt); err != nil {
+		return err
+	}
 
-	var cluster model.Cluster
-
-	err := h.app.DB.Model(&cluster).Where("id = ?", id).Where("deleted = FALSE", id).First()
+	version, err := ifMatch(c)
 	if err != nil {
-		if err == pg.ErrNoRows {
-			return echo.NewHTTPError(http.StatusNotFound, "cluster not found")
-		}
 		return err
 	}
 
-	cluster.Active = params.Active
+	var cluster model.Cluster
 
 	err = h.app.DB.RunInTransaction(func(tx *pg.Tx) error {
-		if _, err := tx.Model(&model.Cluster{}).Set("active = FALSE").Where("environment = ?", cluster.Environment).Update(); err != nil {
+		// Writes to clusters are serialized by the resource version lock, so
+		// concurrent activations in an environment see each other's changes.
+		if err := database.LockResourceVersions(tx); err != nil {
 			return err
 		}
 
-		_, err := tx.Model(&cluster).WherePK().Update()
-		return err
+		err := tx.Model(&cluster).Where("id = ?", id).Where("deleted = FALSE").For("UPDATE").First()
+		if err == pg.ErrNoRows {
+			return apierrors.New(http.StatusNotFound, model.CodeClusterNotFound, "cluster not found")
+		}
+		if err != nil {
+			return err
+		}
+		if version != 0 && version != cluster.ResourceVersion {
+			return errModified
+		}
+
+		// Activating a cluster always deactivates the other clusters of its
+		// environment, even if it was already active, so that updating it
+		// repairs an environment with more than one active cluster. Webhooks
+		// are only notified when it wasn't active before.
+		activate := params.Active != nil && *params.Active
+		activated := activate && !cluster.Active
+		if params.Active != nil {
+			cluster.Active = *params.Active
+		}
+		if params.ExpiresAt != nil {
+			cluster.ExpiresAt = params.ExpiresAt
+		}
+		cluster.CAExpiresAt = caExpiry(cluster.ClusterAuthorityData)
+
+		if activate {
+			if version != 0 {
+				if err := checkActiveUnchanged(tx, cluster, version); err != nil {
+					return err
+				}
+			}
+
+			_, err := tx.Model(&model.Cluster{}).
+				Set("active = FALSE").
+				Where("environment = ?", cluster.Environment).
+				Where("id != ?", cluster.ID).
+				Update()
+			if err != nil {
+				return err
+			}
+		}
+
+		if err := updateIfMatch(tx, &cluster, version); err != nil {
+			return err
+		}
+
+		if !activated {
+			return nil
+		}
+		return webhooks.Enqueue(tx, model.EventClusterActivated, cluster)
 	})
+	if _, ok := err.(*echo.HTTPError); ok {
+		return err
+	}
+	if isActiveConflict(err) {
+		// Another cluster of the environment was activated at the same time.
+		return activeConflict(cluster.Environment)
+	}
 	if err != nil {
 		return errors.WithStack(err)
 	}
 
+	setETag(c, cluster)
 	return c.JSON(http.StatusOK, cluster)
 }
+
+// activeEnvironmentIndex is the unique index on the clusters table that allows
+// at most one active cluster per environment.
+const activeEnvironmentIndex = "clusters_active_environment_idx"
+
+// isActiveConflict returns whether an error was caused by a write that would
+// have left an environment with more than one active cluster.
+func isActiveConflict(err error) bool {
+	return apierrors.IsUniqueViolation(err, activeEnvironmentIndex)
+}
+
+// activeConflict returns the error for a write that would have left an
+// environment with more than one active cluster.
+func activeConflict(environment string) error {
+	return apierrors.New(http.StatusConflict, model.CodeActiveClusterExists, fmt.Sprintf("environment %s already has an active cluster", environment))
+}
+
+// checkActiveUnchanged returns an error if an active cluster in the
+// environment of a cluster being activated has been changed since the version
+// the activation was made against. Resource versions are assigned in commit
+// order across clusters, so such a cluster was changed, for example activated
+// by a concurrent request, after the caller last saw the environment.
+func checkActiveUnchanged(tx *pg.Tx, cluster model.Cluster, version int64) error {
+	var changed model.Cluster
+	err := tx.Model(&changed).
+		Where("environment = ?", cluster.Environment).
+		Where("id != ?", cluster.ID).
+		Where("active = TRUE").
+		Where("deleted = FALSE").
+		Where("resource_version > ?", version).
+		Order("resource_version DESC").
+		For("UPDATE").
+		First()
+	if err == pg.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return apierrors.New(http.StatusPreconditionFailed, model.CodePreconditionFailed, fmt.Sprintf("active cluster %s of environment %s has been modified", changed.ID, changed.Environment))
+}
+
+// errModified is returned when a cluster has changed since the version given
+// in the If-Match header of a request.
+var errModified = apierrors.New(http.StatusPreconditionFailed, model.CodePreconditionFailed, "cluster has been modified")
+
+// ifMatch returns the resource version given in the If-Match header of a
+// request, or 0 if any version matches.
+func ifMatch(c echo.Context) (int64, error) {
+	header := c.Request().Header.Get("If-Match")
+	if header == "" || header == "*" {
+		return 0, nil
+	}
+
+	version, err := strconv.ParseInt(strings.Trim(strings.TrimPrefix(header, "W/"), `"`), 10, 64)
+	if err != nil || version <= 0 {
+		return 0, errModified
+	}
+	return version, nil
+}
+
+// setETag sets the ETag header of a response to the resource version of a
+// cluster, so that it can be given in the If-Match header of later requests.
+func setETag(c echo.Context, cluster model.Cluster) {
+	c.Response().Header().Set("ETag", fmt.Sprintf(`"%d"`, cluster.ResourceVersion))
+}
+
+// updateIfMatch writes a cluster and reads back its new resource version and
+// modification date. If version isn't 0, the cluster is only written if its
+// resource version still matches, and errModified is returned otherwise.
+func updateIfMatch(tx *pg.Tx, cluster *model.Cluster, version int64) error {
+	q := tx.Model(cluster).WherePK().Returning("resource_version").Returning("date_modified")
+	if version != 0 {
+		q = q.Where("resource_version = ?", version)
+	}
+
+	res, err := q.Update()
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return errModified
+	}
+	return nil
+}
+
+// checkExpiry returns an error if a cluster would be given an expiry that has
+// already passed.
+func checkExpiry(expiresAt *time.Time) error {
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return apierrors.New(http.StatusUnprocessableEntity, model.CodeValidationFailed, "expires_at must be in the future")
+	}
+	return nil
+}
+
+// caExpiry returns the earliest expiry of the certificates in the given cluster
+// authority data, or nil if it doesn't contain any certificates.
+func caExpiry(clusterAuthorityData string) *time.Time {
+	expiry, err := certificate.EarliestExpiry(clusterAuthorityData)
+	if err != nil {
+		return nil
+	}
+	return &expiry
+}
+
+// parseDuration parses a duration that may also be given in days, such as
+// "30d", in addition to the units supported by time.ParseDuration.
+func parseDuration(s string) (time.Duration, error) {
+	if strings.HasSuffix(s, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(s, "d"))
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
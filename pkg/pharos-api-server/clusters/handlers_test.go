@@ -4,11 +4,17 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/go-pg/pg"
+	"github.com/labstack/echo"
 	"github.com/lob/pharos/internal/test"
+	"github.com/lob/pharos/pkg/pharos-api-server/apierrors"
 	"github.com/lob/pharos/pkg/pharos-api-server/application"
+	"github.com/lob/pharos/pkg/pharos-api-server/database"
 	"github.com/lob/pharos/pkg/util/model"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -91,6 +97,71 @@ func TestListHandler(t *testing.T) {
 		assert.Equal(tt, activeTestCluster.ID, response[0].ID)
 	})
 
+	t.Run("lists clusters with their latest status", func(tt *testing.T) {
+		test.TruncateTables(tt, h.app.DB)
+		clusters := []model.Cluster{defaultTestCluster, otherTestCluster}
+		err := h.app.DB.Insert(&clusters)
+		require.NoError(tt, err)
+		statuses := []model.ClusterStatus{
+			{ClusterID: defaultTestCluster.ID, Status: model.StatusReachable, DateChecked: time.Now().Add(-time.Minute)},
+			{ClusterID: defaultTestCluster.ID, Status: model.StatusUnreachable, DateChecked: time.Now()},
+		}
+		err = h.app.DB.Insert(&statuses)
+		require.NoError(tt, err)
+
+		c, rr := test.NewContext(tt, "GET", "", strings.NewReader(""), "application/json")
+
+		err = h.list(c)
+		assert.NoError(tt, err)
+		assert.Equal(tt, http.StatusOK, rr.Code)
+
+		var response []model.Cluster
+		err = json.Unmarshal(rr.Body.Bytes(), &response)
+		require.NoError(tt, err)
+		assert.Len(tt, response, 2)
+		for _, cluster := range response {
+			if cluster.ID == defaultTestCluster.ID {
+				require.NotNil(tt, cluster.Status)
+				assert.Equal(tt, model.StatusUnreachable, cluster.Status.Status)
+			} else {
+				assert.Nil(tt, cluster.Status)
+			}
+		}
+	})
+
+	t.Run("filters clusters by certificate authority expiry", func(tt *testing.T) {
+		test.TruncateTables(tt, h.app.DB)
+		soon := time.Now().Add(10 * 24 * time.Hour)
+		later := time.Now().Add(90 * 24 * time.Hour)
+		expiring := defaultTestCluster
+		expiring.CAExpiresAt = &soon
+		notExpiring := otherTestCluster
+		notExpiring.CAExpiresAt = &later
+		clusters := []model.Cluster{expiring, notExpiring, differentEnvironmentCluster}
+		err := h.app.DB.Insert(&clusters)
+		require.NoError(tt, err)
+
+		c, rr := test.NewContext(tt, "GET", "expiring_within=30d", strings.NewReader(""), "application/json")
+
+		err = h.list(c)
+		assert.NoError(tt, err)
+		assert.Equal(tt, http.StatusOK, rr.Code)
+
+		var response []model.Cluster
+		err = json.Unmarshal(rr.Body.Bytes(), &response)
+		require.NoError(tt, err)
+		assert.Len(tt, response, 1)
+		assert.Equal(tt, defaultTestCluster.ID, response[0].ID)
+	})
+
+	t.Run("errors with an invalid expiry window", func(tt *testing.T) {
+		c, _ := test.NewContext(tt, "GET", "expiring_within=soon", strings.NewReader(""), "application/json")
+
+		err := h.list(c)
+		assert.Error(tt, err)
+		assert.Contains(tt, err.Error(), "expiring_within must be a duration")
+	})
+
 	t.Run("does not list deleted clusters", func(tt *testing.T) {
 		test.TruncateTables(tt, h.app.DB)
 		clusters := []model.Cluster{deletedTestCluster}
@@ -108,6 +179,128 @@ func TestListHandler(t *testing.T) {
 		require.NoError(tt, err)
 		assert.Len(tt, response, 0)
 	})
+
+	t.Run("lists clusters changed since a resource version", func(tt *testing.T) {
+		test.TruncateTables(tt, h.app.DB)
+		clusters := []model.Cluster{defaultTestCluster, otherTestCluster}
+		err := h.app.DB.Insert(&clusters)
+		require.NoError(tt, err)
+
+		// Deleting a cluster gives it a new resource version.
+		_, err = h.app.DB.Model(&model.Cluster{}).Set("deleted = TRUE").Where("id = ?", defaultTestCluster.ID).Update()
+		require.NoError(tt, err)
+
+		c, rr := test.NewContext(tt, "GET", fmt.Sprintf("since=%d", clusters[1].ResourceVersion), strings.NewReader(""), "application/json")
+
+		err = h.list(c)
+		assert.NoError(tt, err)
+		assert.Equal(tt, http.StatusOK, rr.Code)
+
+		var response []model.Cluster
+		err = json.Unmarshal(rr.Body.Bytes(), &response)
+		require.NoError(tt, err)
+		require.Len(tt, response, 1)
+		assert.Equal(tt, defaultTestCluster.ID, response[0].ID)
+		assert.True(tt, response[0].Deleted)
+		assert.True(tt, response[0].ResourceVersion > clusters[1].ResourceVersion)
+	})
+
+	t.Run("watches for clusters changed since a resource version", func(tt *testing.T) {
+		watchPollInterval = 10 * time.Millisecond
+		defer func() { watchPollInterval = time.Second }()
+
+		test.TruncateTables(tt, h.app.DB)
+		clusters := []model.Cluster{defaultTestCluster}
+		err := h.app.DB.Insert(&clusters)
+		require.NoError(tt, err)
+
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			_, err := h.app.DB.Model(&model.Cluster{}).Set("active = TRUE").Where("id = ?", defaultTestCluster.ID).Update()
+			assert.NoError(tt, err)
+		}()
+
+		c, rr := test.NewContext(tt, "GET", fmt.Sprintf("watch=true&since=%d", clusters[0].ResourceVersion), strings.NewReader(""), "application/json")
+
+		err = h.list(c)
+		assert.NoError(tt, err)
+		assert.Equal(tt, http.StatusOK, rr.Code)
+
+		var response []model.Cluster
+		err = json.Unmarshal(rr.Body.Bytes(), &response)
+		require.NoError(tt, err)
+		require.Len(tt, response, 1)
+		assert.Equal(tt, defaultTestCluster.ID, response[0].ID)
+		assert.True(tt, response[0].Active)
+	})
+
+	t.Run("stops watching after the timeout", func(tt *testing.T) {
+		test.TruncateTables(tt, h.app.DB)
+		clusters := []model.Cluster{defaultTestCluster}
+		err := h.app.DB.Insert(&clusters)
+		require.NoError(tt, err)
+
+		c, rr := test.NewContext(tt, "GET", fmt.Sprintf("watch=true&since=%d&timeout=10ms", clusters[0].ResourceVersion), strings.NewReader(""), "application/json")
+
+		err = h.list(c)
+		assert.NoError(tt, err)
+		assert.Equal(tt, http.StatusOK, rr.Code)
+
+		var response []model.Cluster
+		err = json.Unmarshal(rr.Body.Bytes(), &response)
+		require.NoError(tt, err)
+		assert.Len(tt, response, 0)
+	})
+
+	t.Run("lists clusters modified since a time", func(tt *testing.T) {
+		test.TruncateTables(tt, h.app.DB)
+		modified := time.Now().Add(-time.Hour)
+		clusters := []model.Cluster{defaultTestCluster, otherTestCluster}
+		for i := range clusters {
+			clusters[i].DateModified = modified
+		}
+		err := h.app.DB.Insert(&clusters)
+		require.NoError(tt, err)
+
+		// Any update of a cluster bumps its modification date.
+		_, err = h.app.DB.Model(&model.Cluster{}).Set("deleted = TRUE").Where("id = ?", defaultTestCluster.ID).Update()
+		require.NoError(tt, err)
+
+		since := modified.Add(time.Minute).UTC().Format(time.RFC3339)
+		c, rr := test.NewContext(tt, "GET", "modified_since="+since, strings.NewReader(""), "application/json")
+
+		err = h.list(c)
+		assert.NoError(tt, err)
+		assert.Equal(tt, http.StatusOK, rr.Code)
+
+		var response []model.Cluster
+		err = json.Unmarshal(rr.Body.Bytes(), &response)
+		require.NoError(tt, err)
+		require.Len(tt, response, 1)
+		assert.Equal(tt, defaultTestCluster.ID, response[0].ID)
+		assert.True(tt, response[0].Deleted)
+		assert.True(tt, response[0].DateModified.After(modified))
+	})
+
+	t.Run("errors with an invalid resource version or timeout", func(tt *testing.T) {
+		c, _ := test.NewContext(tt, "GET", "since=latest", strings.NewReader(""), "application/json")
+
+		err := h.list(c)
+		assert.Error(tt, err)
+		assert.Contains(tt, err.Error(), "since must be a resource version")
+
+		c, _ = test.NewContext(tt, "GET", "watch=true&timeout=forever", strings.NewReader(""), "application/json")
+
+		err = h.list(c)
+		assert.Error(tt, err)
+		assert.Contains(tt, err.Error(), "timeout must be a duration")
+
+		c, _ = test.NewContext(tt, "GET", "modified_since=yesterday", strings.NewReader(""), "application/json")
+
+		err = h.list(c)
+		assert.Error(tt, err)
+		assert.Contains(tt, err.Error(), "modified_since must be a timestamp")
+	})
 }
 
 func TestRetrieveHandler(t *testing.T) {
@@ -165,6 +358,144 @@ func TestRetrieveHandler(t *testing.T) {
 		err := h.retrieve(c)
 		assert.Error(tt, err)
 		assert.Contains(tt, err.Error(), "not found")
+		apiErr, ok := apierrors.From(err)
+		require.True(tt, ok)
+		assert.Equal(tt, model.CodeClusterNotFound, apiErr.Code)
+	})
+}
+
+func TestResolveHandler(t *testing.T) {
+	h := newHandler(t)
+
+	resolve := func(tt *testing.T, name string) (model.Cluster, error) {
+		c, rr := test.NewContext(tt, "GET", "", strings.NewReader(""), "application/json")
+		c.SetParamNames("name")
+		c.SetParamValues(name)
+
+		var response model.Cluster
+		if err := h.resolve(c); err != nil {
+			return response, err
+		}
+		assert.Equal(tt, http.StatusOK, rr.Code)
+		err := json.Unmarshal(rr.Body.Bytes(), &response)
+		require.NoError(tt, err)
+		return response, nil
+	}
+
+	t.Run("resolves a cluster ID", func(tt *testing.T) {
+		test.TruncateTables(tt, h.app.DB)
+		clusters := []model.Cluster{defaultTestCluster, activeTestCluster}
+		err := h.app.DB.Insert(&clusters)
+		require.NoError(tt, err)
+
+		cluster, err := resolve(tt, defaultTestCluster.ID)
+		assert.NoError(tt, err)
+		assert.Equal(tt, defaultTestCluster.ID, cluster.ID)
+	})
+
+	t.Run("resolves an environment to its active cluster", func(tt *testing.T) {
+		test.TruncateTables(tt, h.app.DB)
+		clusters := []model.Cluster{defaultTestCluster, activeTestCluster, differentEnvironmentCluster}
+		err := h.app.DB.Insert(&clusters)
+		require.NoError(tt, err)
+
+		cluster, err := resolve(tt, "test")
+		assert.NoError(tt, err)
+		assert.Equal(tt, activeTestCluster.ID, cluster.ID)
+	})
+
+	t.Run("errors with the candidates when an environment has no active cluster", func(tt *testing.T) {
+		test.TruncateTables(tt, h.app.DB)
+		clusters := []model.Cluster{defaultTestCluster, otherTestCluster, deletedTestCluster}
+		err := h.app.DB.Insert(&clusters)
+		require.NoError(tt, err)
+
+		_, err = resolve(tt, "test")
+		require.Error(tt, err)
+		httpErr, ok := err.(*echo.HTTPError)
+		require.True(tt, ok)
+		assert.Equal(tt, http.StatusConflict, httpErr.Code)
+		assert.Equal(tt, model.CodeNoActiveCluster, httpErr.Message.(*model.Error).Code)
+		assert.Contains(tt, err.Error(), "candidates: test-1, test-2")
+		assert.NotContains(tt, err.Error(), deletedTestCluster.ID)
+	})
+
+	t.Run("errors when a name does not match any cluster", func(tt *testing.T) {
+		test.TruncateTables(tt, h.app.DB)
+		clusters := []model.Cluster{deletedTestCluster}
+		err := h.app.DB.Insert(&clusters)
+		require.NoError(tt, err)
+
+		_, err = resolve(tt, "random")
+		assert.Error(tt, err)
+		assert.Contains(tt, err.Error(), "not found")
+
+		_, err = resolve(tt, deletedTestCluster.ID)
+		assert.Error(tt, err)
+		assert.Contains(tt, err.Error(), "not found")
+	})
+}
+
+func TestStatusHandler(t *testing.T) {
+	h := newHandler(t)
+
+	t.Run("retrieves the latest status and history of a cluster", func(tt *testing.T) {
+		test.TruncateTables(tt, h.app.DB)
+		clusters := []model.Cluster{defaultTestCluster}
+		err := h.app.DB.Insert(&clusters)
+		require.NoError(tt, err)
+		lastSeen := time.Now().Add(-time.Minute)
+		statuses := []model.ClusterStatus{
+			{ClusterID: defaultTestCluster.ID, Status: model.StatusReachable, KubernetesVersion: "v1.14.1", LastSeen: &lastSeen, DateChecked: lastSeen},
+			{ClusterID: defaultTestCluster.ID, Status: model.StatusUnreachable, LastSeen: &lastSeen, DateChecked: time.Now()},
+		}
+		err = h.app.DB.Insert(&statuses)
+		require.NoError(tt, err)
+
+		c, rr := test.NewContext(tt, "GET", "", strings.NewReader(""), "application/json")
+		c.SetParamNames("id")
+		c.SetParamValues(defaultTestCluster.ID)
+
+		err = h.status(c)
+		assert.NoError(tt, err)
+		assert.Equal(tt, http.StatusOK, rr.Code)
+
+		var response statusResponse
+		err = json.Unmarshal(rr.Body.Bytes(), &response)
+		require.NoError(tt, err)
+		assert.Equal(tt, defaultTestCluster.ID, response.ClusterID)
+		assert.Equal(tt, model.StatusUnreachable, response.Status)
+		require.NotNil(tt, response.LastSeen)
+		assert.Len(tt, response.History, 2)
+		assert.Equal(tt, "v1.14.1", response.History[1].KubernetesVersion)
+	})
+
+	t.Run("errors when a cluster has not been checked", func(tt *testing.T) {
+		test.TruncateTables(tt, h.app.DB)
+		clusters := []model.Cluster{defaultTestCluster}
+		err := h.app.DB.Insert(&clusters)
+		require.NoError(tt, err)
+
+		c, _ := test.NewContext(tt, "GET", "", strings.NewReader(""), "application/json")
+		c.SetParamNames("id")
+		c.SetParamValues(defaultTestCluster.ID)
+
+		err = h.status(c)
+		require.Error(tt, err)
+		assert.Contains(tt, err.Error(), "has not been checked yet")
+		assert.Equal(tt, model.CodeStatusNotFound, err.(*echo.HTTPError).Message.(*model.Error).Code)
+	})
+
+	t.Run("errors retrieving the status of a non-existing cluster", func(tt *testing.T) {
+		test.TruncateTables(tt, h.app.DB)
+
+		c, _ := test.NewContext(tt, "GET", "", strings.NewReader(""), "application/json")
+		c.SetParamNames("id")
+		c.SetParamValues("random")
+
+		err := h.status(c)
+		assert.Error(tt, err)
+		assert.Contains(tt, err.Error(), "not found")
 	})
 }
 
@@ -196,6 +527,31 @@ func TestDeleteHandler(t *testing.T) {
 		assert.True(tt, cluster.Deleted)
 	})
 
+	t.Run("errors deleting a cluster modified since the If-Match version", func(tt *testing.T) {
+		test.TruncateTables(tt, h.app.DB)
+		cluster := defaultTestCluster
+		_, err := h.app.DB.Model(&cluster).Returning("*").Insert()
+		require.NoError(tt, err)
+
+		c, _ := test.NewContext(tt, "DELETE", "", strings.NewReader(""), "application/json")
+		c.Request().Header.Set("If-Match", fmt.Sprintf(`"%d"`, cluster.ResourceVersion-1))
+		c.SetParamNames("id")
+		c.SetParamValues(cluster.ID)
+
+		err = h.delete(c)
+		require.Error(tt, err)
+		assert.Equal(tt, http.StatusPreconditionFailed, err.(*echo.HTTPError).Code)
+
+		c, rr := test.NewContext(tt, "DELETE", "", strings.NewReader(""), "application/json")
+		c.Request().Header.Set("If-Match", fmt.Sprintf(`"%d"`, cluster.ResourceVersion))
+		c.SetParamNames("id")
+		c.SetParamValues(cluster.ID)
+
+		err = h.delete(c)
+		require.NoError(tt, err)
+		assert.NotEmpty(tt, rr.Header().Get("ETag"))
+	})
+
 	t.Run("errors deleting non-existing cluster", func(tt *testing.T) {
 		test.TruncateTables(tt, h.app.DB)
 
@@ -232,6 +588,94 @@ func TestCreateHandler(t *testing.T) {
 		assert.Equal(tt, "dGVzdA==", response.ClusterAuthorityData)
 		assert.Equal(tt, false, response.Deleted)
 		assert.Equal(tt, false, response.Active)
+		assert.Nil(tt, response.CAExpiresAt)
+	})
+
+	t.Run("notifies webhooks when a cluster is created", func(tt *testing.T) {
+		test.TruncateTables(tt, h.app.DB)
+		webhook := model.Webhook{URL: "https://hooks.example.com/pharos", Events: []string{model.EventClusterCreated}, Secret: "secret"}
+		_, err := h.app.DB.Model(&webhook).Insert()
+		require.NoError(tt, err)
+
+		payload := `{"id": "test-create", "environment": "test", "server_url": "http://localhost:6443", "cluster_authority_data": "dGVzdA=="}`
+
+		c, _ := test.NewContext(tt, "POST", "", strings.NewReader(payload), "application/json")
+
+		err = h.create(c)
+		require.NoError(tt, err)
+
+		var deliveries []model.WebhookDelivery
+		err = h.app.DB.Model(&deliveries).Select()
+		require.NoError(tt, err)
+		require.Len(tt, deliveries, 1)
+		assert.Equal(tt, webhook.ID, deliveries[0].WebhookID)
+		assert.Equal(tt, model.EventClusterCreated, deliveries[0].Event)
+	})
+
+	t.Run("records the expiry of the certificate authority", func(tt *testing.T) {
+		test.TruncateTables(tt, h.app.DB)
+
+		expiry := time.Now().Add(30 * 24 * time.Hour).UTC().Truncate(time.Second)
+		payload := fmt.Sprintf(`{"id": "test-create", "environment": "test", "server_url": "http://localhost:6443", "cluster_authority_data": "%s"}`, test.NewClusterAuthorityData(tt, expiry))
+
+		c, rr := test.NewContext(tt, "POST", "", strings.NewReader(payload), "application/json")
+
+		err := h.create(c)
+		assert.NoError(tt, err)
+		assert.Equal(tt, http.StatusOK, rr.Code)
+
+		var response model.Cluster
+		err = json.Unmarshal(rr.Body.Bytes(), &response)
+		require.NoError(tt, err)
+		require.NotNil(tt, response.CAExpiresAt)
+		assert.True(tt, expiry.Equal(*response.CAExpiresAt))
+	})
+
+	t.Run("records when the cluster expires", func(tt *testing.T) {
+		test.TruncateTables(tt, h.app.DB)
+
+		expiry := time.Now().Add(72 * time.Hour).UTC().Truncate(time.Second)
+		payload := fmt.Sprintf(`{"id": "preview-create", "environment": "preview", "server_url": "http://localhost:6443", "cluster_authority_data": "dGVzdA==", "expires_at": "%s"}`, expiry.Format(time.RFC3339))
+
+		c, rr := test.NewContext(tt, "POST", "", strings.NewReader(payload), "application/json")
+
+		err := h.create(c)
+		require.NoError(tt, err)
+
+		var response model.Cluster
+		err = json.Unmarshal(rr.Body.Bytes(), &response)
+		require.NoError(tt, err)
+		require.NotNil(tt, response.ExpiresAt)
+		assert.True(tt, expiry.Equal(*response.ExpiresAt))
+	})
+
+	t.Run("errors creating a cluster that has already expired", func(tt *testing.T) {
+		test.TruncateTables(tt, h.app.DB)
+
+		payload := `{"id": "preview-create", "environment": "preview", "server_url": "http://localhost:6443", "cluster_authority_data": "dGVzdA==", "expires_at": "2019-01-01T00:00:00Z"}`
+		c, _ := test.NewContext(tt, "POST", "", strings.NewReader(payload), "application/json")
+
+		err := h.create(c)
+		require.Error(tt, err)
+		assert.Contains(tt, err.Error(), "expires_at must be in the future")
+	})
+
+	t.Run("errors creating a cluster that already exists", func(tt *testing.T) {
+		test.TruncateTables(tt, h.app.DB)
+		clusters := []model.Cluster{defaultTestCluster}
+		err := h.app.DB.Insert(&clusters)
+		require.NoError(tt, err)
+
+		payload := `{"id": "test-1", "environment": "test", "server_url": "http://localhost:6443", "cluster_authority_data": "dGVzdA=="}`
+		c, _ := test.NewContext(tt, "POST", "", strings.NewReader(payload), "application/json")
+
+		err = h.create(c)
+		require.Error(tt, err)
+		apiErr, ok := apierrors.From(err)
+		require.True(tt, ok)
+		assert.Equal(tt, model.CodeClusterExists, apiErr.Code)
+		assert.Equal(tt, http.StatusConflict, apiErr.StatusCode)
+		assert.Equal(tt, "cluster test-1 already exists", apiErr.Message)
 	})
 
 	t.Run("errors with invalid payload", func(tt *testing.T) {
@@ -277,6 +721,80 @@ func TestCreateHandler(t *testing.T) {
 
 }
 
+func TestUpsertHandler(t *testing.T) {
+	h := newHandler(t)
+
+	upsert := func(tt *testing.T, id, payload string) (*httptest.ResponseRecorder, error) {
+		tt.Helper()
+		c, rr := test.NewContext(tt, "PUT", "", strings.NewReader(payload), "application/json")
+		c.SetParamNames("id")
+		c.SetParamValues(id)
+		return rr, h.upsert(c)
+	}
+
+	t.Run("creates a cluster that doesn't exist", func(tt *testing.T) {
+		test.TruncateTables(tt, h.app.DB)
+
+		rr, err := upsert(tt, "test-upsert", `{"environment": "test", "server_url": "http://localhost:6443", "cluster_authority_data": "dGVzdA=="}`)
+		require.NoError(tt, err)
+		assert.Equal(tt, http.StatusCreated, rr.Code)
+
+		var cluster model.Cluster
+		err = h.app.DB.Model(&cluster).Where("id = ?", "test-upsert").First()
+		require.NoError(tt, err)
+		assert.Equal(tt, "http://localhost:6443", cluster.ServerURL)
+	})
+
+	t.Run("replaces the registration of an existing cluster", func(tt *testing.T) {
+		test.TruncateTables(tt, h.app.DB)
+		clusters := []model.Cluster{activeTestCluster}
+		err := h.app.DB.Insert(&clusters)
+		require.NoError(tt, err)
+
+		payload := `{"id": "test-active", "environment": "test", "server_url": "http://test-active.localhost:6443", "cluster_authority_data": "dGVzdA=="}`
+		rr, err := upsert(tt, activeTestCluster.ID, payload)
+		require.NoError(tt, err)
+		assert.Equal(tt, http.StatusOK, rr.Code)
+
+		var response model.Cluster
+		err = json.Unmarshal(rr.Body.Bytes(), &response)
+		require.NoError(tt, err)
+		assert.Equal(tt, "http://test-active.localhost:6443", response.ServerURL)
+		assert.True(tt, response.Active)
+
+		// Repeating the same registration changes nothing.
+		rr, err = upsert(tt, activeTestCluster.ID, payload)
+		require.NoError(tt, err)
+		var repeated model.Cluster
+		err = json.Unmarshal(rr.Body.Bytes(), &repeated)
+		require.NoError(tt, err)
+		assert.Equal(tt, response.ResourceVersion, repeated.ResourceVersion)
+	})
+
+	t.Run("restores a deleted cluster", func(tt *testing.T) {
+		test.TruncateTables(tt, h.app.DB)
+		clusters := []model.Cluster{deletedTestCluster}
+		err := h.app.DB.Insert(&clusters)
+		require.NoError(tt, err)
+
+		rr, err := upsert(tt, deletedTestCluster.ID, `{"environment": "test", "server_url": "http://localhost:6443", "cluster_authority_data": "dGVzdA=="}`)
+		require.NoError(tt, err)
+		assert.Equal(tt, http.StatusCreated, rr.Code)
+
+		var cluster model.Cluster
+		err = h.app.DB.Model(&cluster).Where("id = ?", deletedTestCluster.ID).First()
+		require.NoError(tt, err)
+		assert.False(tt, cluster.Deleted)
+		assert.False(tt, cluster.Active)
+	})
+
+	t.Run("errors when the ID doesn't match the path", func(tt *testing.T) {
+		_, err := upsert(tt, "test-1", `{"id": "test-2", "environment": "test", "server_url": "http://localhost:6443", "cluster_authority_data": "dGVzdA=="}`)
+		require.Error(tt, err)
+		assert.Contains(tt, err.Error(), "id must match the cluster ID in the path")
+	})
+}
+
 func TestUpdateHandler(t *testing.T) {
 	h := newHandler(t)
 
@@ -333,6 +851,288 @@ func TestUpdateHandler(t *testing.T) {
 		assert.False(tt, fetchedClusters[1].Active)
 	})
 
+	t.Run("updates the modification date of every changed cluster", func(tt *testing.T) {
+		test.TruncateTables(tt, h.app.DB)
+		modified := time.Now().Add(-time.Hour)
+		clusters := []model.Cluster{defaultTestCluster, activeTestCluster, differentEnvironmentCluster}
+		for i := range clusters {
+			clusters[i].DateModified = modified
+		}
+		err := h.app.DB.Insert(&clusters)
+		require.NoError(tt, err)
+
+		c, rr := test.NewContext(tt, "POST", "", strings.NewReader(`{"active": true}`), "application/json")
+		c.SetParamNames("id")
+		c.SetParamValues(defaultTestCluster.ID)
+
+		err = h.update(c)
+		require.NoError(tt, err)
+
+		var response model.Cluster
+		err = json.Unmarshal(rr.Body.Bytes(), &response)
+		require.NoError(tt, err)
+		assert.True(tt, response.DateModified.After(modified))
+
+		// The deactivated cluster is modified too, but not the unchanged one.
+		var fetchedClusters []model.Cluster
+		err = h.app.DB.Model(&fetchedClusters).Order("id").Select()
+		require.NoError(tt, err)
+		require.Len(tt, fetchedClusters, 3)
+		assert.Equal(tt, differentEnvironmentCluster.ID, fetchedClusters[0].ID)
+		assert.WithinDuration(tt, modified, fetchedClusters[0].DateModified, time.Millisecond)
+		assert.True(tt, fetchedClusters[1].DateModified.After(modified))
+		assert.True(tt, fetchedClusters[2].DateModified.After(modified))
+	})
+
+	t.Run("notifies webhooks when a cluster is activated", func(tt *testing.T) {
+		test.TruncateTables(tt, h.app.DB)
+		clusters := []model.Cluster{defaultTestCluster, activeTestCluster}
+		err := h.app.DB.Insert(&clusters)
+		require.NoError(tt, err)
+		webhook := model.Webhook{URL: "https://hooks.example.com/pharos", Events: model.Events, Secret: "secret"}
+		_, err = h.app.DB.Model(&webhook).Insert()
+		require.NoError(tt, err)
+
+		// Updating an active cluster doesn't activate it.
+		for _, id := range []string{activeTestCluster.ID, defaultTestCluster.ID} {
+			c, _ := test.NewContext(tt, "POST", "", strings.NewReader(`{"active": true}`), "application/json")
+			c.SetParamNames("id")
+			c.SetParamValues(id)
+
+			err = h.update(c)
+			require.NoError(tt, err)
+		}
+
+		var deliveries []model.WebhookDelivery
+		err = h.app.DB.Model(&deliveries).Select()
+		require.NoError(tt, err)
+		require.Len(tt, deliveries, 1)
+		assert.Equal(tt, model.EventClusterActivated, deliveries[0].Event)
+		assert.Contains(tt, deliveries[0].Payload, defaultTestCluster.ID)
+	})
+
+	t.Run("honors If-Match with the cluster's resource version", func(tt *testing.T) {
+		test.TruncateTables(tt, h.app.DB)
+		cluster := defaultTestCluster
+		_, err := h.app.DB.Model(&cluster).Returning("*").Insert()
+		require.NoError(tt, err)
+
+		c, rr := test.NewContext(tt, "POST", "", strings.NewReader(`{"active": true}`), "application/json")
+		c.Request().Header.Set("If-Match", fmt.Sprintf(`"%d"`, cluster.ResourceVersion))
+		c.SetParamNames("id")
+		c.SetParamValues(cluster.ID)
+
+		err = h.update(c)
+		require.NoError(tt, err)
+
+		var response model.Cluster
+		err = json.Unmarshal(rr.Body.Bytes(), &response)
+		require.NoError(tt, err)
+		assert.True(tt, response.ResourceVersion > cluster.ResourceVersion)
+		assert.Equal(tt, fmt.Sprintf(`"%d"`, response.ResourceVersion), rr.Header().Get("ETag"))
+
+		// The version the update was made against is now stale.
+		c, _ = test.NewContext(tt, "POST", "", strings.NewReader(`{"active": true}`), "application/json")
+		c.Request().Header.Set("If-Match", fmt.Sprintf(`"%d"`, cluster.ResourceVersion))
+		c.SetParamNames("id")
+		c.SetParamValues(cluster.ID)
+
+		err = h.update(c)
+		require.Error(tt, err)
+		assert.Equal(tt, errModified, err)
+		assert.Equal(tt, http.StatusPreconditionFailed, err.(*echo.HTTPError).Code)
+
+		var fetchedCluster model.Cluster
+		err = h.app.DB.Model(&fetchedCluster).Where("id = ?", cluster.ID).First()
+		require.NoError(tt, err)
+		assert.True(tt, fetchedCluster.Active)
+		assert.Equal(tt, response.ResourceVersion, fetchedCluster.ResourceVersion)
+	})
+
+	t.Run("activates only one of two clusters activated concurrently in an environment", func(tt *testing.T) {
+		test.TruncateTables(tt, h.app.DB)
+		clusters := []model.Cluster{activeTestCluster, defaultTestCluster, otherTestCluster}
+		_, err := h.app.DB.Model(&clusters).Returning("*").Insert()
+		require.NoError(tt, err)
+
+		// Both activations wait for a transaction holding the resource version
+		// lock, so that they're made against the same versions.
+		blocker, err := h.app.DB.Begin()
+		require.NoError(tt, err)
+		defer blocker.Rollback()
+		err = database.LockResourceVersions(blocker)
+		require.NoError(tt, err)
+
+		errs := make(chan error, 2)
+		for _, cluster := range clusters[1:] {
+			c, _ := test.NewContext(tt, "POST", "", strings.NewReader(`{"active": true}`), "application/json")
+			c.Request().Header.Set("If-Match", fmt.Sprintf(`"%d"`, cluster.ResourceVersion))
+			c.SetParamNames("id")
+			c.SetParamValues(cluster.ID)
+			go func() {
+				errs <- h.update(c)
+			}()
+		}
+
+		waiting := 0
+		for deadline := time.Now().Add(5 * time.Second); waiting < 2 && time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+			_, err := h.app.DB.QueryOne(pg.Scan(&waiting), "SELECT count(*) FROM pg_locks WHERE locktype = 'advisory' AND NOT granted")
+			require.NoError(tt, err)
+		}
+		require.Equal(tt, 2, waiting)
+		require.NoError(tt, blocker.Commit())
+
+		var failed []error
+		for range clusters[1:] {
+			if err := <-errs; err != nil {
+				failed = append(failed, err)
+			}
+		}
+		require.Len(tt, failed, 1)
+		assert.Equal(tt, http.StatusPreconditionFailed, failed[0].(*echo.HTTPError).Code)
+
+		var active []model.Cluster
+		err = h.app.DB.Model(&active).Where("environment = ?", "test").Where("active = TRUE").Select()
+		require.NoError(tt, err)
+		require.Len(tt, active, 1)
+		assert.NotEqual(tt, activeTestCluster.ID, active[0].ID)
+	})
+
+	t.Run("errors activating a cluster when the active cluster has changed since the If-Match version", func(tt *testing.T) {
+		test.TruncateTables(tt, h.app.DB)
+		cluster := defaultTestCluster
+		_, err := h.app.DB.Model(&cluster).Returning("*").Insert()
+		require.NoError(tt, err)
+		active := activeTestCluster
+		_, err = h.app.DB.Model(&active).Insert()
+		require.NoError(tt, err)
+
+		c, _ := test.NewContext(tt, "POST", "", strings.NewReader(`{"active": true}`), "application/json")
+		c.Request().Header.Set("If-Match", fmt.Sprintf(`"%d"`, cluster.ResourceVersion))
+		c.SetParamNames("id")
+		c.SetParamValues(cluster.ID)
+
+		err = h.update(c)
+		require.Error(tt, err)
+		assert.Equal(tt, http.StatusPreconditionFailed, err.(*echo.HTTPError).Code)
+		assert.Contains(tt, err.Error(), "active cluster test-active of environment test has been modified")
+
+		var fetchedClusters []model.Cluster
+		err = h.app.DB.Model(&fetchedClusters).Order("id").Select()
+		require.NoError(tt, err)
+		require.Len(tt, fetchedClusters, 2)
+		assert.False(tt, fetchedClusters[0].Active)
+		assert.True(tt, fetchedClusters[1].Active)
+	})
+
+	t.Run("keeps only the updated cluster of an environment with more than one active cluster", func(tt *testing.T) {
+		// Databases that predate the active environment index can have more
+		// than one active cluster per environment, which pharos doctor tells
+		// users to fix by updating the cluster to keep. The index is restored
+		// however the test ends, as later tests rely on it.
+		defer func() {
+			test.TruncateTables(tt, h.app.DB)
+			_, err := h.app.DB.Exec("CREATE UNIQUE INDEX IF NOT EXISTS " + activeEnvironmentIndex + " ON clusters (environment) WHERE active AND NOT deleted")
+			assert.NoError(tt, err)
+		}()
+		_, err := h.app.DB.Exec("DROP INDEX " + activeEnvironmentIndex)
+		require.NoError(tt, err)
+
+		test.TruncateTables(tt, h.app.DB)
+		kept := defaultTestCluster
+		kept.Active = true
+		clusters := []model.Cluster{kept, activeTestCluster}
+		err = h.app.DB.Insert(&clusters)
+		require.NoError(tt, err)
+
+		c, rr := test.NewContext(tt, "POST", "", strings.NewReader(`{"active": true}`), "application/json")
+		c.SetParamNames("id")
+		c.SetParamValues(kept.ID)
+
+		err = h.update(c)
+		require.NoError(tt, err)
+		assert.Equal(tt, http.StatusOK, rr.Code)
+
+		var fetchedClusters []model.Cluster
+		err = h.app.DB.Model(&fetchedClusters).Order("id").Select()
+		require.NoError(tt, err)
+		require.Len(tt, fetchedClusters, 2)
+		assert.Equal(tt, kept.ID, fetchedClusters[0].ID)
+		assert.True(tt, fetchedClusters[0].Active)
+		assert.Equal(tt, activeTestCluster.ID, fetchedClusters[1].ID)
+		assert.False(tt, fetchedClusters[1].Active)
+	})
+
+	t.Run("extends the expiry of a cluster without changing whether it's active", func(tt *testing.T) {
+		test.TruncateTables(tt, h.app.DB)
+		cluster := activeTestCluster
+		expiry := time.Now().Add(time.Hour)
+		cluster.ExpiresAt = &expiry
+		_, err := h.app.DB.Model(&cluster).Insert()
+		require.NoError(tt, err)
+
+		extended := time.Now().Add(72 * time.Hour).UTC().Truncate(time.Second)
+		payload := fmt.Sprintf(`{"expires_at": "%s"}`, extended.Format(time.RFC3339))
+		c, _ := test.NewContext(tt, "POST", "", strings.NewReader(payload), "application/json")
+		c.SetParamNames("id")
+		c.SetParamValues(cluster.ID)
+
+		err = h.update(c)
+		require.NoError(tt, err)
+
+		var fetchedCluster model.Cluster
+		err = h.app.DB.Model(&fetchedCluster).Where("id = ?", cluster.ID).First()
+		require.NoError(tt, err)
+		assert.True(tt, fetchedCluster.Active)
+		require.NotNil(tt, fetchedCluster.ExpiresAt)
+		assert.True(tt, extended.Equal(*fetchedCluster.ExpiresAt))
+	})
+
+	t.Run("deactivates a cluster", func(tt *testing.T) {
+		test.TruncateTables(tt, h.app.DB)
+		clusters := []model.Cluster{activeTestCluster}
+		err := h.app.DB.Insert(&clusters)
+		require.NoError(tt, err)
+
+		c, _ := test.NewContext(tt, "POST", "", strings.NewReader(`{"active": false}`), "application/json")
+		c.SetParamNames("id")
+		c.SetParamValues(activeTestCluster.ID)
+
+		err = h.update(c)
+		require.NoError(tt, err)
+
+		var fetchedCluster model.Cluster
+		err = h.app.DB.Model(&fetchedCluster).Where("id = ?", activeTestCluster.ID).First()
+		require.NoError(tt, err)
+		assert.False(tt, fetchedCluster.Active)
+	})
+
+	t.Run("errors without any changes", func(tt *testing.T) {
+		c, _ := test.NewContext(tt, "POST", "", strings.NewReader(`{}`), "application/json")
+		c.SetParamNames("id")
+		c.SetParamValues(defaultTestCluster.ID)
+
+		err := h.update(c)
+		require.Error(tt, err)
+		assert.Contains(tt, err.Error(), "active or expires_at is required")
+	})
+
+	t.Run("errors with an invalid If-Match header", func(tt *testing.T) {
+		test.TruncateTables(tt, h.app.DB)
+		clusters := []model.Cluster{defaultTestCluster}
+		err := h.app.DB.Insert(&clusters)
+		require.NoError(tt, err)
+
+		c, _ := test.NewContext(tt, "POST", "", strings.NewReader(`{"active": true}`), "application/json")
+		c.Request().Header.Set("If-Match", `"abc"`)
+		c.SetParamNames("id")
+		c.SetParamValues(defaultTestCluster.ID)
+
+		err = h.update(c)
+		require.Error(tt, err)
+		assert.Equal(tt, http.StatusPreconditionFailed, err.(*echo.HTTPError).Code)
+	})
+
 	t.Run("errors updated non-existent cluster", func(tt *testing.T) {
 		test.TruncateTables(tt, h.app.DB)
 
@@ -346,6 +1146,33 @@ func TestUpdateHandler(t *testing.T) {
 	})
 }
 
+func TestIsActiveConflict(t *testing.T) {
+	h := newHandler(t)
+
+	t.Run("detects a second active cluster in an environment", func(tt *testing.T) {
+		test.TruncateTables(tt, h.app.DB)
+		clusters := []model.Cluster{activeTestCluster, defaultTestCluster}
+		err := h.app.DB.Insert(&clusters)
+		require.NoError(tt, err)
+
+		// Deleted clusters don't count.
+		deleted := deletedTestCluster
+		deleted.Active = true
+		err = h.app.DB.Insert(&deleted)
+		require.NoError(tt, err)
+
+		_, err = h.app.DB.Model(&model.Cluster{}).Set("active = TRUE").Where("id = ?", defaultTestCluster.ID).Update()
+		require.Error(tt, err)
+		assert.True(tt, isActiveConflict(err))
+
+		apiErr := activeConflict("test").(*echo.HTTPError).Message.(*model.Error)
+		assert.Equal(tt, model.CodeActiveClusterExists, apiErr.Code)
+
+		_, err = h.app.DB.Model(&model.Cluster{}).Set("active = TRUE").Where("id = ?", "missing").Update()
+		assert.False(tt, isActiveConflict(err))
+	})
+}
+
 func newHandler(t *testing.T) handler {
 	t.Helper()
 
@@ -0,0 +1,73 @@
+package clusters
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo"
+	"github.com/lob/pharos/pkg/pharos-api-server/apierrors"
+	"github.com/lob/pharos/pkg/util/kubeconfig"
+	"github.com/lob/pharos/pkg/util/model"
+	"github.com/lob/pharos/pkg/util/selector"
+	"github.com/pkg/errors"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+type kubeconfigQuery struct {
+	Environment     string `query:"environment"`
+	Selector        string `query:"selector"`
+	IncludeInactive bool   `query:"include_inactive"`
+}
+
+// renderKubeconfig renders a kubeconfig for the requested clusters using the same
+// logic as `pharos clusters sync`, for consumers that can't use the CLI.
+func (h *handler) renderKubeconfig(c echo.Context) error {
+	query := kubeconfigQuery{}
+	if err := c.Bind(&query); err != nil {
+		return err
+	}
+
+	var sel selector.Selector
+	if query.Selector != "" {
+		var err error
+		sel, err = selector.Parse(query.Selector)
+		if err != nil {
+			return apierrors.New(http.StatusUnprocessableEntity, model.CodeValidationFailed, err.Error())
+		}
+	}
+
+	clusters := make([]model.Cluster, 0)
+
+	q := h.app.DB.
+		Model(&clusters).
+		Where("deleted = FALSE").
+		Order("id")
+
+	if query.Environment != "" {
+		q = q.Where("environment = ?", query.Environment)
+	}
+
+	if !query.IncludeInactive {
+		q = q.Where("active = TRUE")
+	}
+
+	err := q.Select()
+	if err != nil {
+		return err
+	}
+
+	if sel != nil {
+		clusters = sel.Filter(clusters)
+	}
+
+	kubeConfig, err := kubeconfig.DefaultNaming.New(clusters)
+	if err != nil {
+		return errors.Wrap(err, "failed to render kubeconfig")
+	}
+
+	yaml, err := clientcmd.Write(*kubeConfig)
+	if err != nil {
+		return errors.Wrap(err, "failed to render kubeconfig")
+	}
+
+	return c.Blob(http.StatusOK, "application/yaml", yaml)
+}
@@ -0,0 +1,64 @@
+package clusters
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/lob/pharos/internal/test"
+	"github.com/lob/pharos/pkg/util/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+func TestRenderKubeconfigHandler(t *testing.T) {
+	h := newHandler(t)
+
+	clusters := []model.Cluster{defaultTestCluster, deletedTestCluster, activeTestCluster, differentEnvironmentCluster}
+
+	t.Run("renders a kubeconfig for active clusters", func(tt *testing.T) {
+		test.TruncateTables(tt, h.app.DB)
+		err := h.app.DB.Insert(&clusters)
+		require.NoError(tt, err)
+
+		c, rr := test.NewContext(tt, "GET", "", strings.NewReader(""), "application/json")
+
+		err = h.renderKubeconfig(c)
+		assert.NoError(tt, err)
+		assert.Equal(tt, http.StatusOK, rr.Code)
+		assert.Equal(tt, "application/yaml", rr.Header().Get("Content-Type"))
+
+		kubeConfig, err := clientcmd.Load(rr.Body.Bytes())
+		require.NoError(tt, err)
+		assert.Len(tt, kubeConfig.Clusters, 1)
+		assert.Contains(tt, kubeConfig.Clusters, activeTestCluster.ID)
+		require.Contains(tt, kubeConfig.Contexts, activeTestCluster.Environment)
+		assert.Equal(tt, activeTestCluster.ID, kubeConfig.Contexts[activeTestCluster.Environment].Cluster)
+	})
+
+	t.Run("renders a kubeconfig for the clusters matching the query", func(tt *testing.T) {
+		test.TruncateTables(tt, h.app.DB)
+		err := h.app.DB.Insert(&clusters)
+		require.NoError(tt, err)
+
+		c, rr := test.NewContext(tt, "GET", "environment=test&include_inactive=true&selector=active=false", strings.NewReader(""), "application/json")
+
+		err = h.renderKubeconfig(c)
+		assert.NoError(tt, err)
+		assert.Equal(tt, http.StatusOK, rr.Code)
+
+		kubeConfig, err := clientcmd.Load(rr.Body.Bytes())
+		require.NoError(tt, err)
+		assert.Len(tt, kubeConfig.Clusters, 1)
+		assert.Contains(tt, kubeConfig.Clusters, defaultTestCluster.ID)
+	})
+
+	t.Run("errors with an invalid selector", func(tt *testing.T) {
+		c, _ := test.NewContext(tt, "GET", "selector=region", strings.NewReader(""), "application/json")
+
+		err := h.renderKubeconfig(c)
+		assert.Error(tt, err)
+		assert.Contains(tt, err.Error(), "expected key=value")
+	})
+}
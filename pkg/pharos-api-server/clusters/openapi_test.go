@@ -0,0 +1,35 @@
+package clusters
+
+import (
+	"testing"
+
+	"github.com/lob/pharos/internal/test"
+	"github.com/lob/pharos/pkg/pharos-api-server/openapi"
+	"github.com/lob/pharos/pkg/pharos-api-server/versioning"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenAPI(t *testing.T) {
+	spec, err := openapi.Load()
+	require.NoError(t, err)
+
+	t.Run("documents every field the handlers bind and return", func(tt *testing.T) {
+		schemas := map[string]interface{}{
+			"CreateClusterParams":  createParams{},
+			"UpsertClusterParams":  upsertParams{},
+			"UpdateClusterParams":  updateParams{},
+			"BatchParams":          batchParams{},
+			"BatchOperation":       batchOperation{},
+			"ClusterStatusHistory": statusResponse{},
+		}
+		for name, v := range schemas {
+			assert.Equal(tt, test.FieldNames(v, "json"), spec.Properties(name), "schema %s doesn't match its params", name)
+		}
+	})
+
+	t.Run("documents every query parameter", func(tt *testing.T) {
+		assert.Equal(tt, test.FieldNames(listQuery{}, "query"), spec.QueryParameters("GET", versioning.Prefix+"/clusters"))
+		assert.Equal(tt, test.FieldNames(kubeconfigQuery{}, "query"), spec.QueryParameters("GET", versioning.Prefix+"/kubeconfig"))
+	})
+}
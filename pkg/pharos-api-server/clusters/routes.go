@@ -1,21 +1,32 @@
 package clusters
 
 import (
+	"net/http"
+
 	"github.com/labstack/echo"
 	"github.com/lob/pharos/pkg/pharos-api-server/application"
 	"github.com/lob/pharos/pkg/pharos-api-server/authentication"
 	"github.com/lob/pharos/pkg/pharos-api-server/authorization"
+	"github.com/lob/pharos/pkg/pharos-api-server/idempotency"
+	"github.com/lob/pharos/pkg/pharos-api-server/versioning"
 )
 
-// RegisterRoutes takes in an Echo router and registers routes onto it.
+// RegisterRoutes takes in an Echo router and registers routes onto it. Routes
+// are registered under the /v1 prefix, with deprecated unversioned aliases for
+// older CLIs.
 func RegisterRoutes(e *echo.Echo, app application.App) {
 	h := handler{app}
 
 	config := app.Config
 
-	e.GET("/clusters", h.list, authentication.Middleware(app.TokenVerifier), authorization.Middleware(config.Permissions.Read))
-	e.GET("/clusters/:id", h.retrieve, authentication.Middleware(app.TokenVerifier), authorization.Middleware(config.Permissions.Read))
-	e.DELETE("/clusters/:id", h.delete, authentication.Middleware(app.TokenVerifier), authorization.Middleware(config.Permissions.Admin))
-	e.POST("/clusters", h.create, authentication.Middleware(app.TokenVerifier), authorization.Middleware(config.Permissions.Write))
-	e.POST("/clusters/:id", h.update, authentication.Middleware(app.TokenVerifier), authorization.Middleware(config.Permissions.Admin))
+	versioning.AddWithAlias(e, http.MethodGet, "/clusters", h.list, authentication.Middleware(app.TokenVerifier), authorization.Middleware(config.Permissions.Read))
+	versioning.AddWithAlias(e, http.MethodGet, "/clusters/:id", h.retrieve, authentication.Middleware(app.TokenVerifier), authorization.Middleware(config.Permissions.Read))
+	versioning.Add(e, http.MethodGet, "/clusters/:id/status", h.status, authentication.Middleware(app.TokenVerifier), authorization.Middleware(config.Permissions.Read))
+	versioning.AddWithAlias(e, http.MethodDelete, "/clusters/:id", h.delete, authentication.Middleware(app.TokenVerifier), authorization.Middleware(config.Permissions.Admin))
+	versioning.AddWithAlias(e, http.MethodPost, "/clusters", h.create, authentication.Middleware(app.TokenVerifier), authorization.Middleware(config.Permissions.Write), idempotency.Middleware(app))
+	versioning.Add(e, http.MethodPost, "/clusters/batch", h.batch, authentication.Middleware(app.TokenVerifier), authorization.Middleware(config.Permissions.Admin))
+	versioning.Add(e, http.MethodPut, "/clusters/:id", h.upsert, authentication.Middleware(app.TokenVerifier), authorization.Middleware(config.Permissions.Admin), idempotency.Middleware(app))
+	versioning.Add(e, http.MethodGet, "/resolve/:name", h.resolve, authentication.Middleware(app.TokenVerifier), authorization.Middleware(config.Permissions.Read))
+	versioning.Add(e, http.MethodGet, "/kubeconfig", h.renderKubeconfig, authentication.Middleware(app.TokenVerifier), authorization.Middleware(config.Permissions.Read))
+	versioning.AddWithAlias(e, http.MethodPost, "/clusters/:id", h.update, authentication.Middleware(app.TokenVerifier), authorization.Middleware(config.Permissions.Admin))
 }
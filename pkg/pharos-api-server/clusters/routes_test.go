@@ -25,5 +25,5 @@ func TestRegisterRoutes(t *testing.T) {
 
 	RegisterRoutes(e, app)
 
-	assert.Len(t, e.Routes(), 5)
+	assert.Len(t, e.Routes(), 15)
 }
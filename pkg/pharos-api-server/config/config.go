@@ -3,24 +3,36 @@ package config
 import (
 	"os"
 	"strings"
+	"time"
 )
 
 // Config contains the environment specific configuration values needed by the
 // application.
 type Config struct {
-	DatabaseHost     string
-	DatabasePort     int
-	DatabaseName     string
-	DatabaseUser     string
-	DatabasePassword string
-	DatabaseSSLMode  bool
-	Environment      string
-	Hostname         string
-	Port             int
-	Permissions      *Permissions
-	SentryDSN        string
-	StatsdHost       string
-	StatsdPort       int
+	DatabaseHost         string
+	DatabasePort         int
+	DatabaseName         string
+	DatabaseUser         string
+	DatabasePassword     string
+	DatabaseSSLMode      bool
+	DiscoveryInterval    time.Duration
+	DiscoveryTargets     []DiscoveryTarget
+	Environment          string
+	HealthCheckInterval  time.Duration
+	HealthCheckRetention time.Duration
+	HealthCheckTimeout   time.Duration
+	Hostname             string
+	MinimumCLIVersion    string
+	Port                 int
+	Permissions          *Permissions
+	ReaperInterval       time.Duration
+	SentryDSN            string
+	StatsdHost           string
+	StatsdPort           int
+	WatchTimeout         time.Duration
+	WebhookInterval      time.Duration
+	WebhookMaxAttempts   int
+	WebhookTimeout       time.Duration
 }
 
 // Permissions contains lists of AWS IAM ARNs that are to be associated with
@@ -31,6 +43,14 @@ type Permissions struct {
 	Write []string
 }
 
+// DiscoveryTarget is an AWS account and region in which EKS clusters are
+// discovered. The account is accessed by assuming RoleARN, or with the
+// server's own credentials if it is empty.
+type DiscoveryTarget struct {
+	RoleARN string
+	Region  string
+}
+
 const env = "ENVIRONMENT"
 
 // New returns an instance of Config
@@ -49,6 +69,23 @@ func New() Config {
 		DatabasePassword: os.Getenv("DATABASE_PASSWORD"),
 		DatabaseSSLMode:  true,
 		Permissions:      &Permissions{},
+		// The health checker is disabled when the interval is set to zero.
+		HealthCheckInterval:  time.Minute,
+		HealthCheckRetention: 7 * 24 * time.Hour,
+		HealthCheckTimeout:   5 * time.Second,
+		// Discovery only runs when at least one target is configured.
+		DiscoveryInterval: 10 * time.Minute,
+		// Expired clusters are not deleted when the interval is set to zero.
+		ReaperInterval: time.Minute,
+		// Watches of the cluster list are held open for at most this long.
+		WatchTimeout: time.Minute,
+		// Webhook deliveries are disabled when the interval is set to zero.
+		WebhookInterval:    5 * time.Second,
+		WebhookMaxAttempts: 10,
+		WebhookTimeout:     10 * time.Second,
+		// CLIs older than this version are warned to upgrade. No version is
+		// reported when it is empty.
+		MinimumCLIVersion: os.Getenv("MINIMUM_CLI_VERSION"),
 	}
 
 	switch os.Getenv(env) {
@@ -62,6 +99,21 @@ func New() Config {
 		cfg.DatabaseName = "pharos_test"
 		cfg.DatabaseUser = "pharos_admin"
 		cfg.DatabaseSSLMode = false
+		cfg.HealthCheckInterval = 0
+		cfg.DiscoveryInterval = 0
+		cfg.WebhookInterval = 0
+		cfg.ReaperInterval = 0
+	}
+
+	// Load EKS discovery targets, given as "region" or "role_arn@region".
+	if targets := os.Getenv("DISCOVERY_TARGETS"); targets != "" {
+		for _, target := range strings.Split(targets, ",") {
+			t := DiscoveryTarget{Region: target}
+			if i := strings.LastIndex(target, "@"); i >= 0 {
+				t = DiscoveryTarget{RoleARN: target[:i], Region: target[i+1:]}
+			}
+			cfg.DiscoveryTargets = append(cfg.DiscoveryTargets, t)
+		}
 	}
 
 	// Load admin IAM roles
@@ -35,3 +35,34 @@ func TestNew(t *testing.T) {
 	assert.Equal(t, []string{"read1", "read2", "admin"}, cfg.Permissions.Read)
 	assert.Equal(t, []string{"write", "admin"}, cfg.Permissions.Write)
 }
+
+func TestNewDiscoveryTargets(t *testing.T) {
+	originalDiscoveryTargets := os.Getenv("DISCOVERY_TARGETS")
+	defer func() {
+		err := os.Setenv("DISCOVERY_TARGETS", originalDiscoveryTargets)
+		require.Nil(t, err, "unexpected error restoring original DISCOVERY_TARGETS")
+	}()
+
+	err := os.Setenv("DISCOVERY_TARGETS", "us-west-2,arn:aws:iam::123456789012:role/pharos-discovery@us-east-1")
+	require.Nil(t, err, "unexpected error setting test env value for DISCOVERY_TARGETS")
+
+	cfg := New()
+	assert.Equal(t, []DiscoveryTarget{
+		{Region: "us-west-2"},
+		{RoleARN: "arn:aws:iam::123456789012:role/pharos-discovery", Region: "us-east-1"},
+	}, cfg.DiscoveryTargets)
+}
+
+func TestNewMinimumCLIVersion(t *testing.T) {
+	originalMinimumCLIVersion := os.Getenv("MINIMUM_CLI_VERSION")
+	defer func() {
+		err := os.Setenv("MINIMUM_CLI_VERSION", originalMinimumCLIVersion)
+		require.Nil(t, err, "unexpected error restoring original MINIMUM_CLI_VERSION")
+	}()
+
+	err := os.Setenv("MINIMUM_CLI_VERSION", "1.4.0")
+	require.Nil(t, err, "unexpected error setting test env value for MINIMUM_CLI_VERSION")
+
+	cfg := New()
+	assert.Equal(t, "1.4.0", cfg.MinimumCLIVersion)
+}
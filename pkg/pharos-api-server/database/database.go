@@ -6,6 +6,7 @@ import (
 
 	"github.com/go-pg/pg"
 	"github.com/lob/pharos/pkg/pharos-api-server/config"
+	"github.com/pkg/errors"
 )
 
 // New initializes a new database struct.
@@ -30,3 +31,13 @@ func New(cfg config.Config) (*pg.DB, error) {
 	}
 	return db, nil
 }
+
+// LockResourceVersions takes the lock that every write to the clusters table
+// takes so that resource versions are assigned in commit order. Transactions
+// that lock clusters with SELECT ... FOR UPDATE before writing them must take
+// it first, or they could deadlock with a transaction that holds it and waits
+// for their rows. It is held until the transaction ends.
+func LockResourceVersions(tx *pg.Tx) error {
+	_, err := tx.Exec("SELECT lock_clusters_resource_versions()")
+	return errors.Wrap(err, "failed to lock resource versions")
+}
@@ -2,9 +2,14 @@ package database
 
 import (
 	"testing"
+	"time"
 
+	"github.com/go-pg/pg"
+	"github.com/lob/pharos/internal/test"
 	"github.com/lob/pharos/pkg/pharos-api-server/config"
+	"github.com/lob/pharos/pkg/util/model"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNew(t *testing.T) {
@@ -21,3 +26,60 @@ func TestNew(t *testing.T) {
 
 	assert.Error(t, err, "expected error when connection fails")
 }
+
+func TestResourceVersions(t *testing.T) {
+	db, err := New(config.New())
+	require.NoError(t, err)
+
+	t.Run("assigns resource versions in commit order", func(tt *testing.T) {
+		test.TruncateTables(tt, db)
+		clusters := []model.Cluster{
+			{ID: "sandbox-111111", Environment: "sandbox", ServerURL: "https://sandbox-111111.com", ClusterAuthorityData: "abcdef"},
+			{ID: "sandbox-222222", Environment: "sandbox", ServerURL: "https://sandbox-222222.com", ClusterAuthorityData: "abcdef"},
+		}
+		err := db.Insert(&clusters)
+		require.NoError(tt, err)
+
+		// The first transaction takes a version and stays open.
+		first, err := db.Begin()
+		require.NoError(tt, err)
+		defer first.Rollback()
+		firstCluster := clusters[0]
+		firstCluster.ServerURL = "https://sandbox-111111.changed.com"
+		_, err = first.Model(&firstCluster).WherePK().Returning("resource_version").Update()
+		require.NoError(tt, err)
+
+		// The second transaction can't take a version until the first commits.
+		done := make(chan error, 1)
+		secondCluster := clusters[1]
+		go func() {
+			done <- db.RunInTransaction(func(tx *pg.Tx) error {
+				secondCluster.ServerURL = "https://sandbox-222222.changed.com"
+				_, err := tx.Model(&secondCluster).WherePK().Returning("resource_version").Update()
+				return err
+			})
+		}()
+
+		select {
+		case err := <-done:
+			tt.Fatalf("second transaction wasn't blocked by the first: %v", err)
+		case <-time.After(200 * time.Millisecond):
+		}
+
+		require.NoError(tt, first.Commit())
+		require.NoError(tt, <-done)
+		assert.True(tt, secondCluster.ResourceVersion > firstCluster.ResourceVersion)
+
+	})
+
+	t.Run("lets transactions take the lock before locking rows", func(tt *testing.T) {
+		err := db.RunInTransaction(func(tx *pg.Tx) error {
+			if err := LockResourceVersions(tx); err != nil {
+				return err
+			}
+			// The lock can be taken again by the same transaction.
+			return LockResourceVersions(tx)
+		})
+		assert.NoError(tt, err)
+	})
+}
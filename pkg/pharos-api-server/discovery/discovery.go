@@ -0,0 +1,241 @@
+package discovery
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/go-pg/pg"
+	logger "github.com/lob/logger-go"
+	"github.com/lob/pharos/pkg/pharos-api-server/application"
+	"github.com/lob/pharos/pkg/pharos-api-server/database"
+	"github.com/lob/pharos/pkg/pharos-api-server/webhooks"
+	"github.com/lob/pharos/pkg/util/model"
+	"github.com/pkg/errors"
+)
+
+// Provider is a source of clusters, such as the EKS clusters in an AWS account
+// and region.
+type Provider interface {
+	Clusters() ([]model.Cluster, error)
+}
+
+// Discoverer registers the clusters found by its providers, keeps their
+// endpoints and certificate authorities up to date and flags clusters that can
+// no longer be found.
+type Discoverer struct {
+	app       application.App
+	log       logger.Logger
+	providers []Provider
+}
+
+// New creates a new Discoverer for the given application and providers.
+func New(app application.App, providers []Provider) *Discoverer {
+	return &Discoverer{app, logger.New(), providers}
+}
+
+// Run discovers clusters every Config.DiscoveryInterval until the stop channel
+// is closed.
+func (d *Discoverer) Run(stop <-chan struct{}) {
+	ticker := time.NewTicker(d.app.Config.DiscoveryInterval)
+	defer ticker.Stop()
+
+	for {
+		result, err := d.Discover(false)
+		if err != nil {
+			d.log.Err(err).Error("cluster discovery failed")
+		} else {
+			d.log.Info("discovered clusters", logger.Data{
+				"created": len(result.Created),
+				"updated": len(result.Updated),
+				"missing": len(result.Missing),
+				"errors":  result.Errors,
+			})
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Discover queries every provider and reconciles the discovered clusters with
+// the clusters table. Nothing is written during a dry run.
+//
+// Discovered clusters are matched to the clusters previously discovered from
+// the same source by their SourceRef, such as the ARN of an EKS cluster, so
+// that same-named clusters in other accounts or regions are kept apart. New
+// clusters are registered as inactive, unless their ID is already taken by a
+// cluster from another source, which is reported as an error. Existing
+// clusters have their endpoint, certificate authority and Kubernetes version
+// updated, but deleted clusters are left alone. Clusters previously discovered
+// from EKS that no provider returns anymore are flagged as missing.
+func (d *Discoverer) Discover(dryRun bool) (model.DiscoveryResult, error) {
+	result := model.DiscoveryResult{
+		DryRun:    dryRun,
+		Created:   make([]model.Cluster, 0),
+		Updated:   make([]model.Cluster, 0),
+		Unchanged: make([]model.Cluster, 0),
+		Missing:   make([]model.Cluster, 0),
+	}
+
+	failed := false
+	discovered := make(map[string]model.Cluster)
+	for _, provider := range d.providers {
+		clusters, err := provider.Clusters()
+		if err != nil {
+			result.Errors = append(result.Errors, err.Error())
+			failed = true
+			continue
+		}
+		for _, cluster := range clusters {
+			discovered[sourceKey(cluster)] = cluster
+		}
+	}
+
+	// Discovery runs in the background and on demand, possibly on several
+	// servers, so the clusters are read again and written while holding the
+	// resource version lock that every write to clusters takes. Runs are
+	// serialized, and each one reconciles the changes of the run before it.
+	// Dry runs don't write anything, so they don't take the lock.
+	err := d.app.DB.RunInTransaction(func(tx *pg.Tx) error {
+		if !dryRun {
+			if err := database.LockResourceVersions(tx); err != nil {
+				return err
+			}
+		}
+
+		if err := reconcile(tx, discovered, failed, &result); err != nil {
+			return err
+		}
+		if dryRun {
+			return nil
+		}
+		return errors.Wrap(record(tx, result), "failed to record discovered clusters")
+	})
+	return result, err
+}
+
+// reconcile sorts the discovered clusters into the clusters to create, update
+// and leave unchanged, and the existing clusters to flag as missing.
+func reconcile(tx *pg.Tx, discovered map[string]model.Cluster, failed bool, result *model.DiscoveryResult) error {
+	keys := make([]string, 0, len(discovered))
+	ids := make([]string, 0, len(discovered))
+	for key, cluster := range discovered {
+		keys = append(keys, key)
+		ids = append(ids, cluster.ID)
+	}
+	sort.Strings(keys)
+
+	var existing []model.Cluster
+	err := tx.Model(&existing).Where("source = ?", model.SourceEKS).Order("id").Select()
+	if err != nil {
+		return errors.Wrap(err, "failed to list clusters")
+	}
+
+	known := make(map[string]model.Cluster, len(existing))
+	for _, cluster := range existing {
+		known[sourceKey(cluster)] = cluster
+	}
+
+	// IDs of new clusters may already be taken by clusters registered by hand,
+	// which discovery never changes.
+	taken := make(map[string]bool)
+	if len(ids) > 0 {
+		var others []model.Cluster
+		err := tx.Model(&others).Column("id").Where("source != ?", model.SourceEKS).Where("id IN (?)", pg.In(ids)).Select()
+		if err != nil {
+			return errors.Wrap(err, "failed to list clusters")
+		}
+		for _, cluster := range others {
+			taken[cluster.ID] = true
+		}
+	}
+
+	for _, key := range keys {
+		cluster := discovered[key]
+		current, ok := known[key]
+		switch {
+		case !ok && taken[cluster.ID]:
+			result.Errors = append(result.Errors, fmt.Sprintf("cluster %s is already registered from another source", cluster.ID))
+		case !ok:
+			result.Created = append(result.Created, cluster)
+		case current.Deleted:
+			// Clusters deleted by an operator stay deleted.
+		case changed(current, cluster):
+			current.ServerURL = cluster.ServerURL
+			current.ClusterAuthorityData = cluster.ClusterAuthorityData
+			current.CAExpiresAt = cluster.CAExpiresAt
+			current.SourceRef = cluster.SourceRef
+			current.KubernetesVersion = cluster.KubernetesVersion
+			current.Missing = false
+			result.Updated = append(result.Updated, current)
+		default:
+			result.Unchanged = append(result.Unchanged, current)
+		}
+	}
+
+	// A provider that failed may still own clusters that appear to be missing,
+	// so only flag them when every provider succeeded. Clusters already flagged
+	// as missing aren't written again.
+	if !failed {
+		for _, cluster := range existing {
+			if _, ok := discovered[sourceKey(cluster)]; !ok && !cluster.Deleted && !cluster.Missing {
+				cluster.Missing = true
+				result.Missing = append(result.Missing, cluster)
+			}
+		}
+	}
+	return nil
+}
+
+// record writes the changes found by reconcile.
+func record(tx *pg.Tx, result model.DiscoveryResult) error {
+	for i := range result.Created {
+		if _, err := tx.Model(&result.Created[i]).Insert(); err != nil {
+			return err
+		}
+		if err := webhooks.Enqueue(tx, model.EventClusterCreated, result.Created[i]); err != nil {
+			return err
+		}
+	}
+
+	for i := range result.Updated {
+		_, err := tx.Model(&result.Updated[i]).
+			Column("server_url", "cluster_authority_data", "ca_expires_at", "source_ref", "kubernetes_version", "missing").
+			WherePK().
+			Where("source = ?", model.SourceEKS).
+			Update()
+		if err != nil {
+			return err
+		}
+	}
+
+	for i := range result.Missing {
+		if _, err := tx.Model(&result.Missing[i]).Column("missing").WherePK().Where("source = ?", model.SourceEKS).Update(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sourceKey returns the key that identifies a cluster in its source, which is
+// its SourceRef, or its ID if it has none.
+func sourceKey(cluster model.Cluster) string {
+	if cluster.SourceRef != "" {
+		return cluster.SourceRef
+	}
+	return cluster.ID
+}
+
+// changed reports whether any of the discovered attributes of a cluster differ
+// from what is currently registered.
+func changed(current, discovered model.Cluster) bool {
+	return current.ServerURL != discovered.ServerURL ||
+		current.ClusterAuthorityData != discovered.ClusterAuthorityData ||
+		current.SourceRef != discovered.SourceRef ||
+		current.KubernetesVersion != discovered.KubernetesVersion ||
+		current.Missing
+}
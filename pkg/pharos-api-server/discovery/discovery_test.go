@@ -0,0 +1,266 @@
+package discovery
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-pg/pg"
+	"github.com/lob/pharos/internal/test"
+	"github.com/lob/pharos/pkg/pharos-api-server/application"
+	"github.com/lob/pharos/pkg/pharos-api-server/database"
+	"github.com/lob/pharos/pkg/util/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockProvider struct {
+	clusters []model.Cluster
+	err      error
+}
+
+func (m *mockProvider) Clusters() ([]model.Cluster, error) {
+	return m.clusters, m.err
+}
+
+var (
+	newEKSCluster = model.Cluster{
+		ID:                   "sandbox-111111",
+		Environment:          "sandbox",
+		ServerURL:            "https://sandbox-111111.eks.amazonaws.com",
+		ClusterAuthorityData: "abcdef",
+		Source:               model.SourceEKS,
+		SourceRef:            "arn:aws:eks:us-west-2:123456789012:cluster/sandbox-111111",
+		KubernetesVersion:    "1.13",
+	}
+	existingEKSCluster = model.Cluster{
+		ID:                   "production-222222",
+		Environment:          "production",
+		ServerURL:            "https://production-222222.eks.amazonaws.com",
+		ClusterAuthorityData: "abcdef",
+		Source:               model.SourceEKS,
+		SourceRef:            "arn:aws:eks:us-west-2:123456789012:cluster/production-222222",
+		KubernetesVersion:    "1.12",
+		Active:               true,
+	}
+	goneEKSCluster = model.Cluster{
+		ID:                   "staging-333333",
+		Environment:          "staging",
+		ServerURL:            "https://staging-333333.eks.amazonaws.com",
+		ClusterAuthorityData: "abcdef",
+		Source:               model.SourceEKS,
+		SourceRef:            "arn:aws:eks:us-west-2:123456789012:cluster/staging-333333",
+	}
+	manualCluster = model.Cluster{
+		ID:                   "manual-444444",
+		Environment:          "manual",
+		ServerURL:            "https://manual-444444.example.com",
+		ClusterAuthorityData: "abcdef",
+	}
+)
+
+func TestDiscover(t *testing.T) {
+	app, err := application.New()
+	require.NoError(t, err)
+
+	upgraded := existingEKSCluster
+	upgraded.KubernetesVersion = "1.13"
+	provider := &mockProvider{clusters: []model.Cluster{newEKSCluster, upgraded}}
+
+	t.Run("creates, updates and flags missing clusters", func(tt *testing.T) {
+		test.TruncateTables(tt, app.DB)
+		clusters := []model.Cluster{existingEKSCluster, goneEKSCluster, manualCluster}
+		err := app.DB.Insert(&clusters)
+		require.NoError(tt, err)
+
+		result, err := New(app, []Provider{provider}).Discover(false)
+		require.NoError(tt, err)
+		require.Len(tt, result.Created, 1)
+		assert.Equal(tt, newEKSCluster.ID, result.Created[0].ID)
+		require.Len(tt, result.Updated, 1)
+		assert.Equal(tt, existingEKSCluster.ID, result.Updated[0].ID)
+		require.Len(tt, result.Missing, 1)
+		assert.Equal(tt, goneEKSCluster.ID, result.Missing[0].ID)
+
+		var created model.Cluster
+		err = app.DB.Model(&created).Where("id = ?", newEKSCluster.ID).First()
+		require.NoError(tt, err)
+		assert.Equal(tt, model.SourceEKS, created.Source)
+		assert.False(tt, created.Active)
+
+		var updated model.Cluster
+		err = app.DB.Model(&updated).Where("id = ?", existingEKSCluster.ID).First()
+		require.NoError(tt, err)
+		assert.Equal(tt, "1.13", updated.KubernetesVersion)
+		assert.True(tt, updated.Active)
+
+		var missing model.Cluster
+		err = app.DB.Model(&missing).Where("id = ?", goneEKSCluster.ID).First()
+		require.NoError(tt, err)
+		assert.True(tt, missing.Missing)
+
+		var manual model.Cluster
+		err = app.DB.Model(&manual).Where("id = ?", manualCluster.ID).First()
+		require.NoError(tt, err)
+		assert.False(tt, manual.Missing)
+		assert.Equal(tt, model.SourceManual, manual.Source)
+	})
+
+	t.Run("does not write anything during a dry run", func(tt *testing.T) {
+		test.TruncateTables(tt, app.DB)
+		clusters := []model.Cluster{existingEKSCluster, goneEKSCluster}
+		err := app.DB.Insert(&clusters)
+		require.NoError(tt, err)
+
+		result, err := New(app, []Provider{provider}).Discover(true)
+		require.NoError(tt, err)
+		assert.True(tt, result.DryRun)
+		assert.Len(tt, result.Created, 1)
+		assert.Len(tt, result.Updated, 1)
+		assert.Len(tt, result.Missing, 1)
+
+		count, err := app.DB.Model(&model.Cluster{}).Count()
+		require.NoError(tt, err)
+		assert.Equal(tt, 2, count)
+	})
+
+	t.Run("does not flag missing clusters when a provider fails", func(tt *testing.T) {
+		test.TruncateTables(tt, app.DB)
+		clusters := []model.Cluster{existingEKSCluster, goneEKSCluster}
+		err := app.DB.Insert(&clusters)
+		require.NoError(tt, err)
+
+		failing := &mockProvider{err: errors.New("access denied")}
+		result, err := New(app, []Provider{provider, failing}).Discover(false)
+		require.NoError(tt, err)
+		assert.Equal(tt, []string{"access denied"}, result.Errors)
+		assert.Len(tt, result.Missing, 0)
+	})
+
+	t.Run("leaves deleted clusters alone", func(tt *testing.T) {
+		test.TruncateTables(tt, app.DB)
+		deleted := existingEKSCluster
+		deleted.Deleted = true
+		err := app.DB.Insert(&deleted)
+		require.NoError(tt, err)
+
+		result, err := New(app, []Provider{provider}).Discover(false)
+		require.NoError(tt, err)
+		assert.Len(tt, result.Created, 1)
+		assert.Len(tt, result.Updated, 0)
+		assert.Len(tt, result.Missing, 0)
+	})
+	t.Run("keeps same-named clusters in other regions apart", func(tt *testing.T) {
+		test.TruncateTables(tt, app.DB)
+
+		west := newEKSCluster
+		west.ID = "sandbox-111111.us-west-2.123456789012"
+		east := newEKSCluster
+		east.ID = "sandbox-111111.us-east-1.123456789012"
+		east.ServerURL = "https://sandbox-111111.us-east-1.eks.amazonaws.com"
+		east.SourceRef = "arn:aws:eks:us-east-1:123456789012:cluster/sandbox-111111"
+		regions := []Provider{
+			&mockProvider{clusters: []model.Cluster{west}},
+			&mockProvider{clusters: []model.Cluster{east}},
+		}
+
+		result, err := New(app, regions).Discover(false)
+		require.NoError(tt, err)
+		assert.Len(tt, result.Created, 2)
+
+		var created []model.Cluster
+		err = app.DB.Model(&created).Order("id").Select()
+		require.NoError(tt, err)
+		require.Len(tt, created, 2)
+		assert.Equal(tt, east.ServerURL, created[0].ServerURL)
+		assert.Equal(tt, west.ServerURL, created[1].ServerURL)
+
+		// Running again matches both clusters instead of updating one with the other.
+		result, err = New(app, regions).Discover(false)
+		require.NoError(tt, err)
+		assert.Len(tt, result.Created, 0)
+		assert.Len(tt, result.Updated, 0)
+		assert.Len(tt, result.Unchanged, 2)
+	})
+
+	t.Run("never changes clusters registered by hand", func(tt *testing.T) {
+		test.TruncateTables(tt, app.DB)
+		manual := manualCluster
+		manual.ID = newEKSCluster.ID
+		err := app.DB.Insert(&manual)
+		require.NoError(tt, err)
+
+		result, err := New(app, []Provider{&mockProvider{clusters: []model.Cluster{newEKSCluster}}}).Discover(false)
+		require.NoError(tt, err)
+		assert.Len(tt, result.Created, 0)
+		assert.Len(tt, result.Updated, 0)
+		require.Len(tt, result.Errors, 1)
+		assert.Contains(tt, result.Errors[0], "already registered from another source")
+
+		var fetched model.Cluster
+		err = app.DB.Model(&fetched).Where("id = ?", manual.ID).First()
+		require.NoError(tt, err)
+		assert.Equal(tt, manualCluster.ServerURL, fetched.ServerURL)
+		assert.Equal(tt, model.SourceManual, fetched.Source)
+	})
+
+	t.Run("doesn't flag clusters already flagged as missing again", func(tt *testing.T) {
+		test.TruncateTables(tt, app.DB)
+		gone := goneEKSCluster
+		gone.Missing = true
+		_, err := app.DB.Model(&gone).Returning("*").Insert()
+		require.NoError(tt, err)
+
+		result, err := New(app, []Provider{provider}).Discover(false)
+		require.NoError(tt, err)
+		assert.Len(tt, result.Missing, 0)
+
+		var fetched model.Cluster
+		err = app.DB.Model(&fetched).Where("id = ?", gone.ID).First()
+		require.NoError(tt, err)
+		assert.True(tt, fetched.Missing)
+		assert.Equal(tt, gone.ResourceVersion, fetched.ResourceVersion)
+	})
+
+	t.Run("serializes overlapping runs", func(tt *testing.T) {
+		test.TruncateTables(tt, app.DB)
+
+		// Both runs wait for a transaction holding the resource version lock,
+		// so that they overlap.
+		blocker, err := app.DB.Begin()
+		require.NoError(tt, err)
+		defer blocker.Rollback()
+		err = database.LockResourceVersions(blocker)
+		require.NoError(tt, err)
+
+		results := make(chan model.DiscoveryResult, 2)
+		errs := make(chan error, 2)
+		for i := 0; i < 2; i++ {
+			go func() {
+				result, err := New(app, []Provider{&mockProvider{clusters: []model.Cluster{newEKSCluster}}}).Discover(false)
+				results <- result
+				errs <- err
+			}()
+		}
+
+		waiting := 0
+		for deadline := time.Now().Add(5 * time.Second); waiting < 2 && time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+			_, err := app.DB.QueryOne(pg.Scan(&waiting), "SELECT count(*) FROM pg_locks WHERE locktype = 'advisory' AND NOT granted")
+			require.NoError(tt, err)
+		}
+		require.Equal(tt, 2, waiting)
+		require.NoError(tt, blocker.Commit())
+
+		// The run that goes second finds the cluster created by the first.
+		created := 0
+		for i := 0; i < 2; i++ {
+			require.NoError(tt, <-errs)
+			created += len((<-results).Created)
+		}
+		assert.Equal(tt, 1, created)
+
+		count, err := app.DB.Model(&model.Cluster{}).Count()
+		require.NoError(tt, err)
+		assert.Equal(tt, 1, count)
+	})
+}
@@ -0,0 +1,104 @@
+package discovery
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/eks"
+	"github.com/aws/aws-sdk-go/service/eks/eksiface"
+	"github.com/lob/pharos/pkg/pharos-api-server/config"
+	"github.com/lob/pharos/pkg/util/certificate"
+	"github.com/lob/pharos/pkg/util/model"
+	"github.com/pkg/errors"
+)
+
+type eksProvider struct {
+	client eksiface.EKSAPI
+	region string
+}
+
+// NewEKSProvider returns a Provider that discovers the EKS clusters available
+// to the given client.
+func NewEKSProvider(client eksiface.EKSAPI, region string) Provider {
+	return &eksProvider{client, region}
+}
+
+// EKSProviders returns an EKS Provider for each of the given discovery targets.
+func EKSProviders(targets []config.DiscoveryTarget) []Provider {
+	providers := make([]Provider, len(targets))
+	for i, target := range targets {
+		s := session.Must(session.NewSession(&aws.Config{Region: aws.String(target.Region)}))
+
+		cfg := &aws.Config{}
+		if target.RoleARN != "" {
+			cfg.Credentials = stscreds.NewCredentials(s, target.RoleARN)
+		}
+
+		providers[i] = NewEKSProvider(eks.New(s, cfg), target.Region)
+	}
+	return providers
+}
+
+// Clusters lists and describes every EKS cluster. Clusters that don't have an
+// endpoint or certificate authority yet, because they are still being created,
+// are skipped.
+func (p *eksProvider) Clusters() ([]model.Cluster, error) {
+	var names []*string
+	input := &eks.ListClustersInput{}
+	for {
+		output, err := p.client.ListClusters(input)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to list EKS clusters in %s", p.region)
+		}
+		names = append(names, output.Clusters...)
+
+		if output.NextToken == nil {
+			break
+		}
+		input.NextToken = output.NextToken
+	}
+
+	clusters := make([]model.Cluster, 0, len(names))
+	for _, name := range names {
+		output, err := p.client.DescribeCluster(&eks.DescribeClusterInput{Name: name})
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to describe EKS cluster %s in %s", aws.StringValue(name), p.region)
+		}
+
+		c := output.Cluster
+		if c == nil || c.Endpoint == nil || c.CertificateAuthority == nil || c.CertificateAuthority.Data == nil {
+			continue
+		}
+
+		cluster := model.Cluster{
+			ID:                   p.clusterID(aws.StringValue(c.Name), aws.StringValue(c.Arn)),
+			Environment:          model.EnvironmentFromID(aws.StringValue(c.Name)),
+			ServerURL:            aws.StringValue(c.Endpoint),
+			ClusterAuthorityData: aws.StringValue(c.CertificateAuthority.Data),
+			Source:               model.SourceEKS,
+			SourceRef:            aws.StringValue(c.Arn),
+			KubernetesVersion:    aws.StringValue(c.Version),
+		}
+		if expiry, err := certificate.EarliestExpiry(cluster.ClusterAuthorityData); err == nil {
+			cluster.CAExpiresAt = &expiry
+		}
+		clusters = append(clusters, cluster)
+	}
+
+	return clusters, nil
+}
+
+// clusterID namespaces the name of an EKS cluster by the region and account it
+// is in, taken from its ARN, as names are only unique within them. Clusters
+// without an ARN are namespaced by the provider's region.
+func (p *eksProvider) clusterID(name, arn string) string {
+	// ARNs are of the form arn:partition:eks:region:account:cluster/name.
+	parts := strings.Split(arn, ":")
+	if len(parts) < 6 {
+		return fmt.Sprintf("%s.%s", name, p.region)
+	}
+	return fmt.Sprintf("%s.%s.%s", name, parts[3], parts[4])
+}
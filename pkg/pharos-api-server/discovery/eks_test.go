@@ -0,0 +1,117 @@
+package discovery
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/eks"
+	"github.com/aws/aws-sdk-go/service/eks/eksiface"
+	"github.com/lob/pharos/internal/test"
+	"github.com/lob/pharos/pkg/util/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockEKSClient struct {
+	eksiface.EKSAPI
+	pages    [][]string
+	clusters map[string]*eks.Cluster
+	err      error
+}
+
+func (m *mockEKSClient) ListClusters(input *eks.ListClustersInput) (*eks.ListClustersOutput, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	page := 0
+	if input.NextToken != nil {
+		page = 1
+	}
+	output := &eks.ListClustersOutput{Clusters: aws.StringSlice(m.pages[page])}
+	if page+1 < len(m.pages) {
+		output.NextToken = aws.String("next")
+	}
+	return output, nil
+}
+
+func (m *mockEKSClient) DescribeCluster(input *eks.DescribeClusterInput) (*eks.DescribeClusterOutput, error) {
+	return &eks.DescribeClusterOutput{Cluster: m.clusters[aws.StringValue(input.Name)]}, nil
+}
+
+func TestEKSProviderClusters(t *testing.T) {
+	expiry := time.Now().Add(365 * 24 * time.Hour).UTC().Truncate(time.Second)
+	authorityData := test.NewClusterAuthorityData(t, expiry)
+
+	newEKSCluster := func(name string) *eks.Cluster {
+		return &eks.Cluster{
+			Name:                 aws.String(name),
+			Arn:                  aws.String("arn:aws:eks:us-west-2:123456789012:cluster/" + name),
+			Endpoint:             aws.String("https://" + name + ".eks.amazonaws.com"),
+			CertificateAuthority: &eks.Certificate{Data: aws.String(authorityData)},
+			Version:              aws.String("1.13"),
+		}
+	}
+
+	t.Run("lists and describes clusters across pages", func(tt *testing.T) {
+		client := &mockEKSClient{
+			pages: [][]string{{"sandbox-111111"}, {"production-6906ce", "staging-creating"}},
+			clusters: map[string]*eks.Cluster{
+				"sandbox-111111":    newEKSCluster("sandbox-111111"),
+				"production-6906ce": newEKSCluster("production-6906ce"),
+				"staging-creating":  {Name: aws.String("staging-creating")},
+			},
+		}
+
+		clusters, err := NewEKSProvider(client, "us-west-2").Clusters()
+		require.NoError(tt, err)
+		require.Len(tt, clusters, 2)
+
+		assert.Equal(tt, "sandbox-111111.us-west-2.123456789012", clusters[0].ID)
+		assert.Equal(tt, "sandbox", clusters[0].Environment)
+		assert.Equal(tt, "https://sandbox-111111.eks.amazonaws.com", clusters[0].ServerURL)
+		assert.Equal(tt, authorityData, clusters[0].ClusterAuthorityData)
+		assert.Equal(tt, model.SourceEKS, clusters[0].Source)
+		assert.Equal(tt, "arn:aws:eks:us-west-2:123456789012:cluster/sandbox-111111", clusters[0].SourceRef)
+		assert.Equal(tt, "1.13", clusters[0].KubernetesVersion)
+		require.NotNil(tt, clusters[0].CAExpiresAt)
+		assert.Equal(tt, expiry, *clusters[0].CAExpiresAt)
+
+		assert.Equal(tt, "production-6906ce.us-west-2.123456789012", clusters[1].ID)
+		assert.Equal(tt, "production", clusters[1].Environment)
+	})
+
+	t.Run("namespaces cluster IDs by account and region", func(tt *testing.T) {
+		other := newEKSCluster("sandbox-111111")
+		other.Arn = aws.String("arn:aws:eks:us-east-1:210987654321:cluster/sandbox-111111")
+		west := &mockEKSClient{
+			pages:    [][]string{{"sandbox-111111"}},
+			clusters: map[string]*eks.Cluster{"sandbox-111111": newEKSCluster("sandbox-111111")},
+		}
+		east := &mockEKSClient{
+			pages:    [][]string{{"sandbox-111111"}},
+			clusters: map[string]*eks.Cluster{"sandbox-111111": other},
+		}
+
+		westClusters, err := NewEKSProvider(west, "us-west-2").Clusters()
+		require.NoError(tt, err)
+		eastClusters, err := NewEKSProvider(east, "us-east-1").Clusters()
+		require.NoError(tt, err)
+
+		require.Len(tt, westClusters, 1)
+		require.Len(tt, eastClusters, 1)
+		assert.Equal(tt, "sandbox-111111.us-east-1.210987654321", eastClusters[0].ID)
+		assert.NotEqual(tt, westClusters[0].ID, eastClusters[0].ID)
+		assert.Equal(tt, "sandbox", eastClusters[0].Environment)
+	})
+
+	t.Run("errors when clusters can't be listed", func(tt *testing.T) {
+		client := &mockEKSClient{err: errors.New("access denied")}
+
+		_, err := NewEKSProvider(client, "us-west-2").Clusters()
+		assert.Error(tt, err)
+		assert.Contains(tt, err.Error(), "failed to list EKS clusters in us-west-2")
+	})
+}
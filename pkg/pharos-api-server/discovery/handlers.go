@@ -0,0 +1,35 @@
+package discovery
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo"
+	"github.com/lob/pharos/pkg/pharos-api-server/apierrors"
+	"github.com/lob/pharos/pkg/util/model"
+)
+
+type handler struct {
+	discoverer *Discoverer
+}
+
+type discoverParams struct {
+	DryRun bool `json:"dry_run"`
+}
+
+func (h *handler) discover(c echo.Context) error {
+	params := discoverParams{}
+	if err := c.Bind(&params); err != nil {
+		return err
+	}
+
+	if len(h.discoverer.providers) == 0 {
+		return apierrors.New(http.StatusServiceUnavailable, model.CodeUnavailable, "cluster discovery is not configured")
+	}
+
+	result, err := h.discoverer.Discover(params.DryRun)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
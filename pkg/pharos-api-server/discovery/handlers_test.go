@@ -0,0 +1,45 @@
+package discovery
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/lob/pharos/internal/test"
+	"github.com/lob/pharos/pkg/pharos-api-server/application"
+	"github.com/lob/pharos/pkg/util/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiscoverHandler(t *testing.T) {
+	t.Run("returns the changes made by discovery", func(tt *testing.T) {
+		app, err := application.New()
+		require.NoError(tt, err)
+		test.TruncateTables(tt, app.DB)
+
+		h := handler{New(app, []Provider{&mockProvider{clusters: []model.Cluster{newEKSCluster}}})}
+		c, rr := test.NewContext(tt, "POST", "", strings.NewReader(`{"dry_run": true}`), "application/json")
+
+		err = h.discover(c)
+		assert.NoError(tt, err)
+		assert.Equal(tt, http.StatusOK, rr.Code)
+
+		var response model.DiscoveryResult
+		err = json.Unmarshal(rr.Body.Bytes(), &response)
+		require.NoError(tt, err)
+		assert.True(tt, response.DryRun)
+		require.Len(tt, response.Created, 1)
+		assert.Equal(tt, newEKSCluster.ID, response.Created[0].ID)
+	})
+
+	t.Run("errors when discovery is not configured", func(tt *testing.T) {
+		h := handler{New(application.App{}, nil)}
+		c, _ := test.NewContext(tt, "POST", "", strings.NewReader(`{"dry_run": true}`), "application/json")
+
+		err := h.discover(c)
+		assert.Error(tt, err)
+		assert.Contains(tt, err.Error(), "cluster discovery is not configured")
+	})
+}
@@ -0,0 +1,23 @@
+package discovery
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo"
+	"github.com/lob/pharos/pkg/pharos-api-server/application"
+	"github.com/lob/pharos/pkg/pharos-api-server/authentication"
+	"github.com/lob/pharos/pkg/pharos-api-server/authorization"
+	"github.com/lob/pharos/pkg/pharos-api-server/versioning"
+)
+
+// RegisterRoutes takes in an Echo router and registers routes onto it. Routes
+// are registered under the /v1 prefix, with deprecated unversioned aliases for
+// older CLIs. Discovery is run on demand with the given Discoverer, which
+// should be the one that runs in the background.
+func RegisterRoutes(e *echo.Echo, app application.App, discoverer *Discoverer) {
+	h := handler{discoverer}
+
+	config := app.Config
+
+	versioning.Add(e, http.MethodPost, "/discover", h.discover, authentication.Middleware(app.TokenVerifier), authorization.Middleware(config.Permissions.Admin))
+}
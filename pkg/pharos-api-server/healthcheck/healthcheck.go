@@ -0,0 +1,191 @@
+package healthcheck
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-pg/pg"
+	logger "github.com/lob/logger-go"
+	"github.com/lob/pharos/pkg/pharos-api-server/application"
+	"github.com/lob/pharos/pkg/util/model"
+	"github.com/pkg/errors"
+)
+
+// Checker periodically probes the API server of every non-deleted cluster and
+// records the result in the cluster_statuses table.
+type Checker struct {
+	app application.App
+	log logger.Logger
+}
+
+// New creates a new Checker for the given application.
+func New(app application.App) *Checker {
+	return &Checker{app, logger.New()}
+}
+
+// Run checks all clusters every Config.HealthCheckInterval until the stop
+// channel is closed.
+func (c *Checker) Run(stop <-chan struct{}) {
+	ticker := time.NewTicker(c.app.Config.HealthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := c.CheckAll(); err != nil {
+			c.log.Err(err).Error("cluster health check failed")
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// CheckAll probes every non-deleted cluster concurrently, records the results
+// and removes results older than Config.HealthCheckRetention.
+func (c *Checker) CheckAll() error {
+	var clusters []model.Cluster
+	err := c.app.DB.Model(&clusters).Where("deleted = FALSE").Select()
+	if err != nil {
+		return errors.Wrap(err, "failed to list clusters")
+	}
+	if len(clusters) == 0 {
+		return nil
+	}
+
+	ids := make([]string, len(clusters))
+	for i, cluster := range clusters {
+		ids[i] = cluster.ID
+
+		// Report how long each cluster's certificate authority remains valid so
+		// alerts can be raised well before it expires.
+		if cluster.CAExpiresAt != nil {
+			c.app.Metrics.Gauge("cluster.ca_expiry_seconds", time.Until(*cluster.CAExpiresAt).Seconds(), "cluster_id:"+cluster.ID, "environment:"+cluster.Environment)
+		}
+	}
+	previous, err := LatestStatuses(c.app.DB, ids)
+	if err != nil {
+		return err
+	}
+
+	statuses := make([]model.ClusterStatus, len(clusters))
+	var wg sync.WaitGroup
+	for i := range clusters {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			statuses[i] = Check(clusters[i], c.app.Config.HealthCheckTimeout)
+		}(i)
+	}
+	wg.Wait()
+
+	// Carry the last time a cluster was seen forward from its previous status
+	// if it could not be reached this time around.
+	for i := range statuses {
+		if statuses[i].LastSeen == nil {
+			if p, ok := previous[statuses[i].ClusterID]; ok {
+				statuses[i].LastSeen = p.LastSeen
+			}
+		}
+	}
+
+	if _, err := c.app.DB.Model(&statuses).Insert(); err != nil {
+		return errors.Wrap(err, "failed to record cluster statuses")
+	}
+
+	_, err = c.app.DB.Model(&model.ClusterStatus{}).
+		Where("date_checked < ?", time.Now().Add(-c.app.Config.HealthCheckRetention)).
+		Delete()
+	return errors.Wrap(err, "failed to remove old cluster statuses")
+}
+
+type versionInfo struct {
+	GitVersion string `json:"gitVersion"`
+}
+
+// Check probes the /version endpoint of a cluster's API server, trusting the
+// cluster's own certificate authority. Any HTTP response means the cluster is
+// reachable, even if anonymous access to the endpoint is not allowed.
+func Check(cluster model.Cluster, timeout time.Duration) model.ClusterStatus {
+	status := model.ClusterStatus{
+		ClusterID:   cluster.ID,
+		DateChecked: time.Now(),
+	}
+
+	transport := &http.Transport{
+		TLSClientConfig:   &tls.Config{RootCAs: certPool(cluster.ClusterAuthorityData)},
+		DisableKeepAlives: true,
+	}
+	client := &http.Client{Transport: transport, Timeout: timeout}
+
+	start := time.Now()
+	resp, err := client.Get(strings.TrimSuffix(cluster.ServerURL, "/") + "/version")
+	status.LatencyMS = int64(time.Since(start) / time.Millisecond)
+	if err != nil {
+		status.Status = model.StatusUnreachable
+		status.Error = err.Error()
+		return status
+	}
+	defer resp.Body.Close()
+
+	seen := status.DateChecked
+	status.Status = model.StatusReachable
+	status.LastSeen = &seen
+
+	if resp.StatusCode == http.StatusOK {
+		var info versionInfo
+		if err := json.NewDecoder(resp.Body).Decode(&info); err == nil {
+			status.KubernetesVersion = info.GitVersion
+		}
+	}
+
+	return status
+}
+
+// certPool returns a pool containing the certificates in the base64 encoded
+// cluster authority data. It returns nil, meaning the system roots are used,
+// if the data does not contain any certificates.
+func certPool(clusterAuthorityData string) *x509.CertPool {
+	pem, err := base64.StdEncoding.DecodeString(clusterAuthorityData)
+	if err != nil {
+		return nil
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil
+	}
+	return pool
+}
+
+// LatestStatuses returns the most recent status of each of the given clusters,
+// keyed by cluster ID. Clusters that have never been checked are omitted.
+func LatestStatuses(db *pg.DB, ids []string) (map[string]*model.ClusterStatus, error) {
+	latest := make(map[string]*model.ClusterStatus, len(ids))
+	if len(ids) == 0 {
+		return latest, nil
+	}
+
+	var statuses []model.ClusterStatus
+	_, err := db.Query(&statuses, `
+		SELECT DISTINCT ON (cluster_id) *
+		FROM cluster_statuses
+		WHERE cluster_id IN (?)
+		ORDER BY cluster_id, date_checked DESC
+	`, pg.In(ids))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to retrieve cluster statuses")
+	}
+
+	for i := range statuses {
+		latest[statuses[i].ClusterID] = &statuses[i]
+	}
+	return latest, nil
+}
@@ -0,0 +1,119 @@
+package healthcheck
+
+import (
+	"encoding/base64"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/lob/pharos/internal/test"
+	"github.com/lob/pharos/pkg/pharos-api-server/application"
+	"github.com/lob/pharos/pkg/util/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheck(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/version" {
+			rw.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, err := rw.Write([]byte(`{"major": "1", "minor": "14", "gitVersion": "v1.14.1"}`))
+		require.NoError(t, err)
+	}))
+	defer srv.Close()
+
+	authorityData := base64.StdEncoding.EncodeToString(pem.EncodeToMemory(&pem.Block{
+		Type:  "CERTIFICATE",
+		Bytes: srv.Certificate().Raw,
+	}))
+
+	t.Run("records a reachable cluster and its version", func(tt *testing.T) {
+		cluster := model.Cluster{ID: "test-1", ServerURL: srv.URL, ClusterAuthorityData: authorityData}
+
+		status := Check(cluster, time.Second)
+		assert.Equal(tt, "test-1", status.ClusterID)
+		assert.Equal(tt, model.StatusReachable, status.Status)
+		assert.Equal(tt, "v1.14.1", status.KubernetesVersion)
+		assert.Empty(tt, status.Error)
+		require.NotNil(tt, status.LastSeen)
+		assert.Equal(tt, status.DateChecked, *status.LastSeen)
+	})
+
+	t.Run("records an unreachable cluster when the certificate authority does not match", func(tt *testing.T) {
+		cluster := model.Cluster{ID: "test-1", ServerURL: srv.URL, ClusterAuthorityData: "abcdef"}
+
+		status := Check(cluster, time.Second)
+		assert.Equal(tt, model.StatusUnreachable, status.Status)
+		assert.Contains(tt, status.Error, "certificate")
+		assert.Nil(tt, status.LastSeen)
+	})
+
+	t.Run("records an unreachable cluster when the server is down", func(tt *testing.T) {
+		cluster := model.Cluster{ID: "test-1", ServerURL: "https://127.0.0.1:1", ClusterAuthorityData: authorityData}
+
+		status := Check(cluster, time.Second)
+		assert.Equal(tt, model.StatusUnreachable, status.Status)
+		assert.NotEmpty(tt, status.Error)
+		assert.Empty(tt, status.KubernetesVersion)
+	})
+}
+
+func TestCheckAll(t *testing.T) {
+	app, err := application.New()
+	require.NoError(t, err)
+	app.Config.HealthCheckTimeout = time.Second
+	c := New(app)
+
+	t.Run("records statuses and carries the last seen time forward", func(tt *testing.T) {
+		test.TruncateTables(tt, app.DB)
+
+		lastSeen := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+		clusters := []model.Cluster{
+			{ID: "test-1", Environment: "test", ServerURL: "https://127.0.0.1:1", ClusterAuthorityData: "abcdef"},
+			{ID: "test-2", Environment: "test", ServerURL: "https://127.0.0.1:1", ClusterAuthorityData: "abcdef", Deleted: true},
+		}
+		err := app.DB.Insert(&clusters)
+		require.NoError(tt, err)
+		err = app.DB.Insert(&model.ClusterStatus{
+			ClusterID:   "test-1",
+			Status:      model.StatusReachable,
+			LastSeen:    &lastSeen,
+			DateChecked: lastSeen,
+		})
+		require.NoError(tt, err)
+
+		err = c.CheckAll()
+		assert.NoError(tt, err)
+
+		latest, err := LatestStatuses(app.DB, []string{"test-1", "test-2"})
+		require.NoError(tt, err)
+		assert.Len(tt, latest, 1)
+		assert.Equal(tt, model.StatusUnreachable, latest["test-1"].Status)
+		require.NotNil(tt, latest["test-1"].LastSeen)
+		assert.True(tt, lastSeen.Equal(*latest["test-1"].LastSeen))
+	})
+
+	t.Run("removes statuses older than the retention period", func(tt *testing.T) {
+		test.TruncateTables(tt, app.DB)
+
+		err := app.DB.Insert(&model.Cluster{ID: "test-1", Environment: "test", ServerURL: "https://127.0.0.1:1", ClusterAuthorityData: "abcdef"})
+		require.NoError(tt, err)
+		err = app.DB.Insert(&model.ClusterStatus{
+			ClusterID:   "test-1",
+			Status:      model.StatusReachable,
+			DateChecked: time.Now().Add(-app.Config.HealthCheckRetention - time.Hour),
+		})
+		require.NoError(tt, err)
+
+		err = c.CheckAll()
+		assert.NoError(tt, err)
+
+		count, err := app.DB.Model(&model.ClusterStatus{}).Count()
+		require.NoError(tt, err)
+		assert.Equal(tt, 1, count)
+	})
+}
@@ -0,0 +1,188 @@
+package idempotency
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-pg/pg"
+	"github.com/labstack/echo"
+	"github.com/lob/pharos/pkg/pharos-api-server/apierrors"
+	"github.com/lob/pharos/pkg/pharos-api-server/application"
+	"github.com/lob/pharos/pkg/pharos-api-server/versioning"
+	"github.com/lob/pharos/pkg/util/model"
+	"github.com/lob/pharos/pkg/util/token"
+	"github.com/pkg/errors"
+)
+
+// Headers used to make requests idempotent.
+const (
+	HeaderKey        = "Idempotency-Key"
+	HeaderReplayed   = "Idempotent-Replayed"
+	HeaderRetryAfter = "Retry-After"
+)
+
+// KeyTTL is how long the response to a request with an idempotency key is
+// kept, and replayed to retries of the request.
+const KeyTTL = 24 * time.Hour
+
+// pendingTTL is how long a key is held for a request that is still being
+// handled. Keys held for longer were left behind by requests that never
+// finished, such as ones handled by a server that stopped, and are released.
+const pendingTTL = time.Minute
+
+// retryAfter is how many seconds a caller is asked to wait before retrying a
+// request whose key is held for another request that is still being handled.
+const retryAfter = "1"
+
+// maxKeyLength is the maximum length of an idempotency key.
+const maxKeyLength = 255
+
+// idempotencyKey is a key given by a caller with a request, and the successful
+// response to the request. Keys without a response are pending: the request
+// they came with is still being handled.
+type idempotencyKey struct {
+	Caller      string
+	Key         string
+	RequestHash string
+	StatusCode  int
+	Body        string
+	DateCreated time.Time
+}
+
+// recorder copies the body of a response as it is written.
+type recorder struct {
+	http.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+// Middleware makes requests with an Idempotency-Key header safe to retry. The
+// key is reserved before the request is handled, and the first successful
+// response to the request is stored for KeyTTL and replayed instead of handling
+// retries of the same request with that key. Retries made while the request is
+// still being handled are refused with a conflict and a Retry-After header.
+// Keys are scoped to the caller, and reusing one for a different request is
+// an error. Failed responses aren't stored, so that they can be retried.
+func Middleware(app application.App) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			key := c.Request().Header.Get(HeaderKey)
+			if key == "" {
+				return next(c)
+			}
+			if len(key) > maxKeyLength {
+				return apierrors.New(http.StatusUnprocessableEntity, model.CodeValidationFailed, "Idempotency-Key must be at most 255 characters")
+			}
+
+			var caller string
+			if identity, ok := c.Get("auth").(*token.Identity); ok {
+				caller = identity.CanonicalARN
+			}
+
+			// Read the body so that it can be compared with the original request,
+			// and put it back for the handler.
+			body, err := ioutil.ReadAll(c.Request().Body)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			c.Request().Body = ioutil.NopCloser(bytes.NewReader(body))
+			requestHash := hashRequest(c, body)
+
+			// Expired keys, and pending keys of requests that never finished, are
+			// removed before the key is reserved so that they don't hold it.
+			now := time.Now()
+			_, err = app.DB.Model(&idempotencyKey{}).
+				Where("date_created <= ? OR (status_code IS NULL AND date_created <= ?)", now.Add(-KeyTTL), now.Add(-pendingTTL)).
+				Delete()
+			if err != nil {
+				return errors.Wrap(err, "failed to remove expired idempotency keys")
+			}
+
+			stored := idempotencyKey{
+				Caller:      caller,
+				Key:         key,
+				RequestHash: requestHash,
+			}
+			res, err := app.DB.Model(&stored).OnConflict("DO NOTHING").Insert()
+			if err != nil {
+				return errors.Wrap(err, "failed to reserve idempotency key")
+			}
+			if res.RowsAffected() == 0 {
+				return replay(app, c, caller, key, requestHash)
+			}
+
+			rec := &recorder{ResponseWriter: c.Response().Writer}
+			c.Response().Writer = rec
+			err = next(c)
+			status := c.Response().Status
+			if err != nil || status < 200 || status >= 300 {
+				// The key is released so that the request can be retried.
+				_, releaseErr := app.DB.Model(&idempotencyKey{}).
+					Where("caller = ?", caller).
+					Where("key = ?", key).
+					Where("status_code IS NULL").
+					Delete()
+				if err == nil && releaseErr != nil {
+					return errors.Wrap(releaseErr, "failed to release idempotency key")
+				}
+				return err
+			}
+
+			_, err = app.DB.Model(&idempotencyKey{}).
+				Set("status_code = ?", status).
+				Set("body = ?", rec.body.String()).
+				Where("caller = ?", caller).
+				Where("key = ?", key).
+				Where("status_code IS NULL").
+				Update()
+			return errors.Wrap(err, "failed to store idempotency key")
+		}
+	}
+}
+
+// hashRequest hashes the method, route, path parameters and body of a request.
+// The route is used rather than the path so that a request is the same whether
+// it's made to a versioned route or to its unversioned alias.
+func hashRequest(c echo.Context, body []byte) string {
+	hash := sha256.New()
+	_, _ = hash.Write([]byte(c.Request().Method + " " + strings.TrimPrefix(c.Path(), versioning.Prefix) + "\n"))
+	for _, name := range c.ParamNames() {
+		_, _ = hash.Write([]byte(name + "=" + c.Param(name) + "\n"))
+	}
+	_, _ = hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// replay responds to a request whose key has already been reserved with the
+// response stored for it, or with a conflict if the request it was reserved
+// for is still being handled.
+func replay(app application.App, c echo.Context, caller, key, requestHash string) error {
+	var stored idempotencyKey
+	err := app.DB.Model(&stored).
+		Where("caller = ?", caller).
+		Where("key = ?", key).
+		Select()
+	if err != nil && err != pg.ErrNoRows {
+		return errors.Wrap(err, "failed to look up idempotency key")
+	}
+	if err == nil && stored.RequestHash != requestHash {
+		return apierrors.New(http.StatusUnprocessableEntity, model.CodeValidationFailed, "Idempotency-Key has already been used for a different request")
+	}
+	// A key released since it was reserved is reported as pending too, and
+	// reserved by the retry.
+	if err == pg.ErrNoRows || stored.StatusCode == 0 {
+		c.Response().Header().Set(HeaderRetryAfter, retryAfter)
+		return apierrors.New(http.StatusConflict, model.CodeConflict, "a request with this Idempotency-Key is still being handled, retry it later")
+	}
+	c.Response().Header().Set(HeaderReplayed, "true")
+	return c.JSONBlob(stored.StatusCode, []byte(stored.Body))
+}
@@ -0,0 +1,202 @@
+package idempotency
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo"
+	"github.com/lob/pharos/internal/test"
+	"github.com/lob/pharos/pkg/pharos-api-server/apierrors"
+	"github.com/lob/pharos/pkg/pharos-api-server/application"
+	"github.com/lob/pharos/pkg/pharos-api-server/versioning"
+	"github.com/lob/pharos/pkg/util/token"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMiddleware(t *testing.T) {
+	app, err := application.New()
+	require.NoError(t, err)
+
+	calls := 0
+	started := make(chan struct{})
+	unblock := make(chan struct{})
+	e := echo.New()
+	apierrors.RegisterErrorHandler(e)
+	auth := func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			c.Set("auth", &token.Identity{CanonicalARN: c.Request().Header.Get("X-Caller")})
+			return next(c)
+		}
+	}
+	handler := func(c echo.Context) error {
+		calls++
+		if strings.Contains(c.Request().URL.RawQuery, "block") {
+			started <- struct{}{}
+			<-unblock
+		}
+		if strings.Contains(c.Request().URL.RawQuery, "fail") {
+			return echo.NewHTTPError(http.StatusConflict, "cluster already exists")
+		}
+		return c.JSON(http.StatusOK, map[string]int{"calls": calls})
+	}
+	versioning.AddWithAlias(e, http.MethodPost, "/clusters", handler, auth, Middleware(app))
+	versioning.Add(e, http.MethodPut, "/clusters/:id", handler, auth, Middleware(app))
+
+	sendTo := func(tt *testing.T, method, target, key, caller, body string) *httptest.ResponseRecorder {
+		tt.Helper()
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		if key != "" {
+			req.Header.Set(HeaderKey, key)
+		}
+		req.Header.Set("X-Caller", caller)
+		rr := httptest.NewRecorder()
+		e.ServeHTTP(rr, req)
+		return rr
+	}
+
+	send := func(tt *testing.T, key, caller, body, query string) *httptest.ResponseRecorder {
+		tt.Helper()
+		return sendTo(tt, http.MethodPost, "/v1/clusters?"+query, key, caller, body)
+	}
+
+	countKeys := func(tt *testing.T) int {
+		tt.Helper()
+		count, err := app.DB.Model(&idempotencyKey{}).Count()
+		require.NoError(tt, err)
+		return count
+	}
+
+	t.Run("replays the response to a request with the same key", func(tt *testing.T) {
+		test.TruncateTables(tt, app.DB)
+		calls = 0
+
+		rr := send(tt, "create-test-1", "admin", `{"id":"test-1"}`, "")
+		assert.Equal(tt, http.StatusOK, rr.Code)
+		assert.JSONEq(tt, `{"calls": 1}`, rr.Body.String())
+
+		rr = send(tt, "create-test-1", "admin", `{"id":"test-1"}`, "")
+		assert.Equal(tt, http.StatusOK, rr.Code)
+		assert.JSONEq(tt, `{"calls": 1}`, rr.Body.String())
+		assert.Equal(tt, "true", rr.Header().Get(HeaderReplayed))
+		assert.Equal(tt, 1, calls)
+
+		// Keys are scoped to the caller, and requests without keys always run.
+		rr = send(tt, "create-test-1", "other", `{"id":"test-1"}`, "")
+		assert.JSONEq(tt, `{"calls": 2}`, rr.Body.String())
+		rr = send(tt, "", "admin", `{"id":"test-1"}`, "")
+		assert.JSONEq(tt, `{"calls": 3}`, rr.Body.String())
+	})
+
+	t.Run("errors when a key is reused for a different request", func(tt *testing.T) {
+		test.TruncateTables(tt, app.DB)
+
+		rr := send(tt, "create-test-1", "admin", `{"id":"test-1"}`, "")
+		assert.Equal(tt, http.StatusOK, rr.Code)
+
+		rr = send(tt, "create-test-1", "admin", `{"id":"test-2"}`, "")
+		assert.Equal(tt, http.StatusUnprocessableEntity, rr.Code)
+		assert.Contains(tt, rr.Body.String(), "validation_failed")
+		assert.Contains(tt, rr.Body.String(), "already been used for a different request")
+	})
+
+	t.Run("doesn't store failed responses", func(tt *testing.T) {
+		test.TruncateTables(tt, app.DB)
+		calls = 0
+
+		rr := send(tt, "create-test-1", "admin", `{"id":"test-1"}`, "fail")
+		assert.Equal(tt, http.StatusConflict, rr.Code)
+
+		rr = send(tt, "create-test-1", "admin", `{"id":"test-1"}`, "fail")
+		assert.Equal(tt, http.StatusConflict, rr.Code)
+		assert.Equal(tt, 2, calls)
+
+		// The key reserved for the request is released.
+		assert.Equal(tt, 0, countKeys(tt))
+	})
+
+	t.Run("refuses retries while the request is still being handled", func(tt *testing.T) {
+		test.TruncateTables(tt, app.DB)
+		calls = 0
+
+		done := make(chan *httptest.ResponseRecorder)
+		go func() {
+			done <- send(tt, "create-test-1", "admin", `{"id":"test-1"}`, "block")
+		}()
+		<-started
+
+		rr := send(tt, "create-test-1", "admin", `{"id":"test-1"}`, "")
+		assert.Equal(tt, http.StatusConflict, rr.Code)
+		assert.Contains(tt, rr.Body.String(), "conflict")
+		assert.Contains(tt, rr.Body.String(), "still being handled")
+		assert.Equal(tt, "1", rr.Header().Get(HeaderRetryAfter))
+
+		// Retries of a different request with the key are still an error.
+		rr = send(tt, "create-test-1", "admin", `{"id":"test-2"}`, "")
+		assert.Equal(tt, http.StatusUnprocessableEntity, rr.Code)
+
+		unblock <- struct{}{}
+		rr = <-done
+		assert.Equal(tt, http.StatusOK, rr.Code)
+		assert.JSONEq(tt, `{"calls": 1}`, rr.Body.String())
+
+		rr = send(tt, "create-test-1", "admin", `{"id":"test-1"}`, "")
+		assert.Equal(tt, http.StatusOK, rr.Code)
+		assert.JSONEq(tt, `{"calls": 1}`, rr.Body.String())
+		assert.Equal(tt, "true", rr.Header().Get(HeaderReplayed))
+		assert.Equal(tt, 1, calls)
+	})
+
+	t.Run("releases keys held by requests that never finished", func(tt *testing.T) {
+		test.TruncateTables(tt, app.DB)
+		calls = 0
+
+		_, err := app.DB.Model(&idempotencyKey{Caller: "admin", Key: "create-test-1", RequestHash: "abandoned", DateCreated: time.Now().Add(-pendingTTL)}).Insert()
+		require.NoError(tt, err)
+
+		rr := send(tt, "create-test-1", "admin", `{"id":"test-1"}`, "")
+		assert.Equal(tt, http.StatusOK, rr.Code)
+		assert.JSONEq(tt, `{"calls": 1}`, rr.Body.String())
+	})
+
+	t.Run("treats a route and its unversioned alias as the same request", func(tt *testing.T) {
+		test.TruncateTables(tt, app.DB)
+		calls = 0
+
+		rr := sendTo(tt, http.MethodPost, "/clusters", "create-test-1", "admin", `{"id":"test-1"}`)
+		assert.Equal(tt, http.StatusOK, rr.Code)
+
+		rr = sendTo(tt, http.MethodPost, "/v1/clusters", "create-test-1", "admin", `{"id":"test-1"}`)
+		assert.Equal(tt, http.StatusOK, rr.Code)
+		assert.JSONEq(tt, `{"calls": 1}`, rr.Body.String())
+		assert.Equal(tt, "true", rr.Header().Get(HeaderReplayed))
+	})
+
+	t.Run("treats requests for different resources as different requests", func(tt *testing.T) {
+		test.TruncateTables(tt, app.DB)
+
+		rr := sendTo(tt, http.MethodPut, "/v1/clusters/test-1", "upsert-test", "admin", `{"environment":"sandbox"}`)
+		assert.Equal(tt, http.StatusOK, rr.Code)
+
+		rr = sendTo(tt, http.MethodPut, "/v1/clusters/test-2", "upsert-test", "admin", `{"environment":"sandbox"}`)
+		assert.Equal(tt, http.StatusUnprocessableEntity, rr.Code)
+		assert.Contains(tt, rr.Body.String(), "already been used for a different request")
+	})
+
+	t.Run("forgets keys after they expire", func(tt *testing.T) {
+		test.TruncateTables(tt, app.DB)
+		calls = 0
+
+		send(tt, "create-test-1", "admin", `{"id":"test-1"}`, "")
+		_, err := app.DB.Model(&idempotencyKey{}).Set("date_created = ?", time.Now().Add(-KeyTTL)).Where("key = ?", "create-test-1").Update()
+		require.NoError(tt, err)
+
+		rr := send(tt, "create-test-1", "admin", `{"id":"test-1"}`, "")
+		assert.JSONEq(tt, `{"calls": 2}`, rr.Body.String())
+		assert.Empty(tt, rr.Header().Get(HeaderReplayed))
+	})
+}
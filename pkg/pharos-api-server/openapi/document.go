@@ -0,0 +1,417 @@
+package openapi
+
+// document is the OpenAPI 3 document describing the Pharos API. Tests check it
+// against the registered routes and the structs the handlers bind and return,
+// so it has to be updated along with them.
+const document = `
+{
+  "openapi": "3.0.2",
+  "info": {
+    "title": "Pharos API",
+    "description": "Pharos keeps track of Kubernetes clusters and distributes kubeconfig files for them. Requests are authenticated with a bearer token generated from AWS STS credentials, and errors are returned as {\"error\": {...}} with a stable code. Routes are versioned under /v1, and their unversioned aliases respond with a Deprecation header until they are removed. The CLI sends its version in the Pharos-CLI-Version header, and every response reports the oldest supported CLI version in the Pharos-Minimum-CLI-Version header when one is configured.",
+    "version": "1.0.0"
+  },
+  "security": [{"bearerAuth": []}],
+  "paths": {
+    "/health": {
+      "get": {
+        "operationId": "getHealth",
+        "summary": "Reports whether the server is healthy.",
+        "security": [],
+        "responses": {
+          "200": {"description": "The server is healthy.", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Health"}}}}
+        }
+      }
+    },
+    "/openapi.json": {
+      "get": {
+        "operationId": "getOpenAPI",
+        "summary": "Returns this document.",
+        "security": [],
+        "responses": {
+          "200": {"description": "The OpenAPI document of the Pharos API.", "content": {"application/json": {"schema": {"type": "object"}}}}
+        }
+      }
+    },
+    "/v1/clusters": {
+      "get": {
+        "operationId": "listClusters",
+        "summary": "Lists clusters, or waits for clusters to change.",
+        "parameters": [
+          {"name": "environment", "in": "query", "description": "Only list clusters of this environment.", "schema": {"type": "string"}},
+          {"name": "active", "in": "query", "description": "Only list active clusters.", "schema": {"type": "boolean"}},
+          {"name": "expiring_within", "in": "query", "description": "Only list clusters whose certificate authority expires within this duration, such as 30d or 12h.", "schema": {"type": "string"}},
+          {"name": "since", "in": "query", "description": "List the clusters, including deleted ones, changed since this resource version, oldest first.", "schema": {"type": "integer", "format": "int64"}},
+          {"name": "watch", "in": "query", "description": "Wait for clusters to change after since before responding.", "schema": {"type": "boolean"}},
+          {"name": "timeout", "in": "query", "description": "How long a watch waits before responding with no clusters, such as 30s.", "schema": {"type": "string"}},
+          {"name": "modified_since", "in": "query", "description": "List the clusters, including deleted ones, modified since this time, oldest first.", "schema": {"type": "string", "format": "date-time"}}
+        ],
+        "responses": {
+          "200": {"description": "The clusters.", "content": {"application/json": {"schema": {"type": "array", "items": {"$ref": "#/components/schemas/Cluster"}}}}},
+          "default": {"$ref": "#/components/responses/Error"}
+        }
+      },
+      "post": {
+        "operationId": "createCluster",
+        "summary": "Creates a cluster.",
+        "parameters": [{"$ref": "#/components/parameters/IdempotencyKey"}],
+        "requestBody": {"required": true, "content": {"application/json": {"schema": {"$ref": "#/components/schemas/CreateClusterParams"}}}},
+        "responses": {
+          "200": {"$ref": "#/components/responses/Cluster"},
+          "default": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/v1/clusters/batch": {
+      "post": {
+        "operationId": "batchClusters",
+        "summary": "Makes a list of create, delete and activate operations in a single transaction.",
+        "requestBody": {"required": true, "content": {"application/json": {"schema": {"$ref": "#/components/schemas/BatchParams"}}}},
+        "responses": {
+          "200": {"description": "The result of each operation made. If applied is false, none were applied and the results end with the operation that failed.", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/BatchResult"}}}},
+          "default": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/v1/clusters/{id}": {
+      "get": {
+        "operationId": "getCluster",
+        "summary": "Retrieves a cluster, including a deleted one.",
+        "parameters": [{"$ref": "#/components/parameters/ClusterID"}],
+        "responses": {
+          "200": {"$ref": "#/components/responses/Cluster"},
+          "default": {"$ref": "#/components/responses/Error"}
+        }
+      },
+      "post": {
+        "operationId": "updateCluster",
+        "summary": "Activates or deactivates a cluster, or changes when it expires.",
+        "parameters": [{"$ref": "#/components/parameters/ClusterID"}, {"$ref": "#/components/parameters/IfMatch"}],
+        "requestBody": {"required": true, "content": {"application/json": {"schema": {"$ref": "#/components/schemas/UpdateClusterParams"}}}},
+        "responses": {
+          "200": {"$ref": "#/components/responses/Cluster"},
+          "default": {"$ref": "#/components/responses/Error"}
+        }
+      },
+      "put": {
+        "operationId": "upsertCluster",
+        "summary": "Creates a cluster, or replaces the registration of an existing one.",
+        "parameters": [{"$ref": "#/components/parameters/ClusterID"}, {"$ref": "#/components/parameters/IfMatch"}, {"$ref": "#/components/parameters/IdempotencyKey"}],
+        "requestBody": {"required": true, "content": {"application/json": {"schema": {"$ref": "#/components/schemas/UpsertClusterParams"}}}},
+        "responses": {
+          "200": {"$ref": "#/components/responses/Cluster"},
+          "201": {"$ref": "#/components/responses/Cluster"},
+          "default": {"$ref": "#/components/responses/Error"}
+        }
+      },
+      "delete": {
+        "operationId": "deleteCluster",
+        "summary": "Marks a cluster as deleted.",
+        "parameters": [{"$ref": "#/components/parameters/ClusterID"}, {"$ref": "#/components/parameters/IfMatch"}],
+        "responses": {
+          "200": {"$ref": "#/components/responses/Cluster"},
+          "default": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/v1/clusters/{id}/status": {
+      "get": {
+        "operationId": "getClusterStatus",
+        "summary": "Retrieves the latest health check of a cluster and its recent history.",
+        "parameters": [{"$ref": "#/components/parameters/ClusterID"}],
+        "responses": {
+          "200": {"description": "The latest health check and its history, newest first.", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/ClusterStatusHistory"}}}},
+          "default": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/v1/resolve/{name}": {
+      "get": {
+        "operationId": "resolveCluster",
+        "summary": "Resolves a cluster ID, or an environment to its active cluster.",
+        "parameters": [{"name": "name", "in": "path", "required": true, "description": "A cluster ID or an environment.", "schema": {"type": "string"}}],
+        "responses": {
+          "200": {"$ref": "#/components/responses/Cluster"},
+          "default": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/v1/kubeconfig": {
+      "get": {
+        "operationId": "renderKubeconfig",
+        "summary": "Renders a kubeconfig for the requested clusters.",
+        "parameters": [
+          {"name": "environment", "in": "query", "description": "Only include clusters of this environment.", "schema": {"type": "string"}},
+          {"name": "selector", "in": "query", "description": "Only include clusters matching this selector, such as environment=sandbox,source=eks.", "schema": {"type": "string"}},
+          {"name": "include_inactive", "in": "query", "description": "Include inactive clusters.", "schema": {"type": "boolean"}}
+        ],
+        "responses": {
+          "200": {"description": "The kubeconfig.", "content": {"application/yaml": {"schema": {"type": "string"}}}},
+          "default": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/v1/discover": {
+      "post": {
+        "operationId": "discoverClusters",
+        "summary": "Runs cluster discovery.",
+        "requestBody": {"content": {"application/json": {"schema": {"$ref": "#/components/schemas/DiscoverParams"}}}},
+        "responses": {
+          "200": {"description": "The changes made, or that would be made during a dry run.", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/DiscoveryResult"}}}},
+          "default": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/v1/webhooks": {
+      "get": {
+        "operationId": "listWebhooks",
+        "summary": "Lists webhooks, without their secrets.",
+        "responses": {
+          "200": {"description": "The webhooks.", "content": {"application/json": {"schema": {"type": "array", "items": {"$ref": "#/components/schemas/Webhook"}}}}},
+          "default": {"$ref": "#/components/responses/Error"}
+        }
+      },
+      "post": {
+        "operationId": "createWebhook",
+        "summary": "Subscribes a webhook to cluster events.",
+        "requestBody": {"required": true, "content": {"application/json": {"schema": {"$ref": "#/components/schemas/CreateWebhookParams"}}}},
+        "responses": {
+          "200": {"description": "The webhook, with its secret.", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Webhook"}}}},
+          "default": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/v1/webhooks/{id}": {
+      "delete": {
+        "operationId": "deleteWebhook",
+        "summary": "Deletes a webhook.",
+        "parameters": [{"$ref": "#/components/parameters/WebhookID"}],
+        "responses": {
+          "200": {"description": "The deleted webhook.", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Webhook"}}}},
+          "default": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/v1/webhooks/{id}/dead_letters": {
+      "get": {
+        "operationId": "listWebhookDeadLetters",
+        "summary": "Lists the events that could not be delivered to a webhook.",
+        "parameters": [{"$ref": "#/components/parameters/WebhookID"}],
+        "responses": {
+          "200": {"description": "The dead letters, newest first.", "content": {"application/json": {"schema": {"type": "array", "items": {"$ref": "#/components/schemas/WebhookDeadLetter"}}}}},
+          "default": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    }
+  },
+  "components": {
+    "securitySchemes": {
+      "bearerAuth": {"type": "http", "scheme": "bearer", "description": "A token generated from AWS STS credentials, as created by the pharos CLI."}
+    },
+    "parameters": {
+      "ClusterID": {"name": "id", "in": "path", "required": true, "schema": {"type": "string"}},
+      "WebhookID": {"name": "id", "in": "path", "required": true, "schema": {"type": "integer", "format": "int64"}},
+      "IfMatch": {"name": "If-Match", "in": "header", "description": "Only make the change if the cluster's resource version, given as its ETag, still matches.", "schema": {"type": "string"}},
+      "IdempotencyKey": {"name": "Idempotency-Key", "in": "header", "description": "Replay the response of the first successful request with the same key for 24 hours instead of handling it again. Retries made while the request is still being handled are refused with 409 and a Retry-After header.", "schema": {"type": "string", "maxLength": 255}}
+    },
+    "responses": {
+      "Cluster": {
+        "description": "The cluster.",
+        "headers": {"ETag": {"description": "The cluster's resource version, to send back in If-Match.", "schema": {"type": "string"}}},
+        "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Cluster"}}}
+      },
+      "Error": {
+        "description": "An error.",
+        "content": {"application/json": {"schema": {"$ref": "#/components/schemas/ErrorResponse"}}}
+      }
+    },
+    "schemas": {
+      "Health": {
+        "type": "object",
+        "properties": {"healthy": {"type": "boolean"}}
+      },
+      "Cluster": {
+        "type": "object",
+        "properties": {
+          "id": {"type": "string"},
+          "environment": {"type": "string"},
+          "server_url": {"type": "string"},
+          "cluster_authority_data": {"type": "string", "format": "byte"},
+          "deleted": {"type": "boolean"},
+          "active": {"type": "boolean"},
+          "date_created": {"type": "string", "format": "date-time"},
+          "date_modified": {"type": "string", "format": "date-time"},
+          "ca_expires_at": {"type": "string", "format": "date-time", "nullable": true},
+          "expires_at": {"type": "string", "format": "date-time", "nullable": true},
+          "source": {"type": "string", "enum": ["manual", "eks"]},
+          "source_ref": {"type": "string"},
+          "kubernetes_version": {"type": "string"},
+          "missing": {"type": "boolean"},
+          "resource_version": {"type": "integer", "format": "int64"},
+          "status": {"$ref": "#/components/schemas/ClusterStatus"}
+        }
+      },
+      "ClusterStatus": {
+        "type": "object",
+        "properties": {
+          "cluster_id": {"type": "string"},
+          "status": {"type": "string", "enum": ["reachable", "unreachable"]},
+          "latency_ms": {"type": "integer", "format": "int64"},
+          "kubernetes_version": {"type": "string"},
+          "error": {"type": "string"},
+          "last_seen": {"type": "string", "format": "date-time", "nullable": true},
+          "date_checked": {"type": "string", "format": "date-time"}
+        }
+      },
+      "ClusterStatusHistory": {
+        "type": "object",
+        "properties": {
+          "cluster_id": {"type": "string"},
+          "status": {"type": "string", "enum": ["reachable", "unreachable"]},
+          "latency_ms": {"type": "integer", "format": "int64"},
+          "kubernetes_version": {"type": "string"},
+          "error": {"type": "string"},
+          "last_seen": {"type": "string", "format": "date-time", "nullable": true},
+          "date_checked": {"type": "string", "format": "date-time"},
+          "history": {"type": "array", "items": {"$ref": "#/components/schemas/ClusterStatus"}}
+        }
+      },
+      "CreateClusterParams": {
+        "type": "object",
+        "required": ["id", "environment", "server_url", "cluster_authority_data"],
+        "properties": {
+          "id": {"type": "string"},
+          "environment": {"type": "string"},
+          "server_url": {"type": "string", "format": "uri"},
+          "cluster_authority_data": {"type": "string", "format": "byte"},
+          "expires_at": {"type": "string", "format": "date-time"}
+        }
+      },
+      "UpsertClusterParams": {
+        "type": "object",
+        "required": ["environment", "server_url", "cluster_authority_data"],
+        "properties": {
+          "id": {"type": "string", "description": "Must match the cluster ID in the path if given."},
+          "environment": {"type": "string"},
+          "server_url": {"type": "string", "format": "uri"},
+          "cluster_authority_data": {"type": "string", "format": "byte"},
+          "expires_at": {"type": "string", "format": "date-time", "description": "The existing expiry is kept if omitted."}
+        }
+      },
+      "UpdateClusterParams": {
+        "type": "object",
+        "description": "At least one of active and expires_at is required.",
+        "properties": {
+          "active": {"type": "boolean"},
+          "expires_at": {"type": "string", "format": "date-time"}
+        }
+      },
+      "BatchParams": {
+        "type": "object",
+        "required": ["operations"],
+        "properties": {
+          "operations": {"type": "array", "minItems": 1, "maxItems": 100, "items": {"$ref": "#/components/schemas/BatchOperation"}}
+        }
+      },
+      "BatchOperation": {
+        "type": "object",
+        "required": ["action", "id"],
+        "description": "Create operations also require environment, server_url and cluster_authority_data.",
+        "properties": {
+          "action": {"type": "string", "enum": ["create", "delete", "activate"]},
+          "id": {"type": "string"},
+          "environment": {"type": "string"},
+          "server_url": {"type": "string", "format": "uri"},
+          "cluster_authority_data": {"type": "string", "format": "byte"},
+          "expires_at": {"type": "string", "format": "date-time"},
+          "version": {"type": "integer", "format": "int64", "description": "Only make the operation if the cluster's resource version still matches."}
+        }
+      },
+      "BatchResult": {
+        "type": "object",
+        "properties": {
+          "applied": {"type": "boolean"},
+          "results": {"type": "array", "items": {"$ref": "#/components/schemas/BatchOperationResult"}}
+        }
+      },
+      "BatchOperationResult": {
+        "type": "object",
+        "properties": {
+          "action": {"type": "string", "enum": ["create", "delete", "activate"]},
+          "id": {"type": "string"},
+          "status_code": {"type": "integer"},
+          "cluster": {"$ref": "#/components/schemas/Cluster"},
+          "error": {"$ref": "#/components/schemas/Error"}
+        }
+      },
+      "DiscoverParams": {
+        "type": "object",
+        "properties": {
+          "dry_run": {"type": "boolean"}
+        }
+      },
+      "DiscoveryResult": {
+        "type": "object",
+        "properties": {
+          "dry_run": {"type": "boolean"},
+          "created": {"type": "array", "items": {"$ref": "#/components/schemas/Cluster"}},
+          "updated": {"type": "array", "items": {"$ref": "#/components/schemas/Cluster"}},
+          "unchanged": {"type": "array", "items": {"$ref": "#/components/schemas/Cluster"}},
+          "missing": {"type": "array", "items": {"$ref": "#/components/schemas/Cluster"}},
+          "errors": {"type": "array", "items": {"type": "string"}}
+        }
+      },
+      "Webhook": {
+        "type": "object",
+        "properties": {
+          "id": {"type": "integer", "format": "int64"},
+          "url": {"type": "string", "format": "uri"},
+          "events": {"type": "array", "items": {"$ref": "#/components/schemas/Event"}},
+          "secret": {"type": "string", "description": "Only returned when the webhook is created."},
+          "date_created": {"type": "string", "format": "date-time"}
+        }
+      },
+      "CreateWebhookParams": {
+        "type": "object",
+        "required": ["url", "events"],
+        "properties": {
+          "url": {"type": "string", "format": "uri"},
+          "events": {"type": "array", "minItems": 1, "items": {"$ref": "#/components/schemas/Event"}},
+          "secret": {"type": "string", "description": "Generated if omitted."}
+        }
+      },
+      "WebhookDeadLetter": {
+        "type": "object",
+        "properties": {
+          "id": {"type": "integer", "format": "int64"},
+          "webhook_id": {"type": "integer", "format": "int64"},
+          "event": {"$ref": "#/components/schemas/Event"},
+          "payload": {"type": "string"},
+          "attempts": {"type": "integer"},
+          "last_error": {"type": "string"},
+          "date_created": {"type": "string", "format": "date-time"},
+          "date_failed": {"type": "string", "format": "date-time"}
+        }
+      },
+      "Event": {
+        "type": "string",
+        "enum": ["cluster.created", "cluster.deleted", "cluster.activated"]
+      },
+      "Error": {
+        "type": "object",
+        "properties": {
+          "code": {"type": "string", "enum": ["cluster_not_found", "cluster_exists", "status_not_found", "no_active_cluster", "multiple_active_clusters", "active_cluster_exists", "webhook_not_found", "validation_failed", "unauthorized", "forbidden", "not_found", "conflict", "precondition_failed", "unavailable", "internal_error"]},
+          "message": {"type": "string"},
+          "status_code": {"type": "integer"}
+        }
+      },
+      "ErrorResponse": {
+        "type": "object",
+        "properties": {
+          "error": {"$ref": "#/components/schemas/Error"}
+        }
+      }
+    }
+  }
+}
+`
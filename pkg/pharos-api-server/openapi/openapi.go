@@ -0,0 +1,102 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/labstack/echo"
+	"github.com/pkg/errors"
+)
+
+// Path is the path that the OpenAPI document is served at.
+const Path = "/openapi.json"
+
+// RegisterRoutes takes in an Echo router and registers routes onto it. Like
+// the health check, the document is served without authentication so that
+// clients can be generated from it.
+func RegisterRoutes(e *echo.Echo) {
+	e.GET(Path, documentHandler)
+}
+
+func documentHandler(c echo.Context) error {
+	return c.JSONBlob(http.StatusOK, []byte(document))
+}
+
+// Spec is the part of the OpenAPI document that the API is checked against.
+type Spec struct {
+	OpenAPI    string                          `json:"openapi"`
+	Paths      map[string]map[string]Operation `json:"paths"`
+	Components struct {
+		Schemas map[string]Schema `json:"schemas"`
+	} `json:"components"`
+}
+
+// Operation is a single operation of a path in the OpenAPI document.
+type Operation struct {
+	OperationID string      `json:"operationId"`
+	Parameters  []Parameter `json:"parameters"`
+}
+
+// Parameter is a parameter of an operation. Parameters defined in the
+// components of the document only have a Ref.
+type Parameter struct {
+	Name string `json:"name"`
+	In   string `json:"in"`
+	Ref  string `json:"$ref"`
+}
+
+// Schema is a schema in the components of the OpenAPI document.
+type Schema struct {
+	Properties map[string]json.RawMessage `json:"properties"`
+}
+
+// Load parses the OpenAPI document.
+func Load() (Spec, error) {
+	var spec Spec
+	err := json.Unmarshal([]byte(document), &spec)
+	return spec, errors.Wrap(err, "failed to parse OpenAPI document")
+}
+
+// Routes returns the operations in the document as "METHOD /path", with path
+// parameters in Echo's ":name" form, sorted.
+func (s Spec) Routes() []string {
+	var routes []string
+	for path, operations := range s.Paths {
+		segments := strings.Split(path, "/")
+		for i, segment := range segments {
+			if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+				segments[i] = ":" + strings.Trim(segment, "{}")
+			}
+		}
+		for method := range operations {
+			routes = append(routes, strings.ToUpper(method)+" "+strings.Join(segments, "/"))
+		}
+	}
+	sort.Strings(routes)
+	return routes
+}
+
+// Properties returns the names of the properties of a schema, sorted.
+func (s Spec) Properties(schema string) []string {
+	var names []string
+	for name := range s.Components.Schemas[schema].Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// QueryParameters returns the names of the query parameters of an operation,
+// sorted.
+func (s Spec) QueryParameters(method, path string) []string {
+	var names []string
+	for _, parameter := range s.Paths[path][strings.ToLower(method)].Parameters {
+		if parameter.In == "query" {
+			names = append(names, parameter.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,114 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"testing"
+
+	"github.com/labstack/echo"
+	"github.com/lob/pharos/internal/test"
+	"github.com/lob/pharos/pkg/pharos-api-server/application"
+	"github.com/lob/pharos/pkg/pharos-api-server/clusters"
+	"github.com/lob/pharos/pkg/pharos-api-server/config"
+	"github.com/lob/pharos/pkg/pharos-api-server/discovery"
+	"github.com/lob/pharos/pkg/pharos-api-server/health"
+	"github.com/lob/pharos/pkg/pharos-api-server/versioning"
+	"github.com/lob/pharos/pkg/pharos-api-server/webhooks"
+	"github.com/lob/pharos/pkg/util/model"
+	"github.com/lob/pharos/pkg/util/token"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockVerifier struct{}
+
+func (m *mockVerifier) Verify(t string) (*token.Identity, error) {
+	return &token.Identity{}, nil
+}
+
+func TestDocument(t *testing.T) {
+	t.Run("serves the OpenAPI document", func(tt *testing.T) {
+		c, rr := test.NewContext(tt, "GET", "", nil, "")
+
+		err := documentHandler(c)
+		require.NoError(tt, err)
+		assert.Equal(tt, http.StatusOK, rr.Code)
+
+		var doc map[string]interface{}
+		err = json.Unmarshal(rr.Body.Bytes(), &doc)
+		require.NoError(tt, err)
+		assert.Equal(tt, "3.0.2", doc["openapi"])
+	})
+
+	t.Run("gives every operation a unique ID", func(tt *testing.T) {
+		spec, err := Load()
+		require.NoError(tt, err)
+
+		ids := make(map[string]bool)
+		for path, operations := range spec.Paths {
+			for method, operation := range operations {
+				require.NotEmpty(tt, operation.OperationID, "%s %s has no operationId", method, path)
+				assert.False(tt, ids[operation.OperationID], "operationId %s is used more than once", operation.OperationID)
+				ids[operation.OperationID] = true
+			}
+		}
+	})
+}
+
+func TestRoutesMatchDocument(t *testing.T) {
+	t.Run("documents every route and only registered routes", func(tt *testing.T) {
+		e := echo.New()
+		app := application.App{
+			Config:        config.New(),
+			TokenVerifier: &mockVerifier{},
+		}
+
+		health.RegisterRoutes(e)
+		clusters.RegisterRoutes(e, app)
+		discovery.RegisterRoutes(e, app, discovery.New(app, nil))
+		webhooks.RegisterRoutes(e, app)
+		RegisterRoutes(e)
+
+		registered := make(map[string]bool)
+		for _, route := range e.Routes() {
+			registered[route.Method+" "+route.Path] = true
+		}
+
+		// Deprecated unversioned aliases of versioned routes aren't documented.
+		var routes []string
+		for _, route := range e.Routes() {
+			if registered[route.Method+" "+versioning.Prefix+route.Path] {
+				continue
+			}
+			routes = append(routes, route.Method+" "+route.Path)
+		}
+		sort.Strings(routes)
+
+		spec, err := Load()
+		require.NoError(tt, err)
+		assert.Equal(tt, routes, spec.Routes())
+	})
+}
+
+func TestSchemasMatchModels(t *testing.T) {
+	t.Run("documents every field of the models the API returns", func(tt *testing.T) {
+		spec, err := Load()
+		require.NoError(tt, err)
+
+		schemas := map[string]interface{}{
+			"Cluster":              model.Cluster{},
+			"ClusterStatus":        model.ClusterStatus{},
+			"BatchOperation":       model.BatchOperation{},
+			"BatchResult":          model.BatchResult{},
+			"BatchOperationResult": model.BatchOperationResult{},
+			"DiscoveryResult":      model.DiscoveryResult{},
+			"Webhook":              model.Webhook{},
+			"WebhookDeadLetter":    model.WebhookDeadLetter{},
+			"Error":                model.Error{},
+		}
+		for name, v := range schemas {
+			assert.Equal(tt, test.FieldNames(v, "json"), spec.Properties(name), "schema %s doesn't match its model", name)
+		}
+	})
+}
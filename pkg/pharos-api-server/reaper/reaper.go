@@ -0,0 +1,111 @@
+package reaper
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-pg/pg"
+	logger "github.com/lob/logger-go"
+	"github.com/lob/pharos/pkg/pharos-api-server/application"
+	"github.com/lob/pharos/pkg/pharos-api-server/database"
+	"github.com/lob/pharos/pkg/pharos-api-server/webhooks"
+	"github.com/lob/pharos/pkg/util/model"
+	"github.com/pkg/errors"
+)
+
+// Actor is the actor of the audit entries recorded by the reaper.
+const Actor = "reaper"
+
+// batchSize is the maximum number of clusters deleted at once.
+const batchSize = 100
+
+// Reaper deletes clusters once they expire.
+type Reaper struct {
+	app application.App
+	log logger.Logger
+}
+
+// New creates a new Reaper for the given application.
+func New(app application.App) *Reaper {
+	return &Reaper{app, logger.New()}
+}
+
+// Run deletes expired clusters every Config.ReaperInterval until the stop
+// channel is closed.
+func (r *Reaper) Run(stop <-chan struct{}) {
+	ticker := time.NewTicker(r.app.Config.ReaperInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := r.ReapExpired(); err != nil {
+			r.log.Err(err).Error("cluster reaping failed")
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ReapExpired marks the clusters whose expiry has passed as deleted, records
+// an audit entry for each of them and notifies webhooks, and returns the
+// clusters it deleted. Clusters being reaped by another server at the same
+// time are skipped.
+func (r *Reaper) ReapExpired() ([]model.Cluster, error) {
+	var clusters []model.Cluster
+
+	err := r.app.DB.RunInTransaction(func(tx *pg.Tx) error {
+		if err := database.LockResourceVersions(tx); err != nil {
+			return err
+		}
+
+		err := tx.Model(&clusters).
+			Where("deleted = FALSE").
+			Where("expires_at <= ?", time.Now()).
+			Order("expires_at ASC").
+			Limit(batchSize).
+			For("UPDATE SKIP LOCKED").
+			Select()
+		if err != nil {
+			return errors.Wrap(err, "failed to list expired clusters")
+		}
+
+		for i := range clusters {
+			cluster := &clusters[i]
+			cluster.Deleted = true
+
+			_, err := tx.Model(cluster).WherePK().Returning("resource_version").Returning("date_modified").Update()
+			if err != nil {
+				return errors.Wrapf(err, "failed to delete cluster %s", cluster.ID)
+			}
+
+			entry := model.AuditEntry{
+				ClusterID: cluster.ID,
+				Action:    model.AuditClusterExpired,
+				Actor:     Actor,
+				Details:   fmt.Sprintf("deleted after expiring at %s", cluster.ExpiresAt.Format(time.RFC3339)),
+			}
+			if _, err := tx.Model(&entry).Insert(); err != nil {
+				return errors.Wrapf(err, "failed to record expiry of cluster %s", cluster.ID)
+			}
+
+			if err := webhooks.Enqueue(tx, model.EventClusterDeleted, *cluster); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, cluster := range clusters {
+		r.log.Info("deleted expired cluster", logger.Data{
+			"cluster_id": cluster.ID,
+			"expires_at": cluster.ExpiresAt,
+		})
+	}
+	return clusters, nil
+}
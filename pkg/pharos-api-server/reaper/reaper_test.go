@@ -0,0 +1,75 @@
+package reaper
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lob/pharos/internal/test"
+	"github.com/lob/pharos/pkg/pharos-api-server/application"
+	"github.com/lob/pharos/pkg/util/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReapExpired(t *testing.T) {
+	app, err := application.New()
+	require.NoError(t, err)
+	r := New(app)
+
+	expired := time.Now().Add(-time.Hour)
+	later := time.Now().Add(time.Hour)
+
+	t.Run("deletes expired clusters and records an audit entry", func(tt *testing.T) {
+		test.TruncateTables(tt, app.DB)
+		clusters := []model.Cluster{
+			{ID: "preview-1", Environment: "preview", ServerURL: "http://preview-1.localhost:6443", ClusterAuthorityData: "abcdef", ExpiresAt: &expired},
+			{ID: "preview-2", Environment: "preview", ServerURL: "http://preview-2.localhost:6443", ClusterAuthorityData: "abcdef", ExpiresAt: &later},
+			{ID: "preview-3", Environment: "preview", ServerURL: "http://preview-3.localhost:6443", ClusterAuthorityData: "abcdef"},
+		}
+		err := app.DB.Insert(&clusters)
+		require.NoError(tt, err)
+
+		webhook := model.Webhook{URL: "https://hooks.example.com/pharos", Events: []string{model.EventClusterDeleted}, Secret: "secret"}
+		_, err = app.DB.Model(&webhook).Insert()
+		require.NoError(tt, err)
+
+		reaped, err := r.ReapExpired()
+		require.NoError(tt, err)
+		require.Len(tt, reaped, 1)
+		assert.Equal(tt, "preview-1", reaped[0].ID)
+
+		var remaining []model.Cluster
+		err = app.DB.Model(&remaining).Where("deleted = FALSE").Order("id").Select()
+		require.NoError(tt, err)
+		require.Len(tt, remaining, 2)
+		assert.Equal(tt, "preview-2", remaining[0].ID)
+		assert.Equal(tt, "preview-3", remaining[1].ID)
+
+		var entries []model.AuditEntry
+		err = app.DB.Model(&entries).Select()
+		require.NoError(tt, err)
+		require.Len(tt, entries, 1)
+		assert.Equal(tt, "preview-1", entries[0].ClusterID)
+		assert.Equal(tt, model.AuditClusterExpired, entries[0].Action)
+		assert.Equal(tt, Actor, entries[0].Actor)
+
+		var deliveries []model.WebhookDelivery
+		err = app.DB.Model(&deliveries).Select()
+		require.NoError(tt, err)
+		require.Len(tt, deliveries, 1)
+		assert.Equal(tt, model.EventClusterDeleted, deliveries[0].Event)
+	})
+
+	t.Run("does nothing when no clusters have expired", func(tt *testing.T) {
+		test.TruncateTables(tt, app.DB)
+		clusters := []model.Cluster{
+			{ID: "preview-2", Environment: "preview", ServerURL: "http://preview-2.localhost:6443", ClusterAuthorityData: "abcdef", ExpiresAt: &later},
+		}
+		err := app.DB.Insert(&clusters)
+		require.NoError(tt, err)
+
+		reaped, err := r.ReapExpired()
+		require.NoError(tt, err)
+		assert.Empty(tt, reaped)
+	})
+}
@@ -9,12 +9,19 @@ import (
 	"github.com/labstack/echo"
 	logger "github.com/lob/logger-go"
 	metrics "github.com/lob/metrics-go"
+	"github.com/lob/pharos/pkg/pharos-api-server/apierrors"
 	"github.com/lob/pharos/pkg/pharos-api-server/application"
 	"github.com/lob/pharos/pkg/pharos-api-server/binder"
 	"github.com/lob/pharos/pkg/pharos-api-server/clusters"
+	"github.com/lob/pharos/pkg/pharos-api-server/discovery"
 	"github.com/lob/pharos/pkg/pharos-api-server/health"
+	"github.com/lob/pharos/pkg/pharos-api-server/healthcheck"
+	"github.com/lob/pharos/pkg/pharos-api-server/openapi"
+	"github.com/lob/pharos/pkg/pharos-api-server/reaper"
 	"github.com/lob/pharos/pkg/pharos-api-server/recovery"
 	"github.com/lob/pharos/pkg/pharos-api-server/signals"
+	"github.com/lob/pharos/pkg/pharos-api-server/versioning"
+	"github.com/lob/pharos/pkg/pharos-api-server/webhooks"
 	sentryecho "github.com/lob/sentry-echo/pkg"
 )
 
@@ -29,14 +36,20 @@ func New(app application.App) *http.Server {
 	e.Use(metrics.Middleware(app.Metrics))
 	e.Use(logger.Middleware())
 	e.Use(recovery.Middleware())
+	e.Use(versioning.Middleware(app.Config.MinimumCLIVersion))
 
 	sentryecho.RegisterErrorHandlerWithOptions(e, sentryecho.Options{
 		Reporter:                  &app.Sentry,
 		EnableCustomErrorMessages: true,
 	})
+	apierrors.RegisterErrorHandler(e)
 
 	health.RegisterRoutes(e)
+	openapi.RegisterRoutes(e)
 	clusters.RegisterRoutes(e, app)
+	discoverer := discovery.New(app, discovery.EKSProviders(app.Config.DiscoveryTargets))
+	discovery.RegisterRoutes(e, app, discoverer)
+	webhooks.RegisterRoutes(e, app)
 
 	srv := &http.Server{
 		Addr:         fmt.Sprintf(":%d", app.Config.Port),
@@ -47,6 +60,22 @@ func New(app application.App) *http.Server {
 
 	graceful := signals.Setup()
 
+	if app.Config.HealthCheckInterval > 0 {
+		go healthcheck.New(app).Run(graceful)
+	}
+
+	if app.Config.DiscoveryInterval > 0 && len(app.Config.DiscoveryTargets) > 0 {
+		go discoverer.Run(graceful)
+	}
+
+	if app.Config.WebhookInterval > 0 {
+		go webhooks.New(app).Run(graceful)
+	}
+
+	if app.Config.ReaperInterval > 0 {
+		go reaper.New(app).Run(graceful)
+	}
+
 	go func() {
 		<-graceful
 		err := srv.Shutdown(context.Background())
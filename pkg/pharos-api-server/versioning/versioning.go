@@ -0,0 +1,131 @@
+package versioning
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo"
+	logger "github.com/lob/logger-go"
+	"github.com/lob/pharos/pkg/util/version"
+)
+
+// Prefix is the path prefix of the current version of the API.
+const Prefix = "/v1"
+
+// Headers used to mark deprecated routes.
+const (
+	HeaderDeprecation = "Deprecation"
+	HeaderLink        = "Link"
+)
+
+// deprecationLogInterval is how often calls to a deprecated route are logged
+// for each CLI version that makes them.
+const deprecationLogInterval = time.Hour
+
+// maxLoggedCLIVersions bounds the number of CLI versions tracked for each
+// deprecated route, since the version is supplied by the client. Calls from
+// any further versions are counted together.
+const maxLoggedCLIVersions = 100
+
+// otherCLIVersions is the key calls are counted under once
+// maxLoggedCLIVersions is reached.
+const otherCLIVersions = "other"
+
+// Add registers a route under Prefix.
+func Add(e *echo.Echo, method, path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) {
+	e.Add(method, Prefix+path, h, m...)
+}
+
+// AddWithAlias registers a route under Prefix, along with an unversioned
+// alias of it that older CLIs still call. Responses from the alias are marked
+// as deprecated until the alias is removed. Only routes that existed before
+// Prefix was introduced need an alias.
+func AddWithAlias(e *echo.Echo, method, path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) {
+	Add(e, method, path, h, m...)
+	e.Add(method, path, h, append([]echo.MiddlewareFunc{Deprecated()}, m...)...)
+}
+
+// Deprecated sets the Deprecation header on responses, along with a Link
+// header pointing to the same path under Prefix. The headers are set before
+// the request is handled so that they're sent with errors too. Calls are
+// logged at most once per deprecationLogInterval for each version of the CLI
+// that makes them, along with the number of calls since the last log, so that
+// it's known when the alias is no longer used.
+func Deprecated() echo.MiddlewareFunc {
+	log := logger.New()
+	s := newSampler(deprecationLogInterval, maxLoggedCLIVersions)
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			cliVersion := c.Request().Header.Get(version.HeaderCLIVersion)
+			if calls, ok := s.sample(cliVersion, time.Now()); ok {
+				log.Warn("deprecated route called", logger.Data{
+					"method":      c.Request().Method,
+					"route":       c.Path(),
+					"cli_version": cliVersion,
+					"calls":       calls,
+				})
+			}
+
+			header := c.Response().Header()
+			header.Set(HeaderDeprecation, "true")
+			header.Set(HeaderLink, fmt.Sprintf("<%s%s>; rel=\"successor-version\"", Prefix, c.Request().URL.Path))
+			return next(c)
+		}
+	}
+}
+
+// Middleware reports the minimum CLI version the API supports on every
+// response, so that older CLIs can warn their users to upgrade. Nothing is
+// reported if minimum is empty.
+func Middleware(minimum string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if minimum != "" {
+				c.Response().Header().Set(version.HeaderMinimumCLIVersion, minimum)
+			}
+			return next(c)
+		}
+	}
+}
+
+// sampler counts calls by key and reports when each key is due to be logged.
+type sampler struct {
+	interval time.Duration
+	maxKeys  int
+
+	mu     sync.Mutex
+	logged map[string]time.Time
+	calls  map[string]int
+}
+
+func newSampler(interval time.Duration, maxKeys int) *sampler {
+	return &sampler{
+		interval: interval,
+		maxKeys:  maxKeys,
+		logged:   make(map[string]time.Time),
+		calls:    make(map[string]int),
+	}
+}
+
+// sample counts a call for key at now. It returns true, along with the number
+// of calls for key since it was last logged, if key hasn't been logged within
+// the interval.
+func (s *sampler) sample(key string, now time.Time) (int, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.calls[key]; !ok && len(s.calls) >= s.maxKeys {
+		key = otherCLIVersions
+	}
+
+	s.calls[key]++
+	if last, ok := s.logged[key]; ok && now.Sub(last) < s.interval {
+		return 0, false
+	}
+
+	calls := s.calls[key]
+	s.logged[key] = now
+	s.calls[key] = 0
+	return calls, true
+}
@@ -0,0 +1,146 @@
+package versioning
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo"
+	"github.com/lob/pharos/pkg/util/version"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdd(t *testing.T) {
+	e := echo.New()
+	Add(e, http.MethodGet, "/webhooks/:id", func(c echo.Context) error {
+		return c.String(http.StatusOK, c.Param("id"))
+	})
+
+	serve := func(tt *testing.T, path string) *httptest.ResponseRecorder {
+		tt.Helper()
+		req, err := http.NewRequest(http.MethodGet, path, nil)
+		require.NoError(tt, err)
+		w := httptest.NewRecorder()
+		e.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("registers the route under the version prefix", func(tt *testing.T) {
+		w := serve(tt, "/v1/webhooks/1")
+		assert.Equal(tt, http.StatusOK, w.Code)
+		assert.Equal(tt, "1", w.Body.String())
+	})
+
+	t.Run("doesn't register an unversioned alias", func(tt *testing.T) {
+		w := serve(tt, "/webhooks/1")
+		assert.Equal(tt, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestAddWithAlias(t *testing.T) {
+	e := echo.New()
+	AddWithAlias(e, http.MethodGet, "/clusters/:id", func(c echo.Context) error {
+		return c.String(http.StatusOK, c.Param("id"))
+	})
+
+	serve := func(tt *testing.T, path string) *httptest.ResponseRecorder {
+		tt.Helper()
+		req, err := http.NewRequest(http.MethodGet, path, nil)
+		require.NoError(tt, err)
+		w := httptest.NewRecorder()
+		e.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("registers the route under the version prefix", func(tt *testing.T) {
+		w := serve(tt, "/v1/clusters/sandbox-111111")
+		assert.Equal(tt, http.StatusOK, w.Code)
+		assert.Equal(tt, "sandbox-111111", w.Body.String())
+		assert.Empty(tt, w.Header().Get(HeaderDeprecation))
+	})
+
+	t.Run("registers a deprecated unversioned alias", func(tt *testing.T) {
+		w := serve(tt, "/clusters/sandbox-111111")
+		assert.Equal(tt, http.StatusOK, w.Code)
+		assert.Equal(tt, "sandbox-111111", w.Body.String())
+		assert.Equal(tt, "true", w.Header().Get(HeaderDeprecation))
+		assert.Equal(tt, `</v1/clusters/sandbox-111111>; rel="successor-version"`, w.Header().Get(HeaderLink))
+	})
+}
+
+func TestSampler(t *testing.T) {
+	start := time.Date(2019, 8, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("samples the first call of each key", func(tt *testing.T) {
+		s := newSampler(time.Hour, 10)
+
+		calls, ok := s.sample("1.4.0", start)
+		assert.True(tt, ok)
+		assert.Equal(tt, 1, calls)
+
+		calls, ok = s.sample("1.5.0", start)
+		assert.True(tt, ok)
+		assert.Equal(tt, 1, calls)
+	})
+
+	t.Run("counts the calls within the interval", func(tt *testing.T) {
+		s := newSampler(time.Hour, 10)
+
+		_, ok := s.sample("1.4.0", start)
+		assert.True(tt, ok)
+		_, ok = s.sample("1.4.0", start.Add(time.Minute))
+		assert.False(tt, ok)
+		_, ok = s.sample("1.4.0", start.Add(59*time.Minute))
+		assert.False(tt, ok)
+
+		calls, ok := s.sample("1.4.0", start.Add(time.Hour))
+		assert.True(tt, ok)
+		assert.Equal(tt, 3, calls)
+	})
+
+	t.Run("counts keys beyond the maximum together", func(tt *testing.T) {
+		s := newSampler(time.Hour, 1)
+
+		_, ok := s.sample("1.4.0", start)
+		assert.True(tt, ok)
+
+		calls, ok := s.sample("1.5.0", start)
+		assert.True(tt, ok)
+		assert.Equal(tt, 1, calls)
+		_, ok = s.sample("1.6.0", start)
+		assert.False(tt, ok)
+
+		assert.Len(tt, s.calls, 2)
+		assert.Equal(tt, 1, s.calls[otherCLIVersions])
+	})
+}
+
+func TestMiddleware(t *testing.T) {
+	serve := func(tt *testing.T, minimum string) *httptest.ResponseRecorder {
+		tt.Helper()
+		e := echo.New()
+		e.Use(Middleware(minimum))
+		e.GET("/health", func(c echo.Context) error {
+			return c.NoContent(http.StatusOK)
+		})
+
+		req, err := http.NewRequest(http.MethodGet, "/health", nil)
+		require.NoError(tt, err)
+		w := httptest.NewRecorder()
+		e.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("reports the minimum CLI version", func(tt *testing.T) {
+		w := serve(tt, "1.4.0")
+		assert.Equal(tt, "1.4.0", w.Header().Get(version.HeaderMinimumCLIVersion))
+	})
+
+	t.Run("reports nothing without a minimum CLI version", func(tt *testing.T) {
+		w := serve(tt, "")
+		_, ok := w.Header()[version.HeaderMinimumCLIVersion]
+		assert.False(tt, ok)
+	})
+}
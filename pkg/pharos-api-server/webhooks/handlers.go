@@ -0,0 +1,140 @@
+package webhooks
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-pg/pg"
+	"github.com/labstack/echo"
+	"github.com/lob/pharos/pkg/pharos-api-server/apierrors"
+	"github.com/lob/pharos/pkg/pharos-api-server/application"
+	"github.com/lob/pharos/pkg/util/model"
+	"github.com/pkg/errors"
+)
+
+type handler struct {
+	app application.App
+}
+
+func (h *handler) list(c echo.Context) error {
+	webhooks := make([]model.Webhook, 0)
+
+	err := h.app.DB.Model(&webhooks).Order("id ASC").Select()
+	if err != nil {
+		return err
+	}
+
+	// Secrets are only returned when a webhook is created.
+	for i := range webhooks {
+		webhooks[i].Secret = ""
+	}
+
+	return c.JSON(http.StatusOK, webhooks)
+}
+
+type createParams struct {
+	URL    string   `json:"url"    mod:"trim" validate:"required,url"`
+	Events []string `json:"events"            validate:"required,min=1,dive,required"`
+	Secret string   `json:"secret" mod:"trim"`
+}
+
+func (h *handler) create(c echo.Context) error {
+	params := createParams{}
+	if err := c.Bind(&params); err != nil {
+		return err
+	}
+
+	for _, event := range params.Events {
+		if !validEvent(event) {
+			return apierrors.New(http.StatusUnprocessableEntity, model.CodeValidationFailed, fmt.Sprintf("events must be one of %s", strings.Join(model.Events, ", ")))
+		}
+	}
+
+	// Generate a secret to sign payloads with if none was given.
+	if params.Secret == "" {
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return errors.WithStack(err)
+		}
+		params.Secret = hex.EncodeToString(secret)
+	}
+
+	webhook := model.Webhook{
+		URL:    params.URL,
+		Events: params.Events,
+		Secret: params.Secret,
+	}
+
+	_, err := h.app.DB.Model(&webhook).Insert()
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, webhook)
+}
+
+func (h *handler) delete(c echo.Context) error {
+	webhook, err := h.retrieve(c.Param("id"))
+	if err != nil {
+		return err
+	}
+
+	_, err = h.app.DB.Model(&webhook).WherePK().Delete()
+	if err != nil {
+		return err
+	}
+
+	webhook.Secret = ""
+	return c.JSON(http.StatusOK, webhook)
+}
+
+func (h *handler) deadLetters(c echo.Context) error {
+	webhook, err := h.retrieve(c.Param("id"))
+	if err != nil {
+		return err
+	}
+
+	deadLetters := make([]model.WebhookDeadLetter, 0)
+	err = h.app.DB.Model(&deadLetters).
+		Where("webhook_id = ?", webhook.ID).
+		Order("date_failed DESC").
+		Select()
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, deadLetters)
+}
+
+// retrieve returns the webhook with the given ID.
+func (h *handler) retrieve(param string) (model.Webhook, error) {
+	var webhook model.Webhook
+
+	id, err := strconv.ParseInt(param, 10, 64)
+	if err != nil {
+		return webhook, apierrors.New(http.StatusNotFound, model.CodeWebhookNotFound, "webhook not found")
+	}
+
+	err = h.app.DB.Model(&webhook).Where("id = ?", id).First()
+	if err != nil {
+		if err == pg.ErrNoRows {
+			return webhook, apierrors.New(http.StatusNotFound, model.CodeWebhookNotFound, "webhook not found")
+		}
+		return webhook, err
+	}
+
+	return webhook, nil
+}
+
+func validEvent(event string) bool {
+	for _, e := range model.Events {
+		if event == e {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,190 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo"
+	"github.com/lob/pharos/internal/test"
+	"github.com/lob/pharos/pkg/pharos-api-server/application"
+	"github.com/lob/pharos/pkg/util/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var defaultTestWebhook = model.Webhook{
+	URL:    "https://hooks.example.com/pharos",
+	Events: []string{model.EventClusterCreated, model.EventClusterActivated},
+	Secret: "secret",
+}
+
+func TestListHandler(t *testing.T) {
+	h := newHandler(t)
+
+	t.Run("lists webhooks without their secrets", func(tt *testing.T) {
+		test.TruncateTables(tt, h.app.DB)
+		webhook := defaultTestWebhook
+		_, err := h.app.DB.Model(&webhook).Insert()
+		require.NoError(tt, err)
+
+		c, rr := test.NewContext(tt, "GET", "", strings.NewReader(""), "application/json")
+
+		err = h.list(c)
+		assert.NoError(tt, err)
+		assert.Equal(tt, http.StatusOK, rr.Code)
+
+		var response []model.Webhook
+		err = json.Unmarshal(rr.Body.Bytes(), &response)
+		require.NoError(tt, err)
+		require.Len(tt, response, 1)
+		assert.Equal(tt, defaultTestWebhook.URL, response[0].URL)
+		assert.Equal(tt, defaultTestWebhook.Events, response[0].Events)
+		assert.Equal(tt, "", response[0].Secret)
+	})
+}
+
+func TestCreateHandler(t *testing.T) {
+	h := newHandler(t)
+
+	t.Run("creates a webhook with a generated secret", func(tt *testing.T) {
+		test.TruncateTables(tt, h.app.DB)
+
+		payload := `{"url":"https://hooks.example.com/pharos","events":["cluster.created"]}`
+		c, rr := test.NewContext(tt, "POST", "", strings.NewReader(payload), "application/json")
+
+		err := h.create(c)
+		assert.NoError(tt, err)
+		assert.Equal(tt, http.StatusOK, rr.Code)
+
+		var response model.Webhook
+		err = json.Unmarshal(rr.Body.Bytes(), &response)
+		require.NoError(tt, err)
+		assert.NotZero(tt, response.ID)
+		assert.Equal(tt, []string{model.EventClusterCreated}, response.Events)
+		assert.Len(tt, response.Secret, 64)
+	})
+
+	t.Run("errors with invalid payload", func(tt *testing.T) {
+		test.TruncateTables(tt, h.app.DB)
+
+		cases := []struct {
+			payload, errorMessage string
+		}{
+			{
+				`{"events": ["cluster.created"]}`,
+				"url is required",
+			},
+			{
+				`{"url": "string", "events": ["cluster.created"]}`,
+				"url must be a valid URL",
+			},
+			{
+				`{"url": "https://hooks.example.com/pharos"}`,
+				"events is required",
+			},
+			{
+				`{"url": "https://hooks.example.com/pharos", "events": []}`,
+				"events must contain at least one item",
+			},
+			{
+				`{"url": "https://hooks.example.com/pharos", "events": [""]}`,
+				"events[0] is required",
+			},
+			{
+				`{"url": "https://hooks.example.com/pharos", "events": ["cluster.exploded"]}`,
+				"events must be one of cluster.created, cluster.deleted, cluster.activated",
+			},
+		}
+
+		for _, tc := range cases {
+			c, _ := test.NewContext(tt, "POST", "", strings.NewReader(tc.payload), "application/json")
+			err := h.create(c)
+			assert.Error(tt, err)
+			assert.Contains(tt, err.Error(), tc.errorMessage)
+		}
+	})
+}
+
+func TestDeleteHandler(t *testing.T) {
+	h := newHandler(t)
+
+	t.Run("deletes a webhook", func(tt *testing.T) {
+		test.TruncateTables(tt, h.app.DB)
+		webhook := defaultTestWebhook
+		_, err := h.app.DB.Model(&webhook).Insert()
+		require.NoError(tt, err)
+
+		c, rr := test.NewContext(tt, "DELETE", "", strings.NewReader(""), "application/json")
+		c.SetParamNames("id")
+		c.SetParamValues(fmt.Sprint(webhook.ID))
+
+		err = h.delete(c)
+		assert.NoError(tt, err)
+		assert.Equal(tt, http.StatusOK, rr.Code)
+
+		count, err := h.app.DB.Model(&model.Webhook{}).Count()
+		require.NoError(tt, err)
+		assert.Equal(tt, 0, count)
+	})
+
+	t.Run("errors deleting a non-existing webhook", func(tt *testing.T) {
+		test.TruncateTables(tt, h.app.DB)
+
+		for _, id := range []string{"42", "random"} {
+			c, _ := test.NewContext(tt, "DELETE", "", strings.NewReader(""), "application/json")
+			c.SetParamNames("id")
+			c.SetParamValues(id)
+
+			err := h.delete(c)
+			require.Error(tt, err)
+			assert.Contains(tt, err.Error(), "webhook not found")
+			assert.Equal(tt, model.CodeWebhookNotFound, err.(*echo.HTTPError).Message.(*model.Error).Code)
+		}
+	})
+}
+
+func TestDeadLettersHandler(t *testing.T) {
+	h := newHandler(t)
+
+	t.Run("lists the dead letters of a webhook", func(tt *testing.T) {
+		test.TruncateTables(tt, h.app.DB)
+		webhook := defaultTestWebhook
+		_, err := h.app.DB.Model(&webhook).Insert()
+		require.NoError(tt, err)
+
+		deadLetter := model.WebhookDeadLetter{
+			WebhookID: webhook.ID,
+			Event:     model.EventClusterCreated,
+			Payload:   `{}`,
+			Attempts:  10,
+			LastError: "webhook responded with 500 Internal Server Error",
+		}
+		_, err = h.app.DB.Model(&deadLetter).Insert()
+		require.NoError(tt, err)
+
+		c, rr := test.NewContext(tt, "GET", "", strings.NewReader(""), "application/json")
+		c.SetParamNames("id")
+		c.SetParamValues(fmt.Sprint(webhook.ID))
+
+		err = h.deadLetters(c)
+		assert.NoError(tt, err)
+		assert.Equal(tt, http.StatusOK, rr.Code)
+
+		var response []model.WebhookDeadLetter
+		err = json.Unmarshal(rr.Body.Bytes(), &response)
+		require.NoError(tt, err)
+		require.Len(tt, response, 1)
+		assert.Equal(tt, deadLetter.LastError, response[0].LastError)
+	})
+}
+
+func newHandler(t *testing.T) handler {
+	t.Helper()
+
+	app, err := application.New()
+	require.NoError(t, err)
+	return handler{app}
+}
@@ -0,0 +1,18 @@
+package webhooks
+
+import (
+	"testing"
+
+	"github.com/lob/pharos/internal/test"
+	"github.com/lob/pharos/pkg/pharos-api-server/openapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenAPI(t *testing.T) {
+	t.Run("documents every field the handlers bind", func(tt *testing.T) {
+		spec, err := openapi.Load()
+		require.NoError(tt, err)
+		assert.Equal(tt, test.FieldNames(createParams{}, "json"), spec.Properties("CreateWebhookParams"))
+	})
+}
@@ -0,0 +1,25 @@
+package webhooks
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo"
+	"github.com/lob/pharos/pkg/pharos-api-server/application"
+	"github.com/lob/pharos/pkg/pharos-api-server/authentication"
+	"github.com/lob/pharos/pkg/pharos-api-server/authorization"
+	"github.com/lob/pharos/pkg/pharos-api-server/versioning"
+)
+
+// RegisterRoutes takes in an Echo router and registers routes onto it. Routes
+// are registered under the /v1 prefix, with deprecated unversioned aliases for
+// older CLIs.
+func RegisterRoutes(e *echo.Echo, app application.App) {
+	h := handler{app}
+
+	config := app.Config
+
+	versioning.Add(e, http.MethodGet, "/webhooks", h.list, authentication.Middleware(app.TokenVerifier), authorization.Middleware(config.Permissions.Read))
+	versioning.Add(e, http.MethodPost, "/webhooks", h.create, authentication.Middleware(app.TokenVerifier), authorization.Middleware(config.Permissions.Admin))
+	versioning.Add(e, http.MethodDelete, "/webhooks/:id", h.delete, authentication.Middleware(app.TokenVerifier), authorization.Middleware(config.Permissions.Admin))
+	versioning.Add(e, http.MethodGet, "/webhooks/:id/dead_letters", h.deadLetters, authentication.Middleware(app.TokenVerifier), authorization.Middleware(config.Permissions.Read))
+}
@@ -0,0 +1,260 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-pg/pg"
+	"github.com/go-pg/pg/orm"
+	logger "github.com/lob/logger-go"
+	"github.com/lob/pharos/pkg/pharos-api-server/application"
+	"github.com/lob/pharos/pkg/util/model"
+	"github.com/pkg/errors"
+)
+
+// Headers sent with every webhook delivery.
+const (
+	HeaderDelivery  = "X-Pharos-Delivery"
+	HeaderEvent     = "X-Pharos-Event"
+	HeaderSignature = "X-Pharos-Signature"
+)
+
+// batchSize is the maximum number of deliveries attempted at once.
+const batchSize = 100
+
+// deliveryLease is how long a delivery is reserved for the server attempting
+// it, so that other servers don't attempt it at the same time.
+const deliveryLease = 5 * time.Minute
+
+// Failed deliveries are retried after backoffBase, doubling with every attempt
+// up to backoffMax.
+var (
+	backoffBase = 30 * time.Second
+	backoffMax  = time.Hour
+)
+
+// Enqueue schedules the delivery of an event about a cluster to every webhook
+// subscribed to it. It should be called in the same transaction as the change
+// that caused the event.
+func Enqueue(db orm.DB, event string, cluster model.Cluster) error {
+	var webhooks []model.Webhook
+	err := db.Model(&webhooks).Where("? = ANY(events)", event).Select()
+	if err != nil {
+		return errors.Wrap(err, "failed to list webhooks")
+	}
+	if len(webhooks) == 0 {
+		return nil
+	}
+
+	payload, err := json.Marshal(model.WebhookEvent{
+		Event:       event,
+		Cluster:     cluster,
+		DateCreated: time.Now(),
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	deliveries := make([]model.WebhookDelivery, len(webhooks))
+	for i, webhook := range webhooks {
+		deliveries[i] = model.WebhookDelivery{
+			WebhookID: webhook.ID,
+			Event:     event,
+			Payload:   string(payload),
+		}
+	}
+
+	_, err = db.Model(&deliveries).Insert()
+	return errors.Wrap(err, "failed to enqueue webhook deliveries")
+}
+
+// Sign returns the signature of a payload sent with the given secret, the hex
+// encoded HMAC-SHA256 of the payload prefixed with "sha256=".
+func Sign(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Backoff returns how long to wait before retrying a delivery that has failed
+// the given number of times.
+func Backoff(attempts int) time.Duration {
+	backoff := backoffBase
+	for i := 1; i < attempts && backoff < backoffMax; i++ {
+		backoff *= 2
+	}
+	if backoff > backoffMax {
+		return backoffMax
+	}
+	return backoff
+}
+
+// Deliverer POSTs pending events to their webhooks. Failed deliveries are
+// retried with exponential backoff, and moved to the webhook_dead_letters
+// table after Config.WebhookMaxAttempts attempts.
+type Deliverer struct {
+	app    application.App
+	log    logger.Logger
+	client *http.Client
+}
+
+// New creates a new Deliverer for the given application.
+func New(app application.App) *Deliverer {
+	return &Deliverer{app, logger.New(), &http.Client{Timeout: app.Config.WebhookTimeout}}
+}
+
+// Run delivers pending events every Config.WebhookInterval until the stop
+// channel is closed.
+func (d *Deliverer) Run(stop <-chan struct{}) {
+	ticker := time.NewTicker(d.app.Config.WebhookInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := d.DeliverPending(); err != nil {
+			d.log.Err(err).Error("webhook delivery failed")
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DeliverPending attempts every delivery that is due, concurrently, and
+// records the results.
+func (d *Deliverer) DeliverPending() error {
+	now := time.Now()
+
+	// Reserve the deliveries that are due so that they aren't attempted by
+	// another server at the same time.
+	var deliveries []model.WebhookDelivery
+	_, err := d.app.DB.Query(&deliveries, `
+		UPDATE webhook_deliveries
+		SET next_attempt = ?
+		WHERE id IN (
+			SELECT id
+			FROM webhook_deliveries
+			WHERE next_attempt <= ?
+			ORDER BY next_attempt
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *
+	`, now.Add(deliveryLease), now, batchSize)
+	if err != nil {
+		return errors.Wrap(err, "failed to reserve webhook deliveries")
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, len(deliveries))
+	for i, delivery := range deliveries {
+		ids[i] = delivery.WebhookID
+	}
+	var webhooks []model.Webhook
+	if err := d.app.DB.Model(&webhooks).Where("id IN (?)", pg.In(ids)).Select(); err != nil {
+		return errors.Wrap(err, "failed to list webhooks")
+	}
+	byID := make(map[int64]model.Webhook, len(webhooks))
+	for _, webhook := range webhooks {
+		byID[webhook.ID] = webhook
+	}
+
+	errs := make([]error, len(deliveries))
+	var wg sync.WaitGroup
+	for i := range deliveries {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = d.deliver(byID[deliveries[i].WebhookID], deliveries[i])
+		}(i)
+	}
+	wg.Wait()
+
+	for i := range deliveries {
+		if err := d.record(deliveries[i], errs[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deliver POSTs a single event to a webhook.
+func (d *Deliverer) deliver(webhook model.Webhook, delivery model.WebhookDelivery) error {
+	req, err := http.NewRequest(http.MethodPost, webhook.URL, strings.NewReader(delivery.Payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderDelivery, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(HeaderEvent, delivery.Event)
+	req.Header.Set(HeaderSignature, Sign(webhook.Secret, []byte(delivery.Payload)))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with %s", resp.Status)
+	}
+	return nil
+}
+
+// record removes a successful delivery, and either schedules a failed delivery
+// to be retried or moves it to the dead letters.
+func (d *Deliverer) record(delivery model.WebhookDelivery, deliveryErr error) error {
+	if deliveryErr == nil {
+		_, err := d.app.DB.Model(&delivery).WherePK().Delete()
+		return errors.Wrap(err, "failed to remove webhook delivery")
+	}
+
+	delivery.Attempts++
+	delivery.LastError = deliveryErr.Error()
+
+	if delivery.Attempts < d.app.Config.WebhookMaxAttempts {
+		delivery.NextAttempt = time.Now().Add(Backoff(delivery.Attempts))
+		_, err := d.app.DB.Model(&delivery).Column("attempts", "last_error", "next_attempt").WherePK().Update()
+		return errors.Wrap(err, "failed to reschedule webhook delivery")
+	}
+
+	d.log.Info("webhook delivery failed permanently", logger.Data{
+		"webhook_id": delivery.WebhookID,
+		"event":      delivery.Event,
+		"attempts":   delivery.Attempts,
+		"error":      delivery.LastError,
+	})
+
+	err := d.app.DB.RunInTransaction(func(tx *pg.Tx) error {
+		deadLetter := model.WebhookDeadLetter{
+			WebhookID:   delivery.WebhookID,
+			Event:       delivery.Event,
+			Payload:     delivery.Payload,
+			Attempts:    delivery.Attempts,
+			LastError:   delivery.LastError,
+			DateCreated: delivery.DateCreated,
+		}
+		if _, err := tx.Model(&deadLetter).Insert(); err != nil {
+			return err
+		}
+
+		_, err := tx.Model(&delivery).WherePK().Delete()
+		return err
+	})
+	return errors.Wrap(err, "failed to record webhook dead letter")
+}
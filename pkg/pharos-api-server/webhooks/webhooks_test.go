@@ -0,0 +1,131 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/lob/pharos/internal/test"
+	"github.com/lob/pharos/pkg/pharos-api-server/application"
+	"github.com/lob/pharos/pkg/util/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testCluster = model.Cluster{
+	ID:                   "test-1",
+	Environment:          "test",
+	ServerURL:            "http://test-1.localhost:6443",
+	ClusterAuthorityData: "abcdef",
+}
+
+func TestSign(t *testing.T) {
+	t.Run("signs payloads with HMAC-SHA256", func(tt *testing.T) {
+		signature := Sign("secret", []byte(`{"event":"cluster.created"}`))
+		assert.Equal(tt, "sha256=46b379c40d65462aac8f55ab1d33e4dab46623be502741bd899c33bd52bb84e9", signature)
+		assert.NotEqual(tt, signature, Sign("other", []byte(`{"event":"cluster.created"}`)))
+	})
+}
+
+func TestBackoff(t *testing.T) {
+	t.Run("doubles up to the maximum", func(tt *testing.T) {
+		assert.Equal(tt, 30*time.Second, Backoff(1))
+		assert.Equal(tt, time.Minute, Backoff(2))
+		assert.Equal(tt, 2*time.Minute, Backoff(3))
+		assert.Equal(tt, time.Hour, Backoff(8))
+		assert.Equal(tt, time.Hour, Backoff(100))
+	})
+}
+
+func TestDeliverPending(t *testing.T) {
+	app, err := application.New()
+	require.NoError(t, err)
+	app.Config.WebhookMaxAttempts = 2
+	d := New(app)
+
+	t.Run("delivers signed events", func(tt *testing.T) {
+		test.TruncateTables(tt, app.DB)
+
+		var received *http.Request
+		var body []byte
+		srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			received = r
+			body, _ = ioutil.ReadAll(r.Body)
+		}))
+		defer srv.Close()
+
+		webhook := model.Webhook{URL: srv.URL, Events: []string{model.EventClusterCreated}, Secret: "secret"}
+		_, err := app.DB.Model(&webhook).Insert()
+		require.NoError(tt, err)
+
+		// Only subscribed events are enqueued.
+		err = Enqueue(app.DB, model.EventClusterDeleted, testCluster)
+		require.NoError(tt, err)
+		err = Enqueue(app.DB, model.EventClusterCreated, testCluster)
+		require.NoError(tt, err)
+
+		err = d.DeliverPending()
+		require.NoError(tt, err)
+
+		require.NotNil(tt, received)
+		assert.Equal(tt, model.EventClusterCreated, received.Header.Get(HeaderEvent))
+		assert.Equal(tt, Sign("secret", body), received.Header.Get(HeaderSignature))
+
+		var event model.WebhookEvent
+		err = json.Unmarshal(body, &event)
+		require.NoError(tt, err)
+		assert.Equal(tt, model.EventClusterCreated, event.Event)
+		assert.Equal(tt, testCluster.ID, event.Cluster.ID)
+
+		count, err := app.DB.Model(&model.WebhookDelivery{}).Count()
+		require.NoError(tt, err)
+		assert.Equal(tt, 0, count)
+	})
+
+	t.Run("retries failed deliveries and then dead letters them", func(tt *testing.T) {
+		test.TruncateTables(tt, app.DB)
+
+		srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			rw.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer srv.Close()
+
+		webhook := model.Webhook{URL: srv.URL, Events: model.Events, Secret: "secret"}
+		_, err := app.DB.Model(&webhook).Insert()
+		require.NoError(tt, err)
+
+		err = Enqueue(app.DB, model.EventClusterActivated, testCluster)
+		require.NoError(tt, err)
+
+		err = d.DeliverPending()
+		require.NoError(tt, err)
+
+		var delivery model.WebhookDelivery
+		err = app.DB.Model(&delivery).First()
+		require.NoError(tt, err)
+		assert.Equal(tt, 1, delivery.Attempts)
+		assert.Contains(tt, delivery.LastError, "500 Internal Server Error")
+		assert.True(tt, delivery.NextAttempt.After(time.Now()))
+
+		// Make the delivery due again.
+		_, err = app.DB.Model(&delivery).Set("next_attempt = ?", time.Now()).WherePK().Update()
+		require.NoError(tt, err)
+
+		err = d.DeliverPending()
+		require.NoError(tt, err)
+
+		count, err := app.DB.Model(&model.WebhookDelivery{}).Count()
+		require.NoError(tt, err)
+		assert.Equal(tt, 0, count)
+
+		var deadLetter model.WebhookDeadLetter
+		err = app.DB.Model(&deadLetter).First()
+		require.NoError(tt, err)
+		assert.Equal(tt, webhook.ID, deadLetter.WebhookID)
+		assert.Equal(tt, model.EventClusterActivated, deadLetter.Event)
+		assert.Equal(tt, 2, deadLetter.Attempts)
+	})
+}
@@ -4,25 +4,39 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"os"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/fatih/color"
 	"github.com/lob/pharos/pkg/pharos/config"
+	"github.com/lob/pharos/pkg/util/model"
 	"github.com/lob/pharos/pkg/util/token"
+	"github.com/lob/pharos/pkg/util/version"
 	"github.com/pkg/errors"
 )
 
+// Version is the version of the CLI, which is sent to the Pharos API with
+// every request. It is empty for development builds, which don't send it.
+var Version string
+
 // Client is a struct containing information for an api client.
 type Client struct {
 	client         *http.Client
 	config         *config.Config
 	TokenGenerator token.Generator
+
+	// warnings is where the warning about the CLI being older than the API
+	// supports is written, at most once per Client.
+	warnings io.Writer
+	warned   bool
 }
 
 // NewClient creates a new Client with its own http.Client.
@@ -31,7 +45,7 @@ func NewClient(config *config.Config, generator token.Generator) *Client {
 		Timeout: 10 * time.Second,
 	}
 
-	return &Client{c, config, generator}
+	return &Client{client: c, config: config, TokenGenerator: generator, warnings: os.Stderr}
 }
 
 // ClientFromConfig creates a new Client with its own http.Client
@@ -75,9 +89,22 @@ func ClientFromConfig(configFile string) (*Client, error) {
 	return NewClient(c, token.NewGenerator(stsAPI)), nil
 }
 
+// IsPreconditionFailed returns whether an error was caused by the Pharos API
+// refusing a change because the cluster had been modified since the version it
+// was made against.
+func IsPreconditionFailed(err error) bool {
+	var apiErr *model.Error
+	return errors.As(err, &apiErr) && apiErr.Code == model.CodePreconditionFailed
+}
+
 // send sends a http.Request for the specified method and path, with the given body encoded as JSON.
 // It then marshalls the returned response into the given response interface.
 func (c *Client) send(method string, path string, query map[string]string, body interface{}, response interface{}) error {
+	return c.sendWithHeaders(method, path, query, nil, body, response)
+}
+
+// sendWithHeaders sends a http.Request like send, with additional headers.
+func (c *Client) sendWithHeaders(method string, path string, query map[string]string, headers map[string]string, body interface{}, response interface{}) error {
 	buf := &bytes.Buffer{}
 	if body != nil {
 		if err := json.NewEncoder(buf).Encode(body); err != nil {
@@ -98,6 +125,12 @@ func (c *Client) send(method string, path string, query map[string]string, body
 		return errors.Wrap(err, "unable to create authorization token")
 	}
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	if Version != "" {
+		req.Header.Set(version.HeaderCLIVersion, Version)
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
 
 	// Add queries to request if there are any.
 	if query != nil {
@@ -115,6 +148,8 @@ func (c *Client) send(method string, path string, query map[string]string, body
 	}
 	defer resp.Body.Close()
 
+	c.checkVersion(resp)
+
 	err = checkError(resp)
 	if err != nil {
 		return errors.Wrap(err, "response contained error")
@@ -133,15 +168,37 @@ func (c *Client) send(method string, path string, query map[string]string, body
 	return nil
 }
 
+// checkVersion warns once if the Pharos API reports that it no longer supports
+// this version of the CLI. Development builds and versions that can't be
+// parsed are never warned about.
+func (c *Client) checkVersion(resp *http.Response) {
+	minimum := resp.Header.Get(version.HeaderMinimumCLIVersion)
+	if c.warned || Version == "" || minimum == "" {
+		return
+	}
+
+	current, err := version.Parse(Version)
+	if err != nil {
+		return
+	}
+	required, err := version.Parse(minimum)
+	if err != nil || !current.Less(required) {
+		return
+	}
+
+	c.warned = true
+	if c.warnings != nil {
+		fmt.Fprintf(c.warnings, "%s PHAROS %s IS OLDER THAN %s, THE OLDEST VERSION THE PHAROS API SUPPORTS, PLEASE UPGRADE\n", color.YellowString("WARNING:"), Version, minimum)
+	}
+}
+
 func checkError(resp *http.Response) error {
 	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
 		return nil
 	}
 
 	errMsg := new(struct {
-		Err struct {
-			Message string `json:"message"`
-		} `json:"error"`
+		Err model.Error `json:"error"`
 	})
 
 	err := json.NewDecoder(resp.Body).Decode(errMsg)
@@ -149,5 +206,11 @@ func checkError(resp *http.Response) error {
 		return errors.Wrap(err, http.StatusText((resp.StatusCode)))
 	}
 
-	return fmt.Errorf("%s (%d)", errMsg.Err.Message, resp.StatusCode)
+	// Older servers and unexpected errors don't have a code.
+	apiErr := &errMsg.Err
+	apiErr.StatusCode = resp.StatusCode
+	if apiErr.Code == "" {
+		apiErr.Code = model.CodeForStatus(resp.StatusCode)
+	}
+	return apiErr
 }
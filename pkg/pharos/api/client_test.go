@@ -1,6 +1,7 @@
 package api
 
 import (
+	"bytes"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
@@ -11,6 +12,8 @@ import (
 	"github.com/lob/pharos/internal/test"
 	"github.com/lob/pharos/pkg/pharos/config"
 	"github.com/lob/pharos/pkg/util/model"
+	"github.com/lob/pharos/pkg/util/version"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -59,6 +62,60 @@ func TestClient(t *testing.T) {
 	})
 }
 
+func TestVersion(t *testing.T) {
+	var sent string
+	minimum := "1.4.0"
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		sent = r.Header.Get(version.HeaderCLIVersion)
+		rw.Header().Set(version.HeaderMinimumCLIVersion, minimum)
+		_, err := rw.Write([]byte(`{"id": "production-6906ce"}`))
+		require.NoError(t, err)
+	}))
+	defer srv.Close()
+
+	original := Version
+	defer func() { Version = original }()
+
+	newClient := func() (*Client, *bytes.Buffer) {
+		c := NewClient(&config.Config{BaseURL: srv.URL}, test.NewGenerator())
+		out := &bytes.Buffer{}
+		c.warnings = out
+		return c, out
+	}
+
+	t.Run("sends the CLI version", func(tt *testing.T) {
+		Version = "1.4.2"
+		c, out := newClient()
+
+		err := c.send(http.MethodGet, "", nil, nil, &model.Cluster{})
+		require.NoError(tt, err)
+		assert.Equal(tt, "1.4.2", sent)
+		assert.Empty(tt, out.String())
+	})
+
+	t.Run("warns once when the CLI is older than the API supports", func(tt *testing.T) {
+		Version = "v1.3.9"
+		c, out := newClient()
+
+		for i := 0; i < 2; i++ {
+			err := c.send(http.MethodGet, "", nil, nil, &model.Cluster{})
+			require.NoError(tt, err)
+		}
+		assert.Equal(tt, 1, strings.Count(out.String(), "PLEASE UPGRADE"))
+		assert.Contains(tt, out.String(), "PHAROS v1.3.9 IS OLDER THAN 1.4.0")
+	})
+
+	t.Run("doesn't send a version or warn for development builds", func(tt *testing.T) {
+		Version = ""
+		c, out := newClient()
+
+		err := c.send(http.MethodGet, "", nil, nil, &model.Cluster{})
+		require.NoError(tt, err)
+		assert.Empty(tt, sent)
+		assert.Empty(tt, out.String())
+	})
+}
+
 func TestClientFromConfig(t *testing.T) {
 	t.Run("successfully creates a new client", func(tt *testing.T) {
 		c, err := ClientFromConfig(configFile)
@@ -78,6 +135,27 @@ func TestCheckError(t *testing.T) {
 		})
 		assert.Error(tt, err)
 		assert.Contains(tt, err.Error(), "internal server error")
+		assert.False(tt, IsPreconditionFailed(err))
+
+		// Errors without a code are given the code for their status.
+		var apiErr *model.Error
+		require.True(tt, errors.As(err, &apiErr))
+		assert.Equal(tt, model.CodeInternal, apiErr.Code)
+	})
+
+	t.Run("returns typed errors with their code", func(tt *testing.T) {
+		err := checkError(&http.Response{
+			Body:       ioutil.NopCloser(strings.NewReader(`{"error": {"code": "cluster_not_found", "message": "cluster not found", "status_code": 404}}`)),
+			StatusCode: http.StatusNotFound,
+		})
+		err = errors.Wrap(errors.Wrap(err, "response contained error"), "failed to get cluster")
+
+		var apiErr *model.Error
+		require.True(tt, errors.As(err, &apiErr))
+		assert.Equal(tt, &model.Error{Code: model.CodeClusterNotFound, Message: "cluster not found", StatusCode: http.StatusNotFound}, apiErr)
+		assert.Contains(tt, err.Error(), "cluster not found (404)")
+
+		assert.False(tt, errors.As(errors.New("connection refused"), &apiErr))
 	})
 
 	t.Run("returns nil upon receiving a response with no errors", func(tt *testing.T) {
@@ -3,24 +3,30 @@ package api
 import (
 	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/lob/pharos/pkg/util/model"
 	"github.com/pkg/errors"
 )
 
-// Cluster describes a new cluster to be created in Pharos.
+// Cluster describes a new cluster to be created in Pharos. Clusters with an
+// expiry are deleted automatically once it passes.
 type Cluster struct {
-	ID                   string `json:"id"`
-	Environment          string `json:"environment"`
-	ServerURL            string `json:"server_url"`
-	ClusterAuthorityData string `json:"cluster_authority_data"`
+	ID                   string     `json:"id"`
+	Environment          string     `json:"environment"`
+	ServerURL            string     `json:"server_url"`
+	ClusterAuthorityData string     `json:"cluster_authority_data"`
+	ExpiresAt            *time.Time `json:"expires_at,omitempty"`
 }
 
 // DeleteCluster sends a DELETE request to the clusters endpoint of the Pharos API
-// and returns a Cluster containing the deleted cluster.
-func (c *Client) DeleteCluster(clusterID string) (model.Cluster, error) {
+// and returns a Cluster containing the deleted cluster. Unless version is 0, the
+// cluster is only deleted if its resource version still matches, and an error
+// satisfying IsPreconditionFailed is returned otherwise.
+func (c *Client) DeleteCluster(clusterID string, version int64) (model.Cluster, error) {
 	var cluster model.Cluster
-	err := c.send(http.MethodDelete, fmt.Sprintf("clusters/%s", clusterID), nil, nil, &cluster)
+	err := c.sendWithHeaders(http.MethodDelete, fmt.Sprintf("v1/clusters/%s", clusterID), nil, ifMatch(version), nil, &cluster)
 	if err != nil {
 		return cluster, errors.Wrapf(err, "failed to delete cluster %s", clusterID)
 	}
@@ -33,7 +39,7 @@ func (c *Client) DeleteCluster(clusterID string) (model.Cluster, error) {
 // a certain subset of clusters.
 func (c *Client) ListClusters(query map[string]string) ([]model.Cluster, error) {
 	var clusters []model.Cluster
-	err := c.send(http.MethodGet, "clusters", query, nil, &clusters)
+	err := c.send(http.MethodGet, "v1/clusters", query, nil, &clusters)
 	if err != nil {
 		return clusters, errors.Wrap(err, "failed to list clusters")
 	}
@@ -41,12 +47,43 @@ func (c *Client) ListClusters(query map[string]string) ([]model.Cluster, error)
 	return clusters, nil
 }
 
+// WatchClusters sends a GET request to the clusters endpoint of the Pharos API
+// that waits up to timeout for clusters to change, and returns the clusters,
+// including deleted ones, with a resource version greater than since. It
+// returns no clusters if none changed before the timeout.
+func (c *Client) WatchClusters(since int64, timeout time.Duration) ([]model.Cluster, error) {
+	var clusters []model.Cluster
+	query := map[string]string{
+		"watch":   "true",
+		"since":   strconv.FormatInt(since, 10),
+		"timeout": timeout.String(),
+	}
+
+	// Give the server the whole timeout to respond on top of the usual one.
+	watcher := *c
+	watcher.client = &http.Client{Timeout: c.client.Timeout + timeout}
+
+	err := watcher.send(http.MethodGet, "v1/clusters", query, nil, &clusters)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to watch clusters")
+	}
+
+	return clusters, nil
+}
+
 // CreateCluster sends a POST request to the clusters endpoint of the Pharos API
-// and returns the Cluster that was created.
-func (c *Client) CreateCluster(newCluster Cluster) (model.Cluster, error) {
+// and returns the Cluster that was created. Unless idempotencyKey is empty, it
+// is sent as the Idempotency-Key header, so that retrying with the same key
+// returns the cluster created by the first request instead of an error.
+func (c *Client) CreateCluster(newCluster Cluster, idempotencyKey string) (model.Cluster, error) {
 	var cluster model.Cluster
 
-	err := c.send(http.MethodPost, "clusters", nil, newCluster, &cluster)
+	var headers map[string]string
+	if idempotencyKey != "" {
+		headers = map[string]string{"Idempotency-Key": idempotencyKey}
+	}
+
+	err := c.sendWithHeaders(http.MethodPost, "v1/clusters", nil, headers, newCluster, &cluster)
 	if err != nil {
 		return cluster, errors.Wrap(err, "failed to create cluster")
 	}
@@ -54,11 +91,26 @@ func (c *Client) CreateCluster(newCluster Cluster) (model.Cluster, error) {
 	return cluster, nil
 }
 
+// UpsertCluster sends a PUT request to the clusters/id endpoint of the Pharos
+// API, which creates the cluster or replaces the registration of an existing
+// one, and returns the resulting Cluster. Unless version is 0, an existing
+// cluster is only replaced if its resource version still matches.
+func (c *Client) UpsertCluster(newCluster Cluster, version int64) (model.Cluster, error) {
+	var cluster model.Cluster
+
+	err := c.sendWithHeaders(http.MethodPut, fmt.Sprintf("v1/clusters/%s", newCluster.ID), nil, ifMatch(version), newCluster, &cluster)
+	if err != nil {
+		return cluster, errors.Wrapf(err, "failed to upsert cluster %s", newCluster.ID)
+	}
+
+	return cluster, nil
+}
+
 // GetCluster sends a GET request to the clusters/id endpoint of the Pharos API
 // and returns a Cluster.
 func (c *Client) GetCluster(clusterID string) (model.Cluster, error) {
 	var cluster model.Cluster
-	err := c.send(http.MethodGet, fmt.Sprintf("clusters/%s", clusterID), nil, nil, &cluster)
+	err := c.send(http.MethodGet, fmt.Sprintf("v1/clusters/%s", clusterID), nil, nil, &cluster)
 	if err != nil {
 		return cluster, errors.Wrap(err, "failed to get cluster")
 	}
@@ -66,18 +118,103 @@ func (c *Client) GetCluster(clusterID string) (model.Cluster, error) {
 	return cluster, nil
 }
 
+// ResolveCluster sends a GET request to the resolve/name endpoint of the Pharos
+// API and returns the Cluster with the given ID, or the active Cluster of the
+// environment with the given name.
+func (c *Client) ResolveCluster(name string) (model.Cluster, error) {
+	var cluster model.Cluster
+	err := c.send(http.MethodGet, fmt.Sprintf("v1/resolve/%s", name), nil, nil, &cluster)
+	if err != nil {
+		return cluster, errors.Wrapf(err, "failed to resolve cluster %s", name)
+	}
+
+	return cluster, nil
+}
+
 // UpdateCluster sends a POST request to the clusters/id endpoint of the Pharos API
-// and returns a Cluster containing the updated cluster.
-func (c *Client) UpdateCluster(clusterID string, active bool) (model.Cluster, error) {
+// and returns a Cluster containing the updated cluster. Unless version is 0, the
+// cluster is only updated if its resource version still matches, and an error
+// satisfying IsPreconditionFailed is returned otherwise.
+func (c *Client) UpdateCluster(clusterID string, active bool, version int64) (model.Cluster, error) {
 	var cluster model.Cluster
 	update := &struct {
 		Active bool `json:"active"`
 	}{Active: active}
 
-	err := c.send(http.MethodPost, fmt.Sprintf("clusters/%s", clusterID), nil, update, &cluster)
+	err := c.sendWithHeaders(http.MethodPost, fmt.Sprintf("v1/clusters/%s", clusterID), nil, ifMatch(version), update, &cluster)
 	if err != nil {
 		return cluster, errors.Wrapf(err, "failed to update cluster %s status to %t", clusterID, active)
 	}
 
 	return cluster, nil
 }
+
+// ExtendCluster sends a POST request to the clusters/id endpoint of the Pharos
+// API that changes when the cluster expires, and returns the updated Cluster.
+// Unless version is 0, the cluster is only updated if its resource version
+// still matches, and an error satisfying IsPreconditionFailed is returned
+// otherwise.
+func (c *Client) ExtendCluster(clusterID string, expiresAt time.Time, version int64) (model.Cluster, error) {
+	var cluster model.Cluster
+	update := &struct {
+		ExpiresAt time.Time `json:"expires_at"`
+	}{ExpiresAt: expiresAt}
+
+	err := c.sendWithHeaders(http.MethodPost, fmt.Sprintf("v1/clusters/%s", clusterID), nil, ifMatch(version), update, &cluster)
+	if err != nil {
+		return cluster, errors.Wrapf(err, "failed to extend cluster %s", clusterID)
+	}
+
+	return cluster, nil
+}
+
+// ifMatch returns the If-Match header for a change made against the given
+// resource version of a cluster, or no headers if version is 0.
+func ifMatch(version int64) map[string]string {
+	if version == 0 {
+		return nil
+	}
+	return map[string]string{"If-Match": fmt.Sprintf(`"%d"`, version)}
+}
+
+// Discover sends a POST request to the discover endpoint of the Pharos API and
+// returns the changes made by cluster discovery, or the changes that would be
+// made if dryRun is set.
+func (c *Client) Discover(dryRun bool) (model.DiscoveryResult, error) {
+	var result model.DiscoveryResult
+	params := &struct {
+		DryRun bool `json:"dry_run"`
+	}{DryRun: dryRun}
+
+	err := c.send(http.MethodPost, "v1/discover", nil, params, &result)
+	if err != nil {
+		return result, errors.Wrap(err, "failed to discover clusters")
+	}
+
+	return result, nil
+}
+
+// Batch sends a POST request to the clusters/batch endpoint of the Pharos API,
+// which makes the given operations in a single transaction, and returns the
+// result of each operation. If an operation fails, none of them are applied,
+// and the error returned is caused by the error of the failed operation.
+func (c *Client) Batch(operations []model.BatchOperation) (model.BatchResult, error) {
+	var result model.BatchResult
+	params := &struct {
+		Operations []model.BatchOperation `json:"operations"`
+	}{Operations: operations}
+
+	err := c.send(http.MethodPost, "v1/clusters/batch", nil, params, &result)
+	if err != nil {
+		return result, errors.Wrap(err, "failed to apply batch")
+	}
+	if !result.Applied {
+		if n := len(result.Results); n > 0 && result.Results[n-1].Error != nil {
+			failed := result.Results[n-1]
+			return result, errors.Wrapf(failed.Error, "failed to apply batch, %s %s failed", failed.Action, failed.ID)
+		}
+		return result, errors.New("failed to apply batch")
+	}
+
+	return result, nil
+}
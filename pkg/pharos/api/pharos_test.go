@@ -1,12 +1,16 @@
 package api
 
 import (
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/lob/pharos/internal/test"
 	"github.com/lob/pharos/pkg/pharos/config"
+	"github.com/lob/pharos/pkg/util/model"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -21,7 +25,9 @@ func TestCreateCluster(t *testing.T) {
 		"active":                 true
 	}`)
 
+	var key, method, path string
 	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		key, method, path = r.Header.Get("Idempotency-Key"), r.Method, r.URL.Path
 		_, err := rw.Write(testResponse)
 		require.NoError(t, err)
 	}))
@@ -37,15 +43,32 @@ func TestCreateCluster(t *testing.T) {
 
 	t.Run("creates cluster successfully", func(tt *testing.T) {
 		c := NewClient(&config.Config{BaseURL: srv.URL}, tokenGenerator)
-		cluster, err := c.CreateCluster(newCluster)
+		cluster, err := c.CreateCluster(newCluster, "")
 		assert.NoError(tt, err)
 		assert.Equal(tt, "production-pikachu", cluster.ID)
 		assert.Equal(tt, false, cluster.Deleted)
+		assert.Equal(tt, "", key)
+	})
+
+	t.Run("sends the idempotency key", func(tt *testing.T) {
+		c := NewClient(&config.Config{BaseURL: srv.URL}, tokenGenerator)
+		_, err := c.CreateCluster(newCluster, "terraform-production-pikachu")
+		assert.NoError(tt, err)
+		assert.Equal(tt, "terraform-production-pikachu", key)
+	})
+
+	t.Run("upserts cluster successfully", func(tt *testing.T) {
+		c := NewClient(&config.Config{BaseURL: srv.URL}, tokenGenerator)
+		cluster, err := c.UpsertCluster(newCluster, 0)
+		assert.NoError(tt, err)
+		assert.Equal(tt, "production-pikachu", cluster.ID)
+		assert.Equal(tt, http.MethodPut, method)
+		assert.Equal(tt, "/v1/clusters/production-pikachu", path)
 	})
 
 	t.Run("fails to create cluster using a bad client", func(tt *testing.T) {
 		c := NewClient(&config.Config{BaseURL: ""}, tokenGenerator)
-		cluster, err := c.CreateCluster(newCluster)
+		cluster, err := c.CreateCluster(newCluster, "")
 		assert.Error(tt, err)
 		assert.Equal(tt, "", cluster.ID)
 	})
@@ -70,7 +93,7 @@ func TestDeleteCluster(t *testing.T) {
 
 	t.Run("deletes cluster by ID successfully", func(tt *testing.T) {
 		c := NewClient(&config.Config{BaseURL: srv.URL}, tokenGenerator)
-		cluster, err := c.DeleteCluster("production-pikachu")
+		cluster, err := c.DeleteCluster("production-pikachu", 0)
 		assert.NoError(tt, err)
 		assert.Equal(tt, "production-pikachu", cluster.ID)
 		assert.Equal(tt, true, cluster.Deleted)
@@ -78,7 +101,7 @@ func TestDeleteCluster(t *testing.T) {
 
 	t.Run("fails to delete cluster using a bad client", func(tt *testing.T) {
 		c := NewClient(&config.Config{BaseURL: ""}, tokenGenerator)
-		cluster, err := c.DeleteCluster("production-pikachu")
+		cluster, err := c.DeleteCluster("production-pikachu", 0)
 		assert.Error(tt, err)
 		assert.Equal(tt, "", cluster.ID)
 	})
@@ -127,6 +150,44 @@ func TestListClusters(t *testing.T) {
 	})
 }
 
+func TestWatchClusters(t *testing.T) {
+	testResponse := []byte(`[
+		{
+			"id": "production-6906ce",
+			"environment": "production",
+			"cluster_authority_data": "LS0tLS1CRUdJTiBDR...",
+			"server_url": "https://prod.elb.us-west-2.amazonaws.com:6443",
+			"object": "cluster",
+			"active": true,
+			"resource_version": 43
+		}
+	]`)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "since=42&timeout=30s&watch=true", r.URL.RawQuery)
+		_, err := rw.Write(testResponse)
+		require.NoError(t, err)
+	}))
+	defer srv.Close()
+	tokenGenerator := test.NewGenerator()
+
+	t.Run("watches clusters successfully", func(tt *testing.T) {
+		c := NewClient(&config.Config{BaseURL: srv.URL}, tokenGenerator)
+		clusters, err := c.WatchClusters(42, 30*time.Second)
+		assert.NoError(tt, err)
+
+		require.Len(tt, clusters, 1)
+		assert.Equal(tt, int64(43), clusters[0].ResourceVersion)
+	})
+
+	t.Run("fails to watch clusters using a bad client", func(tt *testing.T) {
+		c := NewClient(&config.Config{BaseURL: ""}, tokenGenerator)
+		clusters, err := c.WatchClusters(42, 30*time.Second)
+		assert.Error(tt, err)
+		assert.Nil(tt, clusters)
+	})
+}
+
 func TestGetCluster(t *testing.T) {
 	testResponse := []byte(`{
 		"id": "production-6906ce",
@@ -159,6 +220,48 @@ func TestGetCluster(t *testing.T) {
 	})
 }
 
+func TestResolveCluster(t *testing.T) {
+	testResponse := []byte(`{
+		"id": "production-6906ce",
+		"environment": "production",
+		"cluster_authority_data": "LS0tLS1CRUdJTiBDR...",
+		"server_url": "https://prod.elb.us-west-2.amazonaws.com:6443",
+		"object": "cluster",
+		"active": true
+	}`)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/resolve/production", "/v1/resolve/production-6906ce":
+			_, err := rw.Write(testResponse)
+			require.NoError(t, err)
+		default:
+			rw.WriteHeader(http.StatusConflict)
+			_, err := rw.Write([]byte(`{"error":{"message":"no active cluster found for environment sandbox, candidates: sandbox-111111, sandbox-222222","status_code":409}}`))
+			require.NoError(t, err)
+		}
+	}))
+	defer srv.Close()
+	tokenGenerator := test.NewGenerator()
+
+	t.Run("resolves cluster by ID or environment successfully", func(tt *testing.T) {
+		c := NewClient(&config.Config{BaseURL: srv.URL}, tokenGenerator)
+		for _, name := range []string{"production", "production-6906ce"} {
+			cluster, err := c.ResolveCluster(name)
+			assert.NoError(tt, err)
+			assert.Equal(tt, "production-6906ce", cluster.ID)
+		}
+	})
+
+	t.Run("fails to resolve an ambiguous name", func(tt *testing.T) {
+		c := NewClient(&config.Config{BaseURL: srv.URL}, tokenGenerator)
+		_, err := c.ResolveCluster("sandbox")
+		assert.Error(tt, err)
+		assert.Contains(tt, err.Error(), "failed to resolve cluster sandbox")
+		assert.Contains(tt, err.Error(), "candidates: sandbox-111111, sandbox-222222")
+	})
+}
+
 func TestUpdateCluster(t *testing.T) {
 	testResponse := []byte(`{
 		"id":                     "production-pikachu",
@@ -178,7 +281,7 @@ func TestUpdateCluster(t *testing.T) {
 
 	t.Run("updates cluster by ID successfully", func(tt *testing.T) {
 		c := NewClient(&config.Config{BaseURL: srv.URL}, tokenGenerator)
-		cluster, err := c.UpdateCluster("production-pikachu", true)
+		cluster, err := c.UpdateCluster("production-pikachu", true, 0)
 		assert.NoError(tt, err)
 		assert.Equal(tt, "production-pikachu", cluster.ID)
 		assert.Equal(tt, true, cluster.Active)
@@ -186,8 +289,150 @@ func TestUpdateCluster(t *testing.T) {
 
 	t.Run("fails to create cluster using a bad client", func(tt *testing.T) {
 		c := NewClient(&config.Config{BaseURL: ""}, tokenGenerator)
-		cluster, err := c.UpdateCluster("production-pikachu", true)
+		cluster, err := c.UpdateCluster("production-pikachu", true, 0)
 		assert.Error(tt, err)
 		assert.Equal(tt, "", cluster.ID)
 	})
+
+	t.Run("sends the resource version in the If-Match header", func(tt *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("If-Match") != `"7"` {
+				rw.WriteHeader(http.StatusPreconditionFailed)
+				_, err := rw.Write([]byte(`{"error":{"message":"cluster has been modified","status_code":412}}`))
+				require.NoError(t, err)
+				return
+			}
+			_, err := rw.Write(testResponse)
+			require.NoError(t, err)
+		}))
+		defer srv.Close()
+
+		c := NewClient(&config.Config{BaseURL: srv.URL}, tokenGenerator)
+		cluster, err := c.UpdateCluster("production-pikachu", true, 7)
+		assert.NoError(tt, err)
+		assert.Equal(tt, "production-pikachu", cluster.ID)
+
+		_, err = c.UpdateCluster("production-pikachu", true, 6)
+		assert.Error(tt, err)
+		assert.True(tt, IsPreconditionFailed(err))
+		assert.Contains(tt, err.Error(), "cluster has been modified (412)")
+	})
+}
+
+func TestExtendCluster(t *testing.T) {
+	tokenGenerator := test.NewGenerator()
+
+	t.Run("sends the new expiry of the cluster", func(tt *testing.T) {
+		expiry := time.Date(2019, 9, 12, 10, 0, 0, 0, time.UTC)
+		srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			assert.Equal(tt, http.MethodPost, r.Method)
+			assert.Equal(tt, "/v1/clusters/preview-111111", r.URL.Path)
+			assert.Equal(tt, `"3"`, r.Header.Get("If-Match"))
+			body, err := ioutil.ReadAll(r.Body)
+			require.NoError(tt, err)
+			assert.JSONEq(tt, `{"expires_at": "2019-09-12T10:00:00Z"}`, string(body))
+
+			_, err = rw.Write([]byte(`{"id": "preview-111111", "expires_at": "2019-09-12T10:00:00Z"}`))
+			require.NoError(tt, err)
+		}))
+		defer srv.Close()
+
+		c := NewClient(&config.Config{BaseURL: srv.URL}, tokenGenerator)
+		cluster, err := c.ExtendCluster("preview-111111", expiry, 3)
+		require.NoError(tt, err)
+		require.NotNil(tt, cluster.ExpiresAt)
+		assert.True(tt, expiry.Equal(*cluster.ExpiresAt))
+	})
+
+	t.Run("fails to extend a cluster using a bad client", func(tt *testing.T) {
+		c := NewClient(&config.Config{BaseURL: ""}, tokenGenerator)
+		_, err := c.ExtendCluster("preview-111111", time.Now(), 0)
+		assert.Error(tt, err)
+	})
+}
+
+func TestDiscover(t *testing.T) {
+	testResponse := []byte(`{
+		"dry_run":   true,
+		"created":   [{"id": "sandbox-111111", "environment": "sandbox", "source": "eks"}],
+		"updated":   [],
+		"unchanged": [],
+		"missing":   [{"id": "staging-333333", "environment": "staging", "source": "eks", "missing": true}]
+	}`)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/discover", r.URL.Path)
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"dry_run": true}`, string(body))
+
+		_, err = rw.Write(testResponse)
+		require.NoError(t, err)
+	}))
+	defer srv.Close()
+	tokenGenerator := test.NewGenerator()
+
+	t.Run("discovers clusters successfully", func(tt *testing.T) {
+		c := NewClient(&config.Config{BaseURL: srv.URL}, tokenGenerator)
+		result, err := c.Discover(true)
+		assert.NoError(tt, err)
+		assert.True(tt, result.DryRun)
+		require.Len(tt, result.Created, 1)
+		assert.Equal(tt, "sandbox-111111", result.Created[0].ID)
+		require.Len(tt, result.Missing, 1)
+		assert.True(tt, result.Missing[0].Missing)
+	})
+
+	t.Run("fails to discover clusters using a bad client", func(tt *testing.T) {
+		c := NewClient(&config.Config{BaseURL: ""}, tokenGenerator)
+		_, err := c.Discover(true)
+		assert.Error(tt, err)
+	})
+}
+
+func TestBatch(t *testing.T) {
+	tokenGenerator := test.NewGenerator()
+
+	t.Run("applies a batch successfully", func(tt *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			assert.Equal(tt, "/v1/clusters/batch", r.URL.Path)
+			body, err := ioutil.ReadAll(r.Body)
+			require.NoError(tt, err)
+			assert.JSONEq(tt, `{"operations": [{"action": "delete", "id": "sandbox-111111", "version": 4}]}`, string(body))
+
+			_, err = rw.Write([]byte(`{"applied": true, "results": [{"action": "delete", "id": "sandbox-111111", "status_code": 200, "cluster": {"id": "sandbox-111111", "deleted": true}}]}`))
+			require.NoError(tt, err)
+		}))
+		defer srv.Close()
+
+		c := NewClient(&config.Config{BaseURL: srv.URL}, tokenGenerator)
+		result, err := c.Batch([]model.BatchOperation{{Action: model.BatchDelete, ID: "sandbox-111111", Version: 4}})
+		require.NoError(tt, err)
+		assert.True(tt, result.Applied)
+		require.Len(tt, result.Results, 1)
+		assert.True(tt, result.Results[0].Cluster.Deleted)
+	})
+
+	t.Run("errors with the failed operation when a batch isn't applied", func(tt *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			_, err := rw.Write([]byte(`{"applied": false, "results": [
+				{"action": "delete", "id": "sandbox-111111", "status_code": 200},
+				{"action": "delete", "id": "sandbox-222222", "status_code": 404, "error": {"code": "cluster_not_found", "message": "cluster not found", "status_code": 404}}
+			]}`))
+			require.NoError(tt, err)
+		}))
+		defer srv.Close()
+
+		c := NewClient(&config.Config{BaseURL: srv.URL}, tokenGenerator)
+		result, err := c.Batch([]model.BatchOperation{
+			{Action: model.BatchDelete, ID: "sandbox-111111"},
+			{Action: model.BatchDelete, ID: "sandbox-222222"},
+		})
+		require.Error(tt, err)
+		assert.False(tt, result.Applied)
+		assert.Contains(tt, err.Error(), "delete sandbox-222222 failed")
+		var apiErr *model.Error
+		require.True(tt, errors.As(err, &apiErr))
+		assert.Equal(tt, model.CodeClusterNotFound, apiErr.Code)
+	})
 }
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/lob/pharos/pkg/util/model"
+	"github.com/pkg/errors"
+)
+
+// Webhook describes a new webhook to be created in Pharos. A secret is
+// generated by Pharos if none is given.
+type Webhook struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	Secret string   `json:"secret,omitempty"`
+}
+
+// ListWebhooks sends a GET request to the webhooks endpoint of the Pharos API
+// and returns a list of Webhooks, without their secrets.
+func (c *Client) ListWebhooks() ([]model.Webhook, error) {
+	var webhooks []model.Webhook
+	err := c.send(http.MethodGet, "v1/webhooks", nil, nil, &webhooks)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list webhooks")
+	}
+
+	return webhooks, nil
+}
+
+// CreateWebhook sends a POST request to the webhooks endpoint of the Pharos
+// API and returns the created Webhook, including its secret.
+func (c *Client) CreateWebhook(newWebhook Webhook) (model.Webhook, error) {
+	var webhook model.Webhook
+	err := c.send(http.MethodPost, "v1/webhooks", nil, newWebhook, &webhook)
+	if err != nil {
+		return webhook, errors.Wrap(err, "failed to create webhook")
+	}
+
+	return webhook, nil
+}
+
+// DeleteWebhook sends a DELETE request to the webhooks/id endpoint of the
+// Pharos API and returns the deleted Webhook.
+func (c *Client) DeleteWebhook(webhookID string) (model.Webhook, error) {
+	var webhook model.Webhook
+	err := c.send(http.MethodDelete, fmt.Sprintf("v1/webhooks/%s", webhookID), nil, nil, &webhook)
+	if err != nil {
+		return webhook, errors.Wrapf(err, "failed to delete webhook %s", webhookID)
+	}
+
+	return webhook, nil
+}
+
+// ListDeadLetters sends a GET request to the webhooks/id/dead_letters endpoint
+// of the Pharos API and returns the events that could not be delivered to a
+// webhook.
+func (c *Client) ListDeadLetters(webhookID string) ([]model.WebhookDeadLetter, error) {
+	var deadLetters []model.WebhookDeadLetter
+	err := c.send(http.MethodGet, fmt.Sprintf("v1/webhooks/%s/dead_letters", webhookID), nil, nil, &deadLetters)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list dead letters of webhook %s", webhookID)
+	}
+
+	return deadLetters, nil
+}
@@ -0,0 +1,95 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lob/pharos/internal/test"
+	"github.com/lob/pharos/pkg/pharos/config"
+	"github.com/lob/pharos/pkg/util/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhooks(t *testing.T) {
+	webhookResponse := []byte(`{
+		"id": 1,
+		"url": "https://hooks.example.com/pharos",
+		"events": ["cluster.created", "cluster.activated"],
+		"secret": "secret"
+	}`)
+	deadLettersResponse := []byte(`[{
+		"id": 7,
+		"webhook_id": 1,
+		"event": "cluster.created",
+		"payload": "{}",
+		"attempts": 10,
+		"last_error": "webhook responded with 500 Internal Server Error"
+	}]`)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		var response []byte
+		switch r.Method + " " + r.URL.Path {
+		case "GET /v1/webhooks":
+			response = []byte("[" + string(webhookResponse) + "]")
+		case "POST /v1/webhooks":
+			var webhook Webhook
+			err := json.NewDecoder(r.Body).Decode(&webhook)
+			require.NoError(t, err)
+			assert.Equal(t, "https://hooks.example.com/pharos", webhook.URL)
+			response = webhookResponse
+		case "DELETE /v1/webhooks/1":
+			response = webhookResponse
+		case "GET /v1/webhooks/1/dead_letters":
+			response = deadLettersResponse
+		default:
+			rw.WriteHeader(http.StatusNotFound)
+			response = []byte(`{"error":{"message":"webhook not found","status_code":404}}`)
+		}
+		_, err := rw.Write(response)
+		require.NoError(t, err)
+	}))
+	defer srv.Close()
+	tokenGenerator := test.NewGenerator()
+	c := NewClient(&config.Config{BaseURL: srv.URL}, tokenGenerator)
+
+	t.Run("lists webhooks successfully", func(tt *testing.T) {
+		webhooks, err := c.ListWebhooks()
+		assert.NoError(tt, err)
+		require.Len(tt, webhooks, 1)
+		assert.Equal(tt, []string{model.EventClusterCreated, model.EventClusterActivated}, webhooks[0].Events)
+	})
+
+	t.Run("creates webhook successfully", func(tt *testing.T) {
+		webhook, err := c.CreateWebhook(Webhook{URL: "https://hooks.example.com/pharos", Events: []string{model.EventClusterCreated}})
+		assert.NoError(tt, err)
+		assert.Equal(tt, "secret", webhook.Secret)
+	})
+
+	t.Run("deletes webhook successfully", func(tt *testing.T) {
+		webhook, err := c.DeleteWebhook("1")
+		assert.NoError(tt, err)
+		assert.Equal(tt, int64(1), webhook.ID)
+
+		_, err = c.DeleteWebhook("2")
+		assert.Error(tt, err)
+		assert.Contains(tt, err.Error(), "failed to delete webhook 2")
+		assert.Contains(tt, err.Error(), "webhook not found")
+	})
+
+	t.Run("lists dead letters successfully", func(tt *testing.T) {
+		deadLetters, err := c.ListDeadLetters("1")
+		assert.NoError(tt, err)
+		require.Len(tt, deadLetters, 1)
+		assert.Equal(tt, 10, deadLetters[0].Attempts)
+	})
+
+	t.Run("fails to list webhooks using a bad client", func(tt *testing.T) {
+		c := NewClient(&config.Config{BaseURL: ""}, tokenGenerator)
+		webhooks, err := c.ListWebhooks()
+		assert.Error(tt, err)
+		assert.Nil(tt, webhooks)
+	})
+}
@@ -0,0 +1,281 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/fatih/color"
+	"github.com/lob/pharos/pkg/pharos/api"
+	"github.com/lob/pharos/pkg/util/model"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+)
+
+// Manifest describes the clusters that should be registered in Pharos, and
+// the active cluster of each environment, so that they can be kept in git and
+// reconciled with ApplyPlan.
+type Manifest struct {
+	Clusters     []ManifestCluster              `json:"clusters"`
+	Environments map[string]ManifestEnvironment `json:"environments,omitempty"`
+}
+
+// ManifestCluster describes a cluster in a Manifest. Its environment is
+// inferred from its ID if it isn't given.
+type ManifestCluster struct {
+	ID                   string `json:"id"`
+	Environment          string `json:"environment,omitempty"`
+	ServerURL            string `json:"server_url"`
+	ClusterAuthorityData string `json:"cluster_authority_data"`
+}
+
+// ManifestEnvironment describes an environment in a Manifest.
+type ManifestEnvironment struct {
+	Active string `json:"active"`
+}
+
+// Actions of the changes in a plan.
+const (
+	ActionCreate   = "create"
+	ActionUpdate   = "update"
+	ActionDelete   = "delete"
+	ActionActivate = "activate"
+)
+
+// Change is a change to a single cluster in a plan. Version is the resource
+// version of the cluster the change was planned against, or 0 for clusters
+// that don't exist yet.
+type Change struct {
+	Action  string
+	Cluster api.Cluster
+	Version int64
+	Details string
+}
+
+// LoadManifest reads a manifest from a YAML or JSON file and validates it.
+func LoadManifest(file string) (Manifest, error) {
+	var manifest Manifest
+
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return manifest, errors.Wrap(err, "unable to read manifest")
+	}
+	if err := yaml.UnmarshalStrict(b, &manifest); err != nil {
+		return manifest, errors.Wrapf(err, "unable to parse manifest %s", file)
+	}
+
+	environments := make(map[string]string, len(manifest.Clusters))
+	for i := range manifest.Clusters {
+		cluster := &manifest.Clusters[i]
+		if cluster.ID == "" {
+			return manifest, fmt.Errorf("cluster %d in manifest has no id", i+1)
+		}
+		if _, ok := environments[cluster.ID]; ok {
+			return manifest, fmt.Errorf("cluster %s appears more than once in manifest", cluster.ID)
+		}
+		if cluster.ServerURL == "" || cluster.ClusterAuthorityData == "" {
+			return manifest, fmt.Errorf("cluster %s in manifest needs a server_url and cluster_authority_data", cluster.ID)
+		}
+		if cluster.Environment == "" {
+			cluster.Environment = model.EnvironmentFromID(cluster.ID)
+		}
+		environments[cluster.ID] = cluster.Environment
+	}
+
+	for name, environment := range manifest.Environments {
+		if environment.Active == "" {
+			continue
+		}
+		if environments[environment.Active] != name {
+			return manifest, fmt.Errorf("active cluster %s of environment %s is not a cluster of that environment in the manifest", environment.Active, name)
+		}
+	}
+
+	return manifest, nil
+}
+
+// PlanManifest compares a manifest with the clusters registered in Pharos, and
+// returns the changes that would make Pharos match it: clusters to create or
+// update, clusters to delete, and clusters to activate, in the order they are
+// applied. Clusters are only deleted from environments that the manifest
+// describes, so that a manifest can manage some environments and leave the
+// others alone. Environments are only activated if the manifest names their
+// active cluster.
+func PlanManifest(manifest Manifest, client *api.Client) ([]Change, error) {
+	clusters, err := client.ListClusters(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	existing := make(map[string]model.Cluster, len(clusters))
+	active := make(map[string][]string)
+	for _, cluster := range clusters {
+		existing[cluster.ID] = cluster
+		if cluster.Active {
+			active[cluster.Environment] = append(active[cluster.Environment], cluster.ID)
+		}
+	}
+
+	var changes []Change
+	listed := make(map[string]bool, len(manifest.Clusters))
+	managed := make(map[string]bool)
+	for _, c := range manifest.Clusters {
+		listed[c.ID] = true
+		managed[c.Environment] = true
+
+		cluster := api.Cluster{
+			ID:                   c.ID,
+			Environment:          c.Environment,
+			ServerURL:            c.ServerURL,
+			ClusterAuthorityData: c.ClusterAuthorityData,
+		}
+
+		current, ok := existing[c.ID]
+		if !ok {
+			changes = append(changes, Change{Action: ActionCreate, Cluster: cluster, Details: c.ServerURL})
+			continue
+		}
+
+		var fields []string
+		if current.Environment != c.Environment {
+			fields = append(fields, "environment")
+		}
+		if current.ServerURL != c.ServerURL {
+			fields = append(fields, "server_url")
+		}
+		if current.ClusterAuthorityData != c.ClusterAuthorityData {
+			fields = append(fields, "cluster_authority_data")
+		}
+		if len(fields) > 0 {
+			changes = append(changes, Change{
+				Action:  ActionUpdate,
+				Cluster: cluster,
+				Version: current.ResourceVersion,
+				Details: "changes " + strings.Join(fields, ", "),
+			})
+		}
+	}
+
+	names := make([]string, 0, len(manifest.Environments))
+	for name := range manifest.Environments {
+		managed[name] = true
+		if manifest.Environments[name].Active != "" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	ids := make([]string, 0, len(clusters))
+	for id := range existing {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		current := existing[id]
+		if listed[id] || !managed[current.Environment] {
+			continue
+		}
+		changes = append(changes, Change{
+			Action:  ActionDelete,
+			Cluster: api.Cluster{ID: id, Environment: current.Environment, ServerURL: current.ServerURL},
+			Version: current.ResourceVersion,
+			Details: "not in manifest",
+		})
+	}
+
+	// Clusters are activated last, once they exist and the clusters they
+	// replace have been deleted.
+	for _, name := range names {
+		id := manifest.Environments[name].Active
+		if len(active[name]) == 1 && active[name][0] == id {
+			continue
+		}
+
+		change := Change{Action: ActionActivate, Cluster: api.Cluster{ID: id, Environment: name}}
+		if current, ok := existing[id]; ok {
+			change.Version = current.ResourceVersion
+		}
+		if len(active[name]) > 0 {
+			sort.Strings(active[name])
+			change.Details = "replaces " + strings.Join(active[name], ", ")
+		}
+		changes = append(changes, change)
+	}
+
+	return changes, nil
+}
+
+// FormatPlan returns a formatted summary of the changes in a plan.
+func FormatPlan(changes []Change) (string, error) {
+	buf := new(bytes.Buffer)
+	if len(changes) == 0 {
+		fmt.Fprintf(buf, "%s PHAROS MATCHES THE MANIFEST\n", color.GreenString("NO CHANGES:"))
+		return buf.String(), nil
+	}
+
+	w := tabwriter.NewWriter(buf, 0, 0, 3, ' ', 0)
+	cyan := color.New(color.FgCyan)
+
+	// Add spaces to prevent ANSI escape codes from breaking the tabwriter formatting.
+	_, err := cyan.Fprint(w, "CHANGE\t     CLUSTER_ID\t     ENVIRONMENT\t     DETAILS")
+	if err != nil {
+		return "", err
+	}
+
+	counts := make(map[string]int)
+	for _, change := range changes {
+		counts[change.Action]++
+		fmt.Fprintf(w, "\n%s\t%s\t%s\t%s", change.Action, change.Cluster.ID, change.Cluster.Environment, change.Details)
+	}
+
+	fmt.Fprintln(w, "")
+	if err := w.Flush(); err != nil {
+		return "", err
+	}
+
+	fmt.Fprintf(buf, "%s %d TO CREATE, %d TO UPDATE, %d TO DELETE AND %d TO ACTIVATE\n", color.CyanString("PLAN:"),
+		counts[ActionCreate], counts[ActionUpdate], counts[ActionDelete], counts[ActionActivate])
+	return buf.String(), nil
+}
+
+// ApplyPlan makes the changes in a plan, in order. Changes to existing
+// clusters are only made if the clusters haven't been modified since the plan
+// was made, so that an outdated plan fails instead of undoing other changes.
+func ApplyPlan(changes []Change, client *api.Client) error {
+	// Track the versions of the clusters changed while applying, so that they
+	// can be activated afterwards.
+	versions := make(map[string]int64)
+
+	for _, change := range changes {
+		var cluster model.Cluster
+		var err error
+
+		switch change.Action {
+		case ActionCreate, ActionUpdate:
+			cluster, err = client.UpsertCluster(change.Cluster, change.Version)
+		case ActionDelete:
+			cluster, err = client.DeleteCluster(change.Cluster.ID, change.Version)
+		case ActionActivate:
+			version, ok := versions[change.Cluster.ID]
+			if !ok {
+				version = change.Version
+			}
+			cluster, err = client.UpdateCluster(change.Cluster.ID, true, version)
+		default:
+			err = fmt.Errorf("unknown change %s", change.Action)
+		}
+		if api.IsPreconditionFailed(err) {
+			return errors.Errorf("cluster %s changed since the plan was made, run the command again", change.Cluster.ID)
+		}
+		if err != nil {
+			return err
+		}
+
+		versions[cluster.ID] = cluster.ResourceVersion
+	}
+
+	return nil
+}
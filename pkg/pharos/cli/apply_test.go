@@ -0,0 +1,135 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/lob/pharos/internal/test"
+	"github.com/lob/pharos/pkg/pharos/api"
+	configpkg "github.com/lob/pharos/pkg/pharos/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	manifestFile        = "../testdata/manifest"
+	invalidManifestFile = "../testdata/invalidManifest"
+)
+
+// manifestClusters are the clusters registered in Pharos when testing
+// manifests.
+var manifestClusters = []byte(`[
+	{"id": "production-000000", "environment": "production", "server_url": "https://production-000000.example.com", "cluster_authority_data": "YWJj", "resource_version": 2},
+	{"id": "production-111111", "environment": "production", "server_url": "https://production-111111.example.com", "cluster_authority_data": "YWJj", "active": true, "resource_version": 3},
+	{"id": "sandbox-333333", "environment": "sandbox", "server_url": "https://sandbox-old.example.com", "cluster_authority_data": "YWJj", "resource_version": 4},
+	{"id": "staging-444444", "environment": "staging", "server_url": "https://staging-444444.example.com", "cluster_authority_data": "YWJj", "resource_version": 5}
+]`)
+
+func TestLoadManifest(t *testing.T) {
+	t.Run("loads and validates a manifest", func(tt *testing.T) {
+		manifest, err := LoadManifest(manifestFile)
+		require.NoError(tt, err)
+		require.Len(tt, manifest.Clusters, 3)
+		// The environment is inferred from the ID if it isn't given.
+		assert.Equal(tt, "production", manifest.Clusters[0].Environment)
+		assert.Equal(tt, "production-222222", manifest.Environments["production"].Active)
+	})
+
+	t.Run("errors when an active cluster isn't in its environment", func(tt *testing.T) {
+		_, err := LoadManifest(invalidManifestFile)
+		assert.Error(tt, err)
+		assert.Contains(tt, err.Error(), "active cluster sandbox-333333 of environment production")
+	})
+
+	t.Run("errors with an unknown field", func(tt *testing.T) {
+		_, err := LoadManifest("../testdata/config")
+		assert.Error(tt, err)
+		assert.Contains(tt, err.Error(), "unable to parse manifest")
+	})
+}
+
+func TestPlanAndApplyManifest(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		requests []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if r.Method == http.MethodGet {
+			_, err := rw.Write(manifestClusters)
+			require.NoError(t, err)
+			return
+		}
+
+		requests = append(requests, fmt.Sprintf("%s %s %s", r.Method, r.URL.Path, r.Header.Get("If-Match")))
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		cluster := map[string]interface{}{}
+		if len(body) > 0 {
+			require.NoError(t, json.Unmarshal(body, &cluster))
+		}
+		cluster["id"] = strings.TrimPrefix(r.URL.Path, "/v1/clusters/")
+		cluster["resource_version"] = 10
+		require.NoError(t, json.NewEncoder(rw).Encode(cluster))
+	}))
+	defer srv.Close()
+	tokenGenerator := test.NewGenerator()
+	client := api.NewClient(&configpkg.Config{BaseURL: srv.URL}, tokenGenerator)
+
+	manifest, err := LoadManifest(manifestFile)
+	require.NoError(t, err)
+
+	t.Run("plans the changes that make Pharos match a manifest", func(tt *testing.T) {
+		changes, err := PlanManifest(manifest, client)
+		require.NoError(tt, err)
+
+		summary := make([]string, len(changes))
+		for i, change := range changes {
+			summary[i] = fmt.Sprintf("%s %s %d", change.Action, change.Cluster.ID, change.Version)
+		}
+		assert.Equal(tt, []string{
+			"create production-222222 0",
+			"update sandbox-333333 4",
+			"delete production-000000 2",
+			"activate production-222222 0",
+		}, summary)
+		assert.Equal(tt, "changes server_url", changes[1].Details)
+		assert.Equal(tt, "replaces production-111111", changes[3].Details)
+
+		plan, err := FormatPlan(changes)
+		require.NoError(tt, err)
+		assert.Regexp(tt, `create\s+production-222222\s+production`, plan)
+		assert.Contains(tt, plan, "1 TO CREATE, 1 TO UPDATE, 1 TO DELETE AND 1 TO ACTIVATE")
+	})
+
+	t.Run("applies a plan against the planned versions", func(tt *testing.T) {
+		changes, err := PlanManifest(manifest, client)
+		require.NoError(tt, err)
+
+		err = ApplyPlan(changes, client)
+		require.NoError(tt, err)
+
+		mu.Lock()
+		defer mu.Unlock()
+		assert.Equal(tt, []string{
+			"PUT /v1/clusters/production-222222 ",
+			`PUT /v1/clusters/sandbox-333333 "4"`,
+			`DELETE /v1/clusters/production-000000 "2"`,
+			`POST /v1/clusters/production-222222 "10"`,
+		}, requests)
+	})
+
+	t.Run("reports no changes", func(tt *testing.T) {
+		plan, err := FormatPlan(nil)
+		require.NoError(tt, err)
+		assert.Contains(tt, plan, "PHAROS MATCHES THE MANIFEST")
+	})
+}
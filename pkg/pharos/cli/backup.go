@@ -0,0 +1,241 @@
+package cli
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// DefaultBackupLimit is the number of backups kept for each kubeconfig file
+// unless configured otherwise.
+const DefaultBackupLimit = 10
+
+// backupTimestampFormat sorts lexicographically in chronological order.
+// Backups taken within the same millisecond are told apart by a -<n> suffix.
+const backupTimestampFormat = "20060102T150405.000"
+
+// BackupDir is the directory that kubeconfig backups are written to, and
+// BackupLimit is the number of backups kept for each kubeconfig file.
+var (
+	BackupDir   = filepath.Join(os.Getenv("HOME"), ".kube", "pharos", "backups")
+	BackupLimit = DefaultBackupLimit
+)
+
+// Backup is a copy of a kubeconfig file taken before it was modified.
+type Backup struct {
+	Timestamp string
+	Path      string
+	Time      time.Time
+
+	// seq is the suffix of backups taken in the same millisecond as another.
+	seq int
+}
+
+// writeKubeConfig backs up a kubeconfig file and then atomically overwrites it.
+// Every command that modifies an existing kubeconfig file must write it through
+// here while holding the lock from lockKubeConfig.
+func writeKubeConfig(kubeConfig clientcmdapi.Config, kubeConfigFile string) error {
+	if err := backupKubeConfig(kubeConfigFile); err != nil {
+		return err
+	}
+
+	raw, err := clientcmd.Write(kubeConfig)
+	if err != nil {
+		return errors.Wrap(err, "unable to write kubeconfig file")
+	}
+	return writeFileAtomic(kubeConfigFile, raw)
+}
+
+// backupKubeConfig copies a kubeconfig file into BackupDir and removes its
+// oldest backups beyond BackupLimit. Missing files are not backed up.
+func backupKubeConfig(kubeConfigFile string) error {
+	raw, err := ioutil.ReadFile(kubeConfigFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap(err, "unable to read kubeconfig file for backup")
+	}
+
+	if err := os.MkdirAll(BackupDir, 0700); err != nil {
+		return errors.Wrap(err, "unable to create backup directory")
+	}
+
+	if err := writeBackup(kubeConfigFile, raw, time.Now().UTC()); err != nil {
+		return err
+	}
+
+	backups, err := ListBackups(kubeConfigFile)
+	if err != nil {
+		return err
+	}
+	for i := BackupLimit; i < len(backups); i++ {
+		if err := os.Remove(backups[i].Path); err != nil {
+			return errors.Wrap(err, "unable to remove old kubeconfig backup")
+		}
+	}
+	return nil
+}
+
+// writeBackup writes a backup of a kubeconfig file taken at t. The backup is
+// created exclusively, so that a backup taken in the same millisecond, by this
+// process or another, is never overwritten. Instead the later backup's
+// timestamp is suffixed with the lowest -<n> that is free.
+func writeBackup(kubeConfigFile string, raw []byte, t time.Time) error {
+	timestamp := t.Format(backupTimestampFormat)
+	for seq := 0; ; seq++ {
+		name := timestamp
+		if seq > 0 {
+			name = fmt.Sprintf("%s-%d", timestamp, seq)
+		}
+
+		f, err := os.OpenFile(filepath.Join(BackupDir, backupPrefix(kubeConfigFile)+name), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if os.IsExist(err) {
+			continue
+		}
+		if err != nil {
+			return errors.Wrap(err, "unable to write kubeconfig backup")
+		}
+
+		_, err = f.Write(raw)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(f.Name())
+			return errors.Wrap(err, "unable to write kubeconfig backup")
+		}
+		return nil
+	}
+}
+
+// parseBackupTimestamp returns the time a backup was taken at and its suffix.
+func parseBackupTimestamp(timestamp string) (time.Time, int, error) {
+	seq := 0
+	if i := strings.Index(timestamp, "-"); i >= 0 {
+		n, err := strconv.Atoi(timestamp[i+1:])
+		if err != nil || n < 1 {
+			return time.Time{}, 0, fmt.Errorf("invalid backup timestamp %q", timestamp)
+		}
+		timestamp, seq = timestamp[:i], n
+	}
+
+	t, err := time.Parse(backupTimestampFormat, timestamp)
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+	return t, seq, nil
+}
+
+// backupPrefix returns the prefix of the names of a kubeconfig file's backups.
+// Files in different directories often share a name, such as config, so the
+// name is followed by a hash of the file's absolute path, with symlinks
+// resolved so that every link to a file shares its backups.
+func backupPrefix(kubeConfigFile string) string {
+	path, err := filepath.Abs(kubeConfigFile)
+	if err != nil {
+		path = kubeConfigFile
+	}
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+
+	sum := sha256.Sum256([]byte(path))
+	return fmt.Sprintf("%s-%s-", filepath.Base(path), hex.EncodeToString(sum[:])[:16])
+}
+
+// ListBackups returns the backups of a kubeconfig file, newest first.
+func ListBackups(kubeConfigFile string) ([]Backup, error) {
+	prefix := backupPrefix(kubeConfigFile)
+
+	files, err := ioutil.ReadDir(BackupDir)
+	if os.IsNotExist(err) {
+		return []Backup{}, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read backup directory")
+	}
+
+	backups := make([]Backup, 0)
+	for _, f := range files {
+		if f.IsDir() || !strings.HasPrefix(f.Name(), prefix) {
+			continue
+		}
+
+		timestamp := strings.TrimPrefix(f.Name(), prefix)
+		t, seq, err := parseBackupTimestamp(timestamp)
+		if err != nil {
+			continue
+		}
+		backups = append(backups, Backup{timestamp, filepath.Join(BackupDir, f.Name()), t, seq})
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		if !backups[i].Time.Equal(backups[j].Time) {
+			return backups[i].Time.After(backups[j].Time)
+		}
+		return backups[i].seq > backups[j].seq
+	})
+	return backups, nil
+}
+
+// RestoreBackup overwrites a kubeconfig file with the backup taken at the given
+// timestamp, or with the most recent backup if timestamp is empty. The current
+// file is backed up first so that the restore can itself be undone. It returns
+// the backup that was restored.
+func RestoreBackup(kubeConfigFile string, timestamp string) (Backup, error) {
+	backups, err := ListBackups(kubeConfigFile)
+	if err != nil {
+		return Backup{}, err
+	}
+	if len(backups) == 0 {
+		return Backup{}, fmt.Errorf("no backups found for %s", kubeConfigFile)
+	}
+
+	backup := backups[0]
+	if timestamp != "" {
+		found := false
+		for _, b := range backups {
+			if b.Timestamp == timestamp {
+				backup, found = b, true
+				break
+			}
+		}
+		if !found {
+			return Backup{}, fmt.Errorf("no backup of %s found with timestamp %s", kubeConfigFile, timestamp)
+		}
+	}
+
+	raw, err := ioutil.ReadFile(backup.Path)
+	if err != nil {
+		return Backup{}, errors.Wrap(err, "unable to read kubeconfig backup")
+	}
+	if _, err := clientcmd.Load(raw); err != nil {
+		return Backup{}, errors.Wrap(err, "kubeconfig backup is malformed")
+	}
+
+	unlock, err := lockKubeConfig(kubeConfigFile)
+	if err != nil {
+		return Backup{}, err
+	}
+	defer unlock()
+
+	if err := backupKubeConfig(kubeConfigFile); err != nil {
+		return Backup{}, err
+	}
+	if err := writeFileAtomic(kubeConfigFile, raw); err != nil {
+		return Backup{}, errors.Wrap(err, "unable to restore kubeconfig file")
+	}
+	return backup, nil
+}
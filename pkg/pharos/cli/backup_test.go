@@ -0,0 +1,217 @@
+package cli
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lob/pharos/internal/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackups(t *testing.T) {
+	originalBackupDir, originalBackupLimit := BackupDir, BackupLimit
+	defer func() { BackupDir, BackupLimit = originalBackupDir, originalBackupLimit }()
+
+	newBackupDir := func(tt *testing.T) string {
+		dir, err := ioutil.TempDir("", "pharos-backups")
+		require.NoError(tt, err)
+		BackupDir = dir
+		return dir
+	}
+
+	t.Run("backs up a kubeconfig file before writing it", func(tt *testing.T) {
+		dir := newBackupDir(tt)
+		defer os.RemoveAll(dir)
+
+		configFile := test.CopyTestFile(tt, "../testdata", "backup", config)
+		defer os.Remove(configFile)
+
+		err := SwitchCluster(configFile, "sandbox-a61631")
+		require.NoError(tt, err)
+
+		backups, err := ListBackups(configFile)
+		require.NoError(tt, err)
+		require.Len(tt, backups, 1)
+
+		original, err := ioutil.ReadFile(config)
+		require.NoError(tt, err)
+		backup, err := ioutil.ReadFile(backups[0].Path)
+		require.NoError(tt, err)
+		assert.Equal(tt, original, backup)
+	})
+
+	t.Run("does not back up a kubeconfig file that does not exist", func(tt *testing.T) {
+		dir := newBackupDir(tt)
+		defer os.RemoveAll(dir)
+
+		err := backupKubeConfig(nonExistentConfig)
+		assert.NoError(tt, err)
+
+		backups, err := ListBackups(nonExistentConfig)
+		require.NoError(tt, err)
+		assert.Len(tt, backups, 0)
+	})
+
+	t.Run("keeps only the configured number of backups", func(tt *testing.T) {
+		dir := newBackupDir(tt)
+		defer os.RemoveAll(dir)
+		BackupLimit = 2
+
+		configFile := test.CopyTestFile(tt, "../testdata", "backup", config)
+		defer os.Remove(configFile)
+
+		for i := 0; i < 4; i++ {
+			err := backupKubeConfig(configFile)
+			require.NoError(tt, err)
+			time.Sleep(2 * time.Millisecond)
+		}
+
+		backups, err := ListBackups(configFile)
+		require.NoError(tt, err)
+		require.Len(tt, backups, 2)
+		assert.True(tt, backups[0].Time.After(backups[1].Time))
+	})
+
+	t.Run("keeps backups taken in the same millisecond", func(tt *testing.T) {
+		dir := newBackupDir(tt)
+		defer os.RemoveAll(dir)
+
+		configFile := test.CopyTestFile(tt, "../testdata", "backup", config)
+		defer os.Remove(configFile)
+
+		now := time.Date(2019, 8, 1, 12, 30, 0, 0, time.UTC)
+		for i := 0; i < 11; i++ {
+			err := writeBackup(configFile, []byte{byte(i)}, now)
+			require.NoError(tt, err)
+		}
+
+		backups, err := ListBackups(configFile)
+		require.NoError(tt, err)
+		require.Len(tt, backups, 11)
+		assert.Equal(tt, "20190801T123000.000-10", backups[0].Timestamp)
+		assert.Equal(tt, "20190801T123000.000-9", backups[1].Timestamp)
+		assert.Equal(tt, "20190801T123000.000", backups[10].Timestamp)
+		for _, b := range backups {
+			assert.True(tt, now.Equal(b.Time))
+		}
+
+		newest, err := ioutil.ReadFile(backups[0].Path)
+		require.NoError(tt, err)
+		assert.Equal(tt, []byte{10}, newest)
+	})
+
+	t.Run("keeps the backups of files with the same name apart", func(tt *testing.T) {
+		dir := newBackupDir(tt)
+		defer os.RemoveAll(dir)
+		BackupLimit = 1
+
+		first, err := ioutil.TempDir("", "pharos-first")
+		require.NoError(tt, err)
+		defer os.RemoveAll(first)
+		second, err := ioutil.TempDir("", "pharos-second")
+		require.NoError(tt, err)
+		defer os.RemoveAll(second)
+
+		firstFile, secondFile := filepath.Join(first, "config"), filepath.Join(second, "config")
+		require.NoError(tt, ioutil.WriteFile(firstFile, []byte("first"), 0600))
+		require.NoError(tt, ioutil.WriteFile(secondFile, []byte("second"), 0600))
+
+		require.NoError(tt, backupKubeConfig(firstFile))
+		require.NoError(tt, backupKubeConfig(secondFile))
+
+		// Pruning the second file's backups must not remove the first's.
+		firstBackups, err := ListBackups(firstFile)
+		require.NoError(tt, err)
+		require.Len(tt, firstBackups, 1)
+		raw, err := ioutil.ReadFile(firstBackups[0].Path)
+		require.NoError(tt, err)
+		assert.Equal(tt, "first", string(raw))
+
+		secondBackups, err := ListBackups(secondFile)
+		require.NoError(tt, err)
+		require.Len(tt, secondBackups, 1)
+		raw, err = ioutil.ReadFile(secondBackups[0].Path)
+		require.NoError(tt, err)
+		assert.Equal(tt, "second", string(raw))
+
+		// A symlink to a file shares its backups.
+		link := filepath.Join(second, "link")
+		require.NoError(tt, os.Symlink(firstFile, link))
+		linkBackups, err := ListBackups(link)
+		require.NoError(tt, err)
+		assert.Equal(tt, firstBackups, linkBackups)
+	})
+
+	t.Run("restores the most recent backup", func(tt *testing.T) {
+		dir := newBackupDir(tt)
+		defer os.RemoveAll(dir)
+		BackupLimit = DefaultBackupLimit
+
+		configFile := test.CopyTestFile(tt, "../testdata", "backup", config)
+		defer os.Remove(configFile)
+
+		err := SwitchCluster(configFile, "sandbox-a61631")
+		require.NoError(tt, err)
+		current, err := CurrentCluster(configFile)
+		require.NoError(tt, err)
+		require.Equal(tt, "sandbox-a61631", current)
+
+		_, err = RestoreBackup(configFile, "")
+		require.NoError(tt, err)
+
+		current, err = CurrentCluster(configFile)
+		require.NoError(tt, err)
+		assert.Equal(tt, "sandbox", current)
+
+		// The file that was overwritten by the restore is backed up too.
+		backups, err := ListBackups(configFile)
+		require.NoError(tt, err)
+		assert.Len(tt, backups, 2)
+	})
+
+	t.Run("restores a backup by timestamp", func(tt *testing.T) {
+		dir := newBackupDir(tt)
+		defer os.RemoveAll(dir)
+
+		configFile := test.CopyTestFile(tt, "../testdata", "backup", config)
+		defer os.Remove(configFile)
+
+		err := SwitchCluster(configFile, "sandbox-a61631")
+		require.NoError(tt, err)
+		time.Sleep(2 * time.Millisecond)
+		err = SwitchCluster(configFile, "sandbox-111111")
+		require.NoError(tt, err)
+
+		backups, err := ListBackups(configFile)
+		require.NoError(tt, err)
+		require.Len(tt, backups, 2)
+
+		restored, err := RestoreBackup(configFile, backups[1].Timestamp)
+		require.NoError(tt, err)
+		assert.Equal(tt, backups[1].Timestamp, restored.Timestamp)
+
+		current, err := CurrentCluster(configFile)
+		require.NoError(tt, err)
+		assert.Equal(tt, "sandbox", current)
+	})
+
+	t.Run("errors when no backup exists", func(tt *testing.T) {
+		dir := newBackupDir(tt)
+		defer os.RemoveAll(dir)
+
+		configFile := test.CopyTestFile(tt, "../testdata", "backup", config)
+		defer os.Remove(configFile)
+
+		_, err := RestoreBackup(configFile, "")
+		assert.Error(tt, err)
+		assert.Contains(tt, err.Error(), "no backups found")
+
+		_, err = RestoreBackup(configFile, "20190101T000000.000")
+		assert.Error(tt, err)
+		assert.Contains(tt, err.Error(), "no backups found")
+	})
+}
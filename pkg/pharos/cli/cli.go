@@ -4,24 +4,31 @@ import (
 	"bytes"
 	"fmt"
 	"os"
-	"regexp"
 	"strconv"
+	"strings"
 	"text/tabwriter"
+	"time"
 
 	"github.com/fatih/color"
 	"github.com/lob/pharos/pkg/pharos/api"
+	"github.com/lob/pharos/pkg/util/kubeconfig"
 	"github.com/lob/pharos/pkg/util/model"
 	"github.com/pkg/errors"
 	"k8s.io/client-go/tools/clientcmd"
 	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 )
 
+// caExpiryWarningWindow is how long before a cluster's certificate authority
+// expires that the CLI starts warning about it.
+const caExpiryWarningWindow = 30 * 24 * time.Hour
+
 // CurrentCluster returns current context name.
 func CurrentCluster(kubeConfigFile string) (string, error) {
-	kubeConfig, err := configFromFile(kubeConfigFile)
+	kubeConfigs, err := loadKubeConfigs(kubeConfigFile, false, true)
 	if err != nil {
 		return "", errors.Wrap(err, "unable to load kubeconfig file")
 	}
+	kubeConfig := kubeConfigs.Merged()
 
 	// Make sure that current context exists.
 	_, ok := kubeConfig.Contexts[kubeConfig.CurrentContext]
@@ -33,82 +40,65 @@ func CurrentCluster(kubeConfigFile string) (string, error) {
 }
 
 // GetCluster gets information from a new cluster
-// and merges it into an existing kubeconfig file.
-func GetCluster(id string, kubeConfigFile string, dryRun bool, client *api.Client) error {
-	// Check whether given kubeconfig file already exists. If it does not, create a new kubeconfig
-	// file in the specified file location. Return an error only if file is malformed, but not
-	// if it is empty or missing.
-	kubeConfig, err := configFromFile(kubeConfigFile)
-	if err != nil && !os.IsNotExist(err) {
+// and merges it into an existing kubeconfig file. The cluster is given by its
+// ID or the name of its environment, which the Pharos API resolves. If diff is
+// set to a diff format, the changes are printed in that format instead of being
+// written.
+func GetCluster(id string, kubeConfigFile string, dryRun bool, diff string, client *api.Client) error {
+	// Load the kubeconfig files, treating missing ones as empty, and hold their
+	// locks until they have been written. Return an error only if a file is
+	// malformed.
+	kubeConfigs, err := loadKubeConfigs(kubeConfigFile, !dryRun && diff == "", false)
+	if err != nil {
 		return errors.Wrap(err, "unable to load kubeconfig file")
 	}
-	if kubeConfig == nil {
-		kubeConfig = clientcmdapi.NewConfig()
-	}
+	defer kubeConfigs.Close()
+	kubeConfig := kubeConfigs.Merged()
 
-	// Check whether given id has a suffix composed of a dash followed by six numbers.
-	// (Example: sandbox-111111 vs sandbox)
-	// If the id has no suffix, this means that we were given an environment name instead
-	// of a cluster id and we need to fetch the id of the currently active cluster from
-	// the Pharos API.
-	var cluster model.Cluster
-	match, err := regexp.MatchString(`-\d{6}`, id)
+	cluster, err := client.ResolveCluster(id)
 	if err != nil {
-		return errors.Wrap(err, "unable to match cluster ID with regex")
+		return err
 	}
-	if !match {
-		// Create query to find active cluster of given environment.
-		q := map[string]string{
-			"active":      "true",
-			"environment": id,
-		}
 
-		clusters, err := client.ListClusters(q)
-		if err != nil {
-			return errors.Wrap(err, "unable to list clusters for specified environment")
-		}
-		switch {
-		case len(clusters) < 1:
-			return fmt.Errorf("no active cluster found for environment %s", id)
-		case len(clusters) > 1:
-			return fmt.Errorf("%d clusters found for environment %s", len(clusters), id)
-		}
+	// Update user, context, and cluster information associated with the cluster
+	// in the kubeconfig.
+	context, err := Naming.AddCluster(kubeConfig, cluster)
+	if err != nil {
+		return errors.Wrap(err, "unable to name kubeconfig entries")
+	}
+	names, err := Naming.Names(cluster)
+	if err != nil {
+		return errors.Wrap(err, "unable to name kubeconfig entries")
+	}
 
-		cluster = clusters[0]
-	} else {
-		// Get cluster information for a specific cluster from Pharos API.
-		cluster, err = client.GetCluster(id)
-		if err != nil {
-			return err
-		}
+	// Update existing context for the specified environment.
+	name := names.Context
+	if id != names.Context {
+		kubeConfig.Contexts[id] = kubeconfig.Alias(context, cluster)
+		name = id
 	}
 
 	// If a kubeconfig has no current context set, set current context to the environment or
 	// id that was passed in.
 	if kubeConfig.CurrentContext == "" {
-		kubeConfig.CurrentContext = id
+		kubeConfig.CurrentContext = name
 	}
 
-	// Set username associated with new cluster.
-	clusterID := cluster.ID
-	username := fmt.Sprintf("iam-%s", clusterID)
-
-	// Update user, context, and cluster information associated with the cluster
-	// in the kubeconfig.
-	kubeConfig.Clusters[clusterID] = newCluster(cluster)
-	kubeConfig.AuthInfos[username] = newUser(clusterID, cluster.Environment)
-	context := newContext(clusterID, username)
-	kubeConfig.Contexts[clusterID] = context
-
-	// Update existing context for the specified environment.
-	kubeConfig.Contexts[id] = context
-
 	// Check for errors in newly created config.
 	err = clientcmd.Validate(*kubeConfig)
 	if err != nil {
 		return errors.Wrap(err, "unable to create valid kubeconfig")
 	}
 
+	// Warn on stderr so that the output of a dry run can still be piped.
+	if warning := caExpiryWarning(cluster); warning != "" {
+		fmt.Fprintln(os.Stderr, warning)
+	}
+
+	if diff != "" {
+		return printDiff(kubeConfigs.String(), kubeConfigs.Merged(), kubeConfig, diff)
+	}
+
 	// Print kubeconfig to terminal instead of saving to file during a dry run.
 	if dryRun {
 		yaml, err := clientcmd.Write(*kubeConfig)
@@ -119,12 +109,12 @@ func GetCluster(id string, kubeConfigFile string, dryRun bool, client *api.Clien
 		return nil
 	}
 
-	// Write kubeconfig to file.
-	err = clientcmd.WriteToFile(*kubeConfig, kubeConfigFile)
+	// Write the changes to the kubeconfig files that own them.
+	written, err := kubeConfigs.Write(kubeConfig)
 	if err != nil {
 		return err
 	}
-	fmt.Printf("%s MERGED CLUSTER %s INTO %s\n", color.GreenString("SUCCESS:"), id, kubeConfigFile)
+	fmt.Printf("%s MERGED CLUSTER %s INTO %s\n", color.GreenString("SUCCESS:"), id, strings.Join(written, ", "))
 	return nil
 }
 
@@ -151,13 +141,18 @@ func ListClusters(env string, inactive bool, client *api.Client) (string, error)
 	cyan := color.New(color.FgCyan)
 
 	// Add spaces to prevent ANSI escape codes from breaking the tabwriter formatting.
-	_, err = cyan.Fprint(w, "CLUSTER_ID\t     ENVIRONMENT\t     ACTIVE\t     SERVER")
+	_, err = cyan.Fprint(w, "CLUSTER_ID\t     ENVIRONMENT\t     ACTIVE\t     STATUS\t     EXPIRES\t     SERVER")
 	if err != nil {
 		return "", err
 	}
 
 	for _, cluster := range c {
-		fmt.Fprintf(w, "\n%s\t%s\t%s\t%s", cluster.ID, cluster.Environment, strconv.FormatBool(cluster.Active), cluster.ServerURL)
+		// Clusters that haven't been checked by the server yet have no status.
+		status := "unknown"
+		if cluster.Status != nil {
+			status = cluster.Status.Status
+		}
+		fmt.Fprintf(w, "\n%s\t%s\t%s\t%s\t%s\t%s", cluster.ID, cluster.Environment, strconv.FormatBool(cluster.Active), status, expiresIn(cluster), cluster.ServerURL)
 	}
 
 	fmt.Fprintln(w, "")
@@ -165,15 +160,63 @@ func ListClusters(env string, inactive bool, client *api.Client) (string, error)
 		return "", err
 	}
 
+	for _, cluster := range c {
+		if warning := caExpiryWarning(cluster); warning != "" {
+			fmt.Fprintln(buf, warning)
+		}
+	}
+
 	return buf.String(), nil
 }
 
+// expiresIn returns how long a cluster has left until it expires, such as
+// "2d4h", or "never" if it doesn't expire.
+func expiresIn(cluster model.Cluster) string {
+	if cluster.ExpiresAt == nil {
+		return "never"
+	}
+
+	remaining := time.Until(*cluster.ExpiresAt)
+	switch {
+	case remaining <= 0:
+		return "expired"
+	case remaining >= 24*time.Hour:
+		return fmt.Sprintf("%dd%dh", int(remaining.Hours())/24, int(remaining.Hours())%24)
+	case remaining >= time.Hour:
+		return fmt.Sprintf("%dh%dm", int(remaining.Hours()), int(remaining.Minutes())%60)
+	default:
+		return fmt.Sprintf("%dm", int(remaining.Minutes()))
+	}
+}
+
+// caExpiryWarning returns a warning if the certificate authority of a cluster
+// has expired or expires within caExpiryWarningWindow, and an empty string
+// otherwise.
+func caExpiryWarning(cluster model.Cluster) string {
+	if cluster.CAExpiresAt == nil {
+		return ""
+	}
+
+	expiry := *cluster.CAExpiresAt
+	remaining := time.Until(expiry)
+	switch {
+	case remaining <= 0:
+		return fmt.Sprintf("%s CERTIFICATE AUTHORITY FOR CLUSTER %s EXPIRED ON %s", color.YellowString("WARNING:"), cluster.ID, expiry.Format("2006-01-02"))
+	case remaining <= caExpiryWarningWindow:
+		days := int(remaining.Hours() / 24)
+		return fmt.Sprintf("%s CERTIFICATE AUTHORITY FOR CLUSTER %s EXPIRES ON %s (IN %d DAYS)", color.YellowString("WARNING:"), cluster.ID, expiry.Format("2006-01-02"), days)
+	}
+	return ""
+}
+
 // SwitchCluster switches current context to given cluster or context name.
 func SwitchCluster(kubeConfigFile string, context string) error {
-	kubeConfig, err := configFromFile(kubeConfigFile)
+	kubeConfigs, err := loadKubeConfigs(kubeConfigFile, true, true)
 	if err != nil {
 		return err
 	}
+	defer kubeConfigs.Close()
+	kubeConfig := kubeConfigs.Merged()
 
 	// Check if there is a context corresponding to the given context name or cluster.
 	_, ok := kubeConfig.Contexts[context]
@@ -190,26 +233,30 @@ func SwitchCluster(kubeConfigFile string, context string) error {
 		return errors.Wrap(err, "unable to create valid kubeconfig")
 	}
 
-	return clientcmd.WriteToFile(*kubeConfig, kubeConfigFile)
+	// The current context is written to the file that sets it.
+	_, err = kubeConfigs.Write(kubeConfig)
+	return err
 }
 
 // SyncClusters gets information from clusters and merges it into a kubeconfig file.
-func SyncClusters(kubeConfigFile string, inactive bool, dryRun bool, overwrite bool, client *api.Client) error {
-	var kubeConfig *clientcmdapi.Config
-	var err error
-
-	if !overwrite {
-		// Check whether given kubeconfig file already exists. If it does not, create a new kubeconfig
-		// file in the specified file location. Return an error only if file is malformed, but not
-		// if it is empty or missing.
-		kubeConfig, err = configFromFile(kubeConfigFile)
-		if err != nil && !os.IsNotExist(err) {
-			return errors.Wrap(err, "unable to load kubeconfig file")
-		}
+// If diff is set to a diff format, the changes are printed in that format
+// instead of being written. If prune is set, entries written by Pharos for
+// clusters that weren't synced are removed.
+func SyncClusters(kubeConfigFile string, inactive bool, dryRun bool, diff string, overwrite bool, prune bool, client *api.Client) error {
+	// Load the kubeconfig files, treating missing ones as empty, and hold their
+	// locks until they have been written. Return an error only if a file is
+	// malformed.
+	kubeConfigs, err := loadKubeConfigs(kubeConfigFile, !dryRun && diff == "", false)
+	if err != nil {
+		return errors.Wrap(err, "unable to load kubeconfig file")
 	}
-	// If overwrite is set to true, start with new kubeconfig file regardless.
-	if kubeConfig == nil {
-		kubeConfig = clientcmdapi.NewConfig()
+	defer kubeConfigs.Close()
+
+	// If overwrite is set to true, start with a new kubeconfig that replaces the
+	// destination file regardless.
+	before, kubeConfig := kubeConfigs.Merged(), kubeConfigs.Merged()
+	if overwrite {
+		before, kubeConfig = kubeConfigs.configs[kubeConfigs.dest], clientcmdapi.NewConfig()
 	}
 
 	// If inactive is false, we'll only sync active clusters, otherwise we'll
@@ -226,18 +273,14 @@ func SyncClusters(kubeConfigFile string, inactive bool, dryRun bool, overwrite b
 
 	// Add cluster, context, and user for each cluster. There should never be
 	// more than one cluster marked active for each environment.
-	for _, cluster := range clusters {
-		clusterID := cluster.ID
-		env := cluster.Environment
-		username := fmt.Sprintf("iam-%s", clusterID)
-		kubeConfig.Clusters[clusterID] = newCluster(cluster)
-		kubeConfig.AuthInfos[username] = newUser(clusterID, env)
-		context := newContext(clusterID, username)
-		kubeConfig.Contexts[clusterID] = context
-
-		if cluster.Active {
-			kubeConfig.Contexts[env] = context
-		}
+	err = Naming.Merge(kubeConfig, clusters)
+	if err != nil {
+		return errors.Wrap(err, "unable to name kubeconfig entries")
+	}
+
+	var pruned []string
+	if prune {
+		pruned = kubeconfig.Prune(kubeConfig, clusters)
 	}
 
 	// Check for errors in newly created config.
@@ -246,6 +289,10 @@ func SyncClusters(kubeConfigFile string, inactive bool, dryRun bool, overwrite b
 		return errors.Wrap(err, "unable to create valid kubeconfig")
 	}
 
+	if diff != "" {
+		return printDiff(kubeConfigs.String(), before, kubeConfig, diff)
+	}
+
 	// Print kubeconfig to terminal instead of saving to file during a dry run.
 	if dryRun {
 		yaml, err := clientcmd.Write(*kubeConfig)
@@ -256,7 +303,12 @@ func SyncClusters(kubeConfigFile string, inactive bool, dryRun bool, overwrite b
 		return nil
 	}
 
-	err = clientcmd.WriteToFile(*kubeConfig, kubeConfigFile)
+	written := []string{kubeConfigs.dest}
+	if overwrite {
+		err = kubeConfigs.Overwrite(kubeConfig)
+	} else {
+		written, err = kubeConfigs.Write(kubeConfig)
+	}
 	if err != nil {
 		return err
 	}
@@ -266,6 +318,9 @@ func SyncClusters(kubeConfigFile string, inactive bool, dryRun bool, overwrite b
 	if overwrite {
 		verb = "OVERWROTE"
 	}
-	fmt.Printf("%s SYNCED AND %s %d CLUSTERS INTO %s\n", color.GreenString("SUCCESS:"), verb, len(clusters), kubeConfigFile)
+	fmt.Printf("%s SYNCED AND %s %d CLUSTERS INTO %s\n", color.GreenString("SUCCESS:"), verb, len(clusters), strings.Join(written, ", "))
+	if len(pruned) > 0 {
+		fmt.Printf("%s PRUNED %d CLUSTERS: %s\n", color.GreenString("SUCCESS:"), len(pruned), strings.Join(pruned, ", "))
+	}
 	return nil
 }
@@ -1,15 +1,18 @@
 package cli
 
 import (
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/lob/pharos/internal/test"
 	"github.com/lob/pharos/pkg/pharos/api"
 	configpkg "github.com/lob/pharos/pkg/pharos/config"
+	"github.com/lob/pharos/pkg/util/model"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
@@ -58,38 +61,51 @@ func TestGetCluster(t *testing.T) {
 		"object":                 "cluster",
 		"active":                 false
 	}`)
-	listResponse := []byte(`[{
+	activeResponse := []byte(`{
 		"id":                     "sandbox-333333",
 		"environment":            "sandbox",
 		"cluster_authority_data": "LS0tLS1CRUdJTiBDR...",
 		"server_url":             "https://test.elb.us-west-2.amazonaws.com:6443",
 		"object":                 "cluster",
 		"active":                 true
-	}]`)
-	listResponse0 := []byte(`[]`)
-	listResponse2 := []byte(`[{},{}]`)
-	listResponse3 := []byte(`[{
+	}`)
+	activeResponse2 := []byte(`{
 		"id":                     "platform-postmasters-777777",
 		"environment":            "platform-postmasters",
 		"cluster_authority_data": "LS0tLS1CRUdJTiBDR...",
 		"server_url":             "https://test.elb.us-west-2.amazonaws.com:6443",
 		"object":                 "cluster",
 		"active":                 true
-	}]`)
+	}`)
+	hexResponse := []byte(`{
+		"id":                     "production-6906ce",
+		"environment":            "production",
+		"cluster_authority_data": "LS0tLS1CRUdJTiBDR...",
+		"server_url":             "https://test.elb.us-west-2.amazonaws.com:6443",
+		"object":                 "cluster",
+		"active":                 false
+	}`)
 
 	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
 		var response []byte
 		switch r.URL.String() {
-		case "/clusters/sandbox-222222":
+		case "/v1/resolve/sandbox-222222":
 			response = getResponse
-		case "/clusters?active=true&environment=sandbox":
-			response = listResponse
-		case "/clusters?active=true&environment=test0clusters":
-			response = listResponse0
-		case "/clusters?active=true&environment=test2clusters":
-			response = listResponse2
-		case "/clusters?active=true&environment=platform-postmasters":
-			response = listResponse3
+		case "/v1/resolve/sandbox":
+			response = activeResponse
+		case "/v1/resolve/platform-postmasters":
+			response = activeResponse2
+		case "/v1/resolve/production-6906ce":
+			response = hexResponse
+		case "/v1/resolve/test0clusters":
+			rw.WriteHeader(http.StatusConflict)
+			response = []byte(`{"error":{"message":"no active cluster found for environment test0clusters, candidates: test0clusters-111111","status_code":409}}`)
+		case "/v1/resolve/test2clusters":
+			rw.WriteHeader(http.StatusConflict)
+			response = []byte(`{"error":{"message":"2 active clusters found for environment test2clusters, candidates: test2clusters-111111, test2clusters-222222","status_code":409}}`)
+		default:
+			rw.WriteHeader(http.StatusNotFound)
+			response = []byte(`{"error":{"message":"cluster not found","status_code":404}}`)
 		}
 		_, err := rw.Write(response)
 		require.NoError(t, err)
@@ -106,7 +122,7 @@ func TestGetCluster(t *testing.T) {
 		defer os.Remove(configFile)
 
 		// Merge cluster information from active cluster for sandbox into configFile.
-		err := GetCluster("sandbox", configFile, false, client)
+		err := GetCluster("sandbox", configFile, false, "", client)
 		assert.NoError(tt, err)
 
 		// Load kubeconfig file for testing.
@@ -144,7 +160,7 @@ func TestGetCluster(t *testing.T) {
 		defer os.Remove(nonExistentConfig)
 
 		// Merge cluster information from active cluster for sandbox into nonexistent file.
-		err := GetCluster("sandbox", nonExistentConfig, false, client)
+		err := GetCluster("sandbox", nonExistentConfig, false, "", client)
 		assert.NoError(tt, err)
 
 		// Load kubeconfig file for testing.
@@ -161,7 +177,7 @@ func TestGetCluster(t *testing.T) {
 		defer os.Remove(configFile)
 
 		// Merge cluster information from active cluster for sandbox into configFile.
-		err := GetCluster("sandbox-222222", configFile, false, client)
+		err := GetCluster("sandbox-222222", configFile, false, "", client)
 		assert.NoError(tt, err)
 
 		// Load kubeconfig file for testing.
@@ -177,41 +193,81 @@ func TestGetCluster(t *testing.T) {
 		assert.NoError(tt, err)
 
 		// Run get cluster with dry-run.
-		err = GetCluster("sandbox", config, true, client)
+		err = GetCluster("sandbox", config, true, "", client)
+		assert.NoError(tt, err)
+
+		// Check that kubeconfig file has not been modified.
+		kubeConfig, err := configFromFile(config)
+		assert.NoError(tt, err)
+		assert.True(tt, reflect.DeepEqual(oldKubeConfig, kubeConfig))
+	})
+
+	t.Run("takes no action when --diff flag is set", func(tt *testing.T) {
+		oldKubeConfig, err := configFromFile(config)
+		assert.NoError(tt, err)
+
+		// Run get cluster with diff.
+		err = GetCluster("sandbox", config, false, DiffJSON, client)
 		assert.NoError(tt, err)
 
 		// Check that kubeconfig file has not been modified.
 		kubeConfig, err := configFromFile(config)
 		assert.NoError(tt, err)
 		assert.True(tt, reflect.DeepEqual(oldKubeConfig, kubeConfig))
+
+		// Unknown diff formats are rejected.
+		err = GetCluster("sandbox", config, false, "yaml", client)
+		assert.Error(tt, err)
+		assert.Contains(tt, err.Error(), "unknown diff format yaml")
 	})
 
 	t.Run("errors on merging with malformed kubeconfig file", func(tt *testing.T) {
-		err := GetCluster("sandbox", malformedConfig, true, client)
+		err := GetCluster("sandbox", malformedConfig, true, "", client)
 		assert.Error(tt, err)
 		assert.Contains(tt, err.Error(), "unable to load kubeconfig file")
 	})
 
 	t.Run("errors related to retrieving cluster information from the pharos API", func(tt *testing.T) {
-		// Failed to list cluster.
-		err := GetCluster("production", config, false, client)
+		// Failed to resolve an unknown environment.
+		err := GetCluster("production", config, false, "", client)
 		assert.Error(tt, err)
-		assert.Contains(tt, err.Error(), "unable to list clusters for specified environment")
+		assert.Contains(tt, err.Error(), "failed to resolve cluster production")
+		assert.Contains(tt, err.Error(), "cluster not found")
 
-		// Failed to get cluster.
-		err = GetCluster("sandbox-707070", config, false, client)
+		// Failed to resolve an unknown cluster.
+		err = GetCluster("sandbox-707070", config, false, "", client)
 		assert.Error(tt, err)
-		assert.Contains(tt, err.Error(), "failed to get cluster")
+		assert.Contains(tt, err.Error(), "failed to resolve cluster sandbox-707070")
 
-		// Received zero clusters from list cluster.
-		err = GetCluster("test0clusters", config, true, client)
+		// Environment has no active cluster.
+		err = GetCluster("test0clusters", config, true, "", client)
 		assert.Error(tt, err)
 		assert.Contains(tt, err.Error(), "no active cluster found for environment")
+		assert.Contains(tt, err.Error(), "candidates: test0clusters-111111")
 
-		// Received too many clusters from list cluster.
-		err = GetCluster("test2clusters", config, true, client)
+		// Environment has too many active clusters.
+		err = GetCluster("test2clusters", config, true, "", client)
 		assert.Error(tt, err)
-		assert.Contains(tt, err.Error(), "2 clusters found for environment")
+		assert.Contains(tt, err.Error(), "2 active clusters found for environment")
+	})
+
+	t.Run("successfully merges a cluster whose ID doesn't end in six digits", func(tt *testing.T) {
+		// Create temporary test config file and defer cleanup.
+		configFile := test.CopyTestFile(tt, "../testdata", "get", emptyConfig)
+		defer os.Remove(configFile)
+
+		err := GetCluster("production-6906ce", configFile, false, "", client)
+		assert.NoError(tt, err)
+
+		kubeConfig, err := configFromFile(configFile)
+		assert.NoError(tt, err)
+
+		// The ID is not mistaken for an environment, so no alias is added.
+		_, ok := kubeConfig.Contexts["production-6906ce"]
+		assert.True(tt, ok)
+		_, ok = kubeConfig.Contexts["production"]
+		assert.False(tt, ok)
+		assert.Equal(tt, "production-6906ce", kubeConfig.CurrentContext)
 	})
 
 	t.Run("successfully merges new kubeconfig file from cluster using environment with more than one dash into an empty file", func(tt *testing.T) {
@@ -220,7 +276,7 @@ func TestGetCluster(t *testing.T) {
 		defer os.Remove(configFile)
 
 		// Merge cluster information from active cluster for sandbox into configFile.
-		err := GetCluster("platform-postmasters", configFile, false, client)
+		err := GetCluster("platform-postmasters", configFile, false, "", client)
 		assert.NoError(tt, err)
 
 		// Load kubeconfig file for testing.
@@ -246,28 +302,35 @@ func TestGetCluster(t *testing.T) {
 
 func TestListClusters(t *testing.T) {
 	// Set up dummy server for testing.
-	listClusters := []byte(`[{
+	expiresSoon := time.Now().Add(10 * 24 * time.Hour).Format(time.RFC3339)
+	expiresLater := time.Now().Add(90 * 24 * time.Hour).Format(time.RFC3339)
+	previewExpiry := time.Now().Add(50*time.Hour + 30*time.Minute).Format(time.RFC3339)
+	listClusters := []byte(fmt.Sprintf(`[{
 		"id":                     "production-eggs",
 		"environment":            "production",
 		"cluster_authority_data": "LS0tLS1CRUdJTiBDR...",
 		"server_url":             "https://test.elb.us-west-2.amazonaws.com:6443",
 		"object":                 "cluster",
-		"active":                 false
+		"active":                 false,
+		"ca_expires_at":          "%s"
 	},{
 		"id":                     "sandbox-333333",
 		"environment":            "sandbox",
 		"cluster_authority_data": "LS0tLS1CRUdJTiBDR...",
 		"server_url":             "https://test.elb.us-west-2.amazonaws.com:6443",
 		"object":                 "cluster",
-		"active":                 true
+		"active":                 true,
+		"status":                 {"cluster_id": "sandbox-333333", "status": "reachable"},
+		"expires_at":             "%s"
 	},{
 		"id":                     "sandbox-222222",
 		"environment":            "sandbox",
 		"cluster_authority_data": "LS0tLS1CRUdJTiBDR...",
 		"server_url":             "https://test.elb.us-west-2.amazonaws.com:6443",
 		"object":                 "cluster",
-		"active":                 false
-	}]`)
+		"active":                 false,
+		"ca_expires_at":          "%s"
+	}]`, expiresLater, previewExpiry, expiresSoon))
 	listSandbox := []byte(`[{
 		"id":                     "sandbox-333333",
 		"environment":            "sandbox",
@@ -295,11 +358,11 @@ func TestListClusters(t *testing.T) {
 	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
 		var response []byte
 		switch r.URL.String() {
-		case "/clusters":
+		case "/v1/clusters":
 			response = listClusters
-		case "/clusters?environment=sandbox":
+		case "/v1/clusters?environment=sandbox":
 			response = listSandbox
-		case "/clusters?active=true&environment=staging":
+		case "/v1/clusters?active=true&environment=staging":
 			response = listActiveStaging
 		}
 		_, err := rw.Write(response)
@@ -320,6 +383,29 @@ func TestListClusters(t *testing.T) {
 		assert.Contains(tt, clusters, "production-eggs")
 	})
 
+	t.Run("successfully lists the status of clusters", func(tt *testing.T) {
+		clusters, err := ListClusters("", true, client)
+		assert.NoError(tt, err)
+		assert.Contains(tt, clusters, "STATUS")
+		assert.Regexp(tt, `sandbox-333333\s+sandbox\s+true\s+reachable`, clusters)
+		assert.Regexp(tt, `sandbox-222222\s+sandbox\s+false\s+unknown`, clusters)
+	})
+
+	t.Run("lists the time clusters have left until they expire", func(tt *testing.T) {
+		clusters, err := ListClusters("", true, client)
+		assert.NoError(tt, err)
+		assert.Contains(tt, clusters, "EXPIRES")
+		assert.Regexp(tt, `sandbox-333333\s+sandbox\s+true\s+reachable\s+2d2h\s`, clusters)
+		assert.Regexp(tt, `sandbox-222222\s+sandbox\s+false\s+unknown\s+never\s`, clusters)
+	})
+
+	t.Run("warns about clusters whose certificate authority expires soon", func(tt *testing.T) {
+		clusters, err := ListClusters("", true, client)
+		assert.NoError(tt, err)
+		assert.Contains(tt, clusters, "CERTIFICATE AUTHORITY FOR CLUSTER sandbox-222222 EXPIRES ON")
+		assert.NotContains(tt, clusters, "CERTIFICATE AUTHORITY FOR CLUSTER production-eggs")
+	})
+
 	t.Run("successfully lists all clusters for an environment", func(tt *testing.T) {
 		// List all clusters for a certain environment.
 		clusters, err := ListClusters("sandbox", true, client)
@@ -343,6 +429,21 @@ func TestListClusters(t *testing.T) {
 	})
 }
 
+func TestExpiresIn(t *testing.T) {
+	t.Run("formats the time left until a cluster expires", func(tt *testing.T) {
+		at := func(d time.Duration) model.Cluster {
+			expiry := time.Now().Add(d)
+			return model.Cluster{ExpiresAt: &expiry}
+		}
+
+		assert.Equal(tt, "never", expiresIn(model.Cluster{}))
+		assert.Equal(tt, "expired", expiresIn(at(-time.Minute)))
+		assert.Equal(tt, "45m", expiresIn(at(45*time.Minute+30*time.Second)))
+		assert.Equal(tt, "5h10m", expiresIn(at(5*time.Hour+10*time.Minute+30*time.Second)))
+		assert.Equal(tt, "3d0h", expiresIn(at(72*time.Hour+30*time.Second)))
+	})
+}
+
 func TestSwitchCluster(t *testing.T) {
 	t.Run("successfully switches to cluster", func(tt *testing.T) {
 		// Create temporary test config file and defer cleanup.
@@ -463,9 +564,9 @@ func TestSyncClusters(t *testing.T) {
 	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
 		var response []byte
 		switch r.URL.String() {
-		case "/clusters?active=true":
+		case "/v1/clusters?active=true":
 			response = syncResponse
-		case "/clusters":
+		case "/v1/clusters":
 			response = syncInactiveResponse
 		}
 		_, err := rw.Write(response)
@@ -483,7 +584,7 @@ func TestSyncClusters(t *testing.T) {
 		defer os.Remove(configFile)
 
 		// Sync clusters, including inactive ones.
-		err := SyncClusters(configFile, true, false, false, client)
+		err := SyncClusters(configFile, true, false, "", false, false, client)
 		assert.NoError(tt, err)
 
 		// Load kubeconfig file for testing.
@@ -544,7 +645,7 @@ func TestSyncClusters(t *testing.T) {
 		defer os.Remove(configFile)
 
 		// Sync clusters, including inactive ones.
-		err := SyncClusters(configFile, true, false, true, client)
+		err := SyncClusters(configFile, true, false, "", true, false, client)
 		assert.NoError(tt, err)
 
 		// Load kubeconfig file for testing.
@@ -570,7 +671,7 @@ func TestSyncClusters(t *testing.T) {
 		defer os.Remove(nonExistentConfig)
 
 		// Sync clusters, including inactive ones.
-		err := SyncClusters(nonExistentConfig, true, false, false, client)
+		err := SyncClusters(nonExistentConfig, true, false, "", false, false, client)
 		assert.NoError(tt, err)
 
 		// Load kubeconfig file for testing.
@@ -606,7 +707,7 @@ func TestSyncClusters(t *testing.T) {
 		defer os.Remove(configFile)
 
 		// Sync only active clusters.
-		err := SyncClusters(configFile, false, false, false, client)
+		err := SyncClusters(configFile, false, false, "", false, false, client)
 		assert.NoError(tt, err)
 
 		// Load kubeconfig file for testing.
@@ -622,12 +723,36 @@ func TestSyncClusters(t *testing.T) {
 		assert.False(tt, ok)
 	})
 
+	t.Run("prunes clusters written by pharos that were not synced", func(tt *testing.T) {
+		// Create temporary test config file and defer cleanup.
+		configFile := test.CopyTestFile(tt, "../testdata", "sync", config)
+		defer os.Remove(configFile)
+
+		// Sync all clusters, then only active ones with pruning.
+		err := SyncClusters(configFile, true, false, "", false, false, client)
+		require.NoError(tt, err)
+		err = SyncClusters(configFile, false, false, "", false, true, client)
+		require.NoError(tt, err)
+
+		kubeConfig, err := configFromFile(configFile)
+		require.NoError(tt, err)
+
+		// Inactive clusters written by the first sync were removed.
+		assert.NotContains(tt, kubeConfig.Clusters, "staging-555555")
+		assert.NotContains(tt, kubeConfig.AuthInfos, "iam-staging-555555")
+		assert.NotContains(tt, kubeConfig.Contexts, "staging-555555")
+		assert.Contains(tt, kubeConfig.Clusters, "staging-666666")
+
+		// Clusters that weren't written by pharos were kept.
+		assert.Contains(tt, kubeConfig.Clusters, "sandbox-111111")
+	})
+
 	t.Run("takes no action when --dry-run flag is set", func(tt *testing.T) {
 		oldKubeConfig, err := configFromFile(config)
 		assert.NoError(tt, err)
 
 		// Run get cluster with dry-run.
-		err = SyncClusters(config, false, true, false, client)
+		err = SyncClusters(config, false, true, "", false, false, client)
 		assert.NoError(tt, err)
 
 		// Check that kubeconfig file has not been modified.
@@ -637,14 +762,14 @@ func TestSyncClusters(t *testing.T) {
 	})
 
 	t.Run("errors on merging with malformed kubeconfig file", func(tt *testing.T) {
-		err := SyncClusters(malformedConfig, false, true, false, client)
+		err := SyncClusters(malformedConfig, false, true, "", false, false, client)
 		assert.Error(tt, err)
 		assert.Contains(tt, err.Error(), "unable to load kubeconfig file")
 	})
 
 	t.Run("errors related to retrieving cluster information from the pharos API", func(tt *testing.T) {
 		// Failed to list cluster.
-		err := SyncClusters(config, false, false, false, api.NewClient(&configpkg.Config{BaseURL: ""}, tokenGenerator))
+		err := SyncClusters(config, false, false, "", false, false, api.NewClient(&configpkg.Config{BaseURL: ""}, tokenGenerator))
 		assert.Error(tt, err)
 		assert.Contains(tt, err.Error(), "failed to list clusters")
 	})
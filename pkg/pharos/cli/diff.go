@@ -0,0 +1,224 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/pkg/errors"
+	"github.com/pmezard/go-difflib/difflib"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// Formats that a kubeconfig diff can be printed in.
+const (
+	DiffText = "text"
+	DiffJSON = "json"
+)
+
+// ConfigDiff is a semantic diff between two kubeconfigs.
+type ConfigDiff struct {
+	Clusters       EntryDiff    `json:"clusters"`
+	Users          EntryDiff    `json:"users"`
+	Contexts       EntryDiff    `json:"contexts"`
+	CurrentContext *ValueChange `json:"current_context,omitempty"`
+}
+
+// EntryDiff lists the names of the kubeconfig entries of one kind that were
+// added, changed or removed.
+type EntryDiff struct {
+	Added   []string `json:"added"`
+	Changed []string `json:"changed"`
+	Removed []string `json:"removed"`
+}
+
+// ValueChange describes a value that changed.
+type ValueChange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Empty reports whether the kubeconfigs are the same.
+func (d ConfigDiff) Empty() bool {
+	return d.Clusters.empty() && d.Users.empty() && d.Contexts.empty() && d.CurrentContext == nil
+}
+
+func (d EntryDiff) empty() bool {
+	return len(d.Added) == 0 && len(d.Changed) == 0 && len(d.Removed) == 0
+}
+
+// DiffConfigs returns the semantic diff between two kubeconfigs.
+func DiffConfigs(before, after *clientcmdapi.Config) (ConfigDiff, error) {
+	var diff ConfigDiff
+	var err error
+
+	clusters := func(c *clientcmdapi.Config) map[string]interface{} {
+		m := make(map[string]interface{}, len(c.Clusters))
+		for name, cluster := range c.Clusters {
+			cluster = cluster.DeepCopy()
+			cluster.LocationOfOrigin = ""
+			m[name] = cluster
+		}
+		return m
+	}
+	users := func(c *clientcmdapi.Config) map[string]interface{} {
+		m := make(map[string]interface{}, len(c.AuthInfos))
+		for name, user := range c.AuthInfos {
+			user = user.DeepCopy()
+			user.LocationOfOrigin = ""
+			m[name] = user
+		}
+		return m
+	}
+	contexts := func(c *clientcmdapi.Config) map[string]interface{} {
+		m := make(map[string]interface{}, len(c.Contexts))
+		for name, context := range c.Contexts {
+			context = context.DeepCopy()
+			context.LocationOfOrigin = ""
+			m[name] = context
+		}
+		return m
+	}
+
+	if diff.Clusters, err = diffEntries(clusters(before), clusters(after)); err != nil {
+		return diff, err
+	}
+	if diff.Users, err = diffEntries(users(before), users(after)); err != nil {
+		return diff, err
+	}
+	if diff.Contexts, err = diffEntries(contexts(before), contexts(after)); err != nil {
+		return diff, err
+	}
+	if before.CurrentContext != after.CurrentContext {
+		diff.CurrentContext = &ValueChange{before.CurrentContext, after.CurrentContext}
+	}
+
+	return diff, nil
+}
+
+// diffEntries compares kubeconfig entries by their serialized form.
+func diffEntries(before, after map[string]interface{}) (EntryDiff, error) {
+	diff := EntryDiff{Added: []string{}, Changed: []string{}, Removed: []string{}}
+
+	for name, entry := range after {
+		old, ok := before[name]
+		if !ok {
+			diff.Added = append(diff.Added, name)
+			continue
+		}
+
+		a, err := json.Marshal(old)
+		if err != nil {
+			return diff, err
+		}
+		b, err := json.Marshal(entry)
+		if err != nil {
+			return diff, err
+		}
+		if !bytes.Equal(a, b) {
+			diff.Changed = append(diff.Changed, name)
+		}
+	}
+	for name := range before {
+		if _, ok := after[name]; !ok {
+			diff.Removed = append(diff.Removed, name)
+		}
+	}
+
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Changed)
+	sort.Strings(diff.Removed)
+	return diff, nil
+}
+
+// printDiff prints the diff between kubeconfigs loaded from the given files and
+// the kubeconfig that would be written to them, either as JSON or as a summary
+// followed by a colored unified diff.
+func printDiff(kubeConfigFile string, before, after *clientcmdapi.Config, format string) error {
+	diff, err := DiffConfigs(before, after)
+	if err != nil {
+		return errors.Wrap(err, "unable to diff kubeconfig")
+	}
+
+	switch format {
+	case DiffJSON:
+		raw, err := json.MarshalIndent(diff, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(raw))
+		return nil
+	case DiffText:
+	default:
+		return fmt.Errorf("unknown diff format %s, must be %s or %s", format, DiffText, DiffJSON)
+	}
+
+	if diff.Empty() {
+		fmt.Printf("NO CHANGES TO %s\n", kubeConfigFile)
+		return nil
+	}
+
+	summary := []struct {
+		kind string
+		diff EntryDiff
+	}{
+		{"cluster", diff.Clusters},
+		{"user", diff.Users},
+		{"context", diff.Contexts},
+	}
+	for _, s := range summary {
+		for _, name := range s.diff.Added {
+			fmt.Println(color.GreenString("+ %s %s", s.kind, name))
+		}
+		for _, name := range s.diff.Changed {
+			fmt.Println(color.YellowString("~ %s %s", s.kind, name))
+		}
+		for _, name := range s.diff.Removed {
+			fmt.Println(color.RedString("- %s %s", s.kind, name))
+		}
+	}
+	if diff.CurrentContext != nil {
+		fmt.Println(color.YellowString("~ current-context %q => %q", diff.CurrentContext.From, diff.CurrentContext.To))
+	}
+	fmt.Println()
+
+	a, err := clientcmd.Write(*before)
+	if err != nil {
+		return errors.Wrap(err, "unable to write kubeconfig")
+	}
+	b, err := clientcmd.Write(*after)
+	if err != nil {
+		return errors.Wrap(err, "unable to write kubeconfig")
+	}
+	unified, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(a)),
+		B:        difflib.SplitLines(string(b)),
+		FromFile: kubeConfigFile,
+		ToFile:   kubeConfigFile,
+		Context:  3,
+	})
+	if err != nil {
+		return errors.Wrap(err, "unable to diff kubeconfig")
+	}
+
+	for _, line := range difflib.SplitLines(unified) {
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+			fmt.Println(color.New(color.Bold).Sprint(line))
+		case strings.HasPrefix(line, "+"):
+			fmt.Println(color.GreenString("%s", line))
+		case strings.HasPrefix(line, "-"):
+			fmt.Println(color.RedString("%s", line))
+		case strings.HasPrefix(line, "@@"):
+			fmt.Println(color.CyanString("%s", line))
+		default:
+			fmt.Println(line)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/lob/pharos/pkg/util/kubeconfig"
+	"github.com/lob/pharos/pkg/util/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffConfigs(t *testing.T) {
+	before, err := configFromFile(config)
+	require.NoError(t, err)
+
+	t.Run("returns an empty diff for identical kubeconfigs", func(tt *testing.T) {
+		after, err := configFromFile(config)
+		require.NoError(tt, err)
+
+		diff, err := DiffConfigs(before, after)
+		require.NoError(tt, err)
+		assert.True(tt, diff.Empty())
+	})
+
+	t.Run("reports added, changed and removed entries", func(tt *testing.T) {
+		after := before.DeepCopy()
+
+		// Add a new cluster.
+		_, err = kubeconfig.DefaultNaming.AddCluster(after, model.Cluster{ID: "staging-123456", Environment: "staging", ServerURL: "https://staging.com"})
+		require.NoError(tt, err)
+
+		// Change and remove existing entries.
+		after.Clusters["sandbox-111111"].Server = "https://changed.com"
+		delete(after.Contexts, "sandbox-a61631")
+		after.CurrentContext = "staging-123456"
+
+		diff, err := DiffConfigs(before, after)
+		require.NoError(tt, err)
+		assert.False(tt, diff.Empty())
+		assert.Equal(tt, []string{"staging-123456"}, diff.Clusters.Added)
+		assert.Equal(tt, []string{"sandbox-111111"}, diff.Clusters.Changed)
+		assert.Empty(tt, diff.Clusters.Removed)
+		assert.Equal(tt, []string{"iam-staging-123456"}, diff.Users.Added)
+		assert.Equal(tt, []string{"staging-123456"}, diff.Contexts.Added)
+		assert.Empty(tt, diff.Contexts.Changed)
+		assert.Equal(tt, []string{"sandbox-a61631"}, diff.Contexts.Removed)
+		require.NotNil(tt, diff.CurrentContext)
+		assert.Equal(tt, before.CurrentContext, diff.CurrentContext.From)
+		assert.Equal(tt, "staging-123456", diff.CurrentContext.To)
+	})
+}
@@ -0,0 +1,62 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/fatih/color"
+	"github.com/lob/pharos/pkg/pharos/api"
+	"github.com/lob/pharos/pkg/util/model"
+)
+
+// DiscoverClusters runs cluster discovery on the Pharos API server and returns
+// a formatted summary of the clusters that were, or during a dry run would be,
+// created, updated or flagged as missing.
+func DiscoverClusters(dryRun bool, client *api.Client) (string, error) {
+	result, err := client.Discover(dryRun)
+	if err != nil {
+		return "", err
+	}
+
+	buf := new(bytes.Buffer)
+	w := tabwriter.NewWriter(buf, 0, 0, 3, ' ', 0)
+	cyan := color.New(color.FgCyan)
+
+	// Add spaces to prevent ANSI escape codes from breaking the tabwriter formatting.
+	_, err = cyan.Fprint(w, "CHANGE\t     CLUSTER_ID\t     ENVIRONMENT\t     SERVER")
+	if err != nil {
+		return "", err
+	}
+
+	changes := []struct {
+		name     string
+		clusters []model.Cluster
+	}{
+		{"create", result.Created},
+		{"update", result.Updated},
+		{"missing", result.Missing},
+	}
+	for _, change := range changes {
+		for _, cluster := range change.clusters {
+			fmt.Fprintf(w, "\n%s\t%s\t%s\t%s", change.name, cluster.ID, cluster.Environment, cluster.ServerURL)
+		}
+	}
+
+	fmt.Fprintln(w, "")
+	if err := w.Flush(); err != nil {
+		return "", err
+	}
+
+	for _, e := range result.Errors {
+		fmt.Fprintf(buf, "%s %s\n", color.YellowString("WARNING:"), e)
+	}
+
+	if dryRun {
+		fmt.Fprintf(buf, "%s WOULD CREATE %d, UPDATE %d AND FLAG %d MISSING CLUSTERS\n", color.CyanString("DRY RUN:"), len(result.Created), len(result.Updated), len(result.Missing))
+	} else {
+		fmt.Fprintf(buf, "%s CREATED %d, UPDATED %d AND FLAGGED %d MISSING CLUSTERS\n", color.GreenString("SUCCESS:"), len(result.Created), len(result.Updated), len(result.Missing))
+	}
+
+	return buf.String(), nil
+}
@@ -0,0 +1,52 @@
+package cli
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lob/pharos/internal/test"
+	"github.com/lob/pharos/pkg/pharos/api"
+	configpkg "github.com/lob/pharos/pkg/pharos/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiscoverClusters(t *testing.T) {
+	// Set up dummy server for testing.
+	discoverResponse := []byte(`{
+		"dry_run":   true,
+		"created":   [{"id": "sandbox-111111", "environment": "sandbox", "server_url": "https://sandbox.eks.amazonaws.com", "source": "eks"}],
+		"updated":   [{"id": "production-222222", "environment": "production", "server_url": "https://production.eks.amazonaws.com", "source": "eks"}],
+		"unchanged": [],
+		"missing":   [{"id": "staging-333333", "environment": "staging", "server_url": "https://staging.eks.amazonaws.com", "source": "eks", "missing": true}],
+		"errors":    ["failed to list EKS clusters in us-east-1: access denied"]
+	}`)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		_, err := rw.Write(discoverResponse)
+		require.NoError(t, err)
+	}))
+	defer srv.Close()
+	tokenGenerator := test.NewGenerator()
+
+	// Set BaseURL in config to be the url of the dummy server.
+	client := api.NewClient(&configpkg.Config{BaseURL: srv.URL}, tokenGenerator)
+
+	t.Run("successfully summarizes discovered clusters", func(tt *testing.T) {
+		output, err := DiscoverClusters(true, client)
+		assert.NoError(tt, err)
+		assert.Regexp(tt, `create\s+sandbox-111111\s+sandbox`, output)
+		assert.Regexp(tt, `update\s+production-222222\s+production`, output)
+		assert.Regexp(tt, `missing\s+staging-333333\s+staging`, output)
+		assert.Contains(tt, output, "failed to list EKS clusters in us-east-1")
+		assert.Contains(tt, output, "WOULD CREATE 1, UPDATE 1 AND FLAG 1 MISSING CLUSTERS")
+	})
+
+	t.Run("errors related to discovering clusters on the pharos API", func(tt *testing.T) {
+		client := api.NewClient(&configpkg.Config{BaseURL: ""}, tokenGenerator)
+		_, err := DiscoverClusters(true, client)
+		assert.Error(tt, err)
+		assert.Contains(tt, err.Error(), "failed to discover clusters")
+	})
+}
@@ -0,0 +1,51 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/lob/pharos/pkg/pharos/api"
+)
+
+// Doctor checks the clusters registered in Pharos for problems that break
+// resolving environments to clusters, and returns a formatted report along
+// with the number of problems found.
+func Doctor(client *api.Client) (string, int, error) {
+	clusters, err := client.ListClusters(map[string]string{"active": "true"})
+	if err != nil {
+		return "", 0, err
+	}
+
+	active := make(map[string][]string)
+	for _, cluster := range clusters {
+		active[cluster.Environment] = append(active[cluster.Environment], cluster.ID)
+	}
+	environments := make([]string, 0, len(active))
+	for environment := range active {
+		environments = append(environments, environment)
+	}
+	sort.Strings(environments)
+
+	buf := new(bytes.Buffer)
+	problems := 0
+	for _, environment := range environments {
+		ids := active[environment]
+		if len(ids) < 2 {
+			continue
+		}
+		sort.Strings(ids)
+		problems++
+		fmt.Fprintf(buf, "%s ENVIRONMENT %s HAS %d ACTIVE CLUSTERS: %s\n", color.RedString("PROBLEM:"), environment, len(ids), strings.Join(ids, ", "))
+	}
+
+	if problems == 0 {
+		fmt.Fprintf(buf, "%s EVERY ENVIRONMENT HAS AT MOST ONE ACTIVE CLUSTER\n", color.GreenString("OK:"))
+	} else {
+		fmt.Fprintf(buf, "Run pharos clusters update <cluster_id> to keep only that cluster of its environment active.\n")
+	}
+
+	return buf.String(), problems, nil
+}
@@ -0,0 +1,58 @@
+package cli
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lob/pharos/internal/test"
+	"github.com/lob/pharos/pkg/pharos/api"
+	configpkg "github.com/lob/pharos/pkg/pharos/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDoctor(t *testing.T) {
+	tokenGenerator := test.NewGenerator()
+
+	t.Run("reports environments with more than one active cluster", func(tt *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			assert.Equal(tt, "true", r.URL.Query().Get("active"))
+			_, err := rw.Write([]byte(`[
+				{"id": "sandbox-222222", "environment": "sandbox", "active": true},
+				{"id": "production-333333", "environment": "production", "active": true},
+				{"id": "sandbox-111111", "environment": "sandbox", "active": true}
+			]`))
+			require.NoError(tt, err)
+		}))
+		defer srv.Close()
+		client := api.NewClient(&configpkg.Config{BaseURL: srv.URL}, tokenGenerator)
+
+		report, problems, err := Doctor(client)
+		assert.NoError(tt, err)
+		assert.Equal(tt, 1, problems)
+		assert.Contains(tt, report, "ENVIRONMENT sandbox HAS 2 ACTIVE CLUSTERS: sandbox-111111, sandbox-222222")
+		assert.NotContains(tt, report, "ENVIRONMENT production")
+	})
+
+	t.Run("reports no problems", func(tt *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			_, err := rw.Write([]byte(`[{"id": "sandbox-111111", "environment": "sandbox", "active": true}]`))
+			require.NoError(tt, err)
+		}))
+		defer srv.Close()
+		client := api.NewClient(&configpkg.Config{BaseURL: srv.URL}, tokenGenerator)
+
+		report, problems, err := Doctor(client)
+		assert.NoError(tt, err)
+		assert.Equal(tt, 0, problems)
+		assert.Contains(tt, report, "EVERY ENVIRONMENT HAS AT MOST ONE ACTIVE CLUSTER")
+	})
+
+	t.Run("errors when clusters can't be listed", func(tt *testing.T) {
+		client := api.NewClient(&configpkg.Config{BaseURL: ""}, tokenGenerator)
+		_, _, err := Doctor(client)
+		assert.Error(tt, err)
+		assert.Contains(tt, err.Error(), "failed to list clusters")
+	})
+}
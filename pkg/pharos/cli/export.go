@@ -0,0 +1,101 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/fatih/color"
+	"github.com/lob/pharos/pkg/pharos/api"
+	"github.com/lob/pharos/pkg/util/kubeconfig"
+	"github.com/lob/pharos/pkg/util/model"
+	"github.com/lob/pharos/pkg/util/selector"
+	"github.com/pkg/errors"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// ExportClusters writes a standalone kubeconfig containing only the requested
+// clusters to outputFile, or to stdout if outputFile is empty. Clusters are
+// requested either by a cluster ID or environment, or by a selector such as
+// "environment=sandbox,active=true".
+func ExportClusters(id string, sel string, outputFile string, client *api.Client) error {
+	var clusters []model.Cluster
+	if sel != "" {
+		s, err := selector.Parse(sel)
+		if err != nil {
+			return err
+		}
+
+		all, err := client.ListClusters(nil)
+		if err != nil {
+			return err
+		}
+		clusters = s.Filter(all)
+		if len(clusters) == 0 {
+			return fmt.Errorf("no clusters found matching selector %s", sel)
+		}
+	} else {
+		cluster, err := client.ResolveCluster(id)
+		if err != nil {
+			return err
+		}
+		clusters = []model.Cluster{cluster}
+	}
+
+	sort.Slice(clusters, func(i, j int) bool { return clusters[i].ID < clusters[j].ID })
+
+	kubeConfig, err := Naming.New(clusters)
+	if err != nil {
+		return errors.Wrap(err, "unable to name kubeconfig entries")
+	}
+
+	// When exporting a single cluster, make the name it was requested by the
+	// current context so the kubeconfig can be used without any flags.
+	if len(clusters) == 1 {
+		names, err := Naming.Names(clusters[0])
+		if err != nil {
+			return errors.Wrap(err, "unable to name kubeconfig entries")
+		}
+		kubeConfig.CurrentContext = names.Context
+
+		if sel == "" && id != names.Context {
+			kubeConfig.Contexts[id] = kubeconfig.Alias(kubeConfig.Contexts[names.Context], clusters[0])
+			kubeConfig.CurrentContext = id
+		}
+	}
+
+	// Check for errors in newly created config.
+	err = clientcmd.Validate(*kubeConfig)
+	if err != nil {
+		return errors.Wrap(err, "unable to create valid kubeconfig")
+	}
+
+	for _, cluster := range clusters {
+		if warning := caExpiryWarning(cluster); warning != "" {
+			fmt.Fprintln(os.Stderr, warning)
+		}
+	}
+
+	yaml, err := clientcmd.Write(*kubeConfig)
+	if err != nil {
+		return errors.Wrap(err, "unable to write kubeconfig")
+	}
+
+	if outputFile == "" {
+		fmt.Print(string(yaml))
+		return nil
+	}
+
+	unlock, err := lockKubeConfig(outputFile)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	err = writeFileAtomic(outputFile, yaml)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s EXPORTED %d CLUSTERS TO %s\n", color.GreenString("SUCCESS:"), len(clusters), outputFile)
+	return nil
+}
@@ -0,0 +1,137 @@
+package cli
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/lob/pharos/internal/test"
+	"github.com/lob/pharos/pkg/pharos/api"
+	configpkg "github.com/lob/pharos/pkg/pharos/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportClusters(t *testing.T) {
+	// Set up dummy server for testing.
+	getResponse := []byte(`{
+		"id":                     "sandbox-222222",
+		"environment":            "sandbox",
+		"cluster_authority_data": "LS0tLS1CRUdJTiBDR...",
+		"server_url":             "https://sandbox-222222.elb.us-west-2.amazonaws.com:6443",
+		"active":                 false
+	}`)
+	activeResponse := []byte(`{
+		"id":                     "sandbox-333333",
+		"environment":            "sandbox",
+		"cluster_authority_data": "LS0tLS1CRUdJTiBDR...",
+		"server_url":             "https://sandbox-333333.elb.us-west-2.amazonaws.com:6443",
+		"active":                 true
+	}`)
+	listResponse := []byte(`[{
+		"id":                     "sandbox-333333",
+		"environment":            "sandbox",
+		"cluster_authority_data": "LS0tLS1CRUdJTiBDR...",
+		"server_url":             "https://sandbox-333333.elb.us-west-2.amazonaws.com:6443",
+		"active":                 true
+	},{
+		"id":                     "sandbox-222222",
+		"environment":            "sandbox",
+		"cluster_authority_data": "LS0tLS1CRUdJTiBDR...",
+		"server_url":             "https://sandbox-222222.elb.us-west-2.amazonaws.com:6443",
+		"active":                 false
+	},{
+		"id":                     "staging-555555",
+		"environment":            "staging",
+		"cluster_authority_data": "LS0tLS1CRUdJTiBDR...",
+		"server_url":             "https://staging-555555.elb.us-west-2.amazonaws.com:6443",
+		"active":                 true
+	}]`)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		var response []byte
+		switch r.URL.String() {
+		case "/v1/resolve/sandbox-222222":
+			response = getResponse
+		case "/v1/resolve/sandbox":
+			response = activeResponse
+		case "/v1/clusters":
+			response = listResponse
+		}
+		_, err := rw.Write(response)
+		require.NoError(t, err)
+	}))
+	defer srv.Close()
+	tokenGenerator := test.NewGenerator()
+
+	// Set BaseURL in config to be the url of the dummy server.
+	client := api.NewClient(&configpkg.Config{BaseURL: srv.URL}, tokenGenerator)
+
+	newOutputFile := func(tt *testing.T) string {
+		f, err := ioutil.TempFile("../testdata", "export")
+		require.NoError(tt, err)
+		require.NoError(tt, f.Close())
+		require.NoError(tt, os.Remove(f.Name()))
+		return f.Name()
+	}
+
+	t.Run("successfully exports the active cluster of an environment", func(tt *testing.T) {
+		outputFile := newOutputFile(tt)
+		defer os.Remove(outputFile)
+
+		err := ExportClusters("sandbox", "", outputFile, client)
+		assert.NoError(tt, err)
+
+		kubeConfig, err := configFromFile(outputFile)
+		require.NoError(tt, err)
+		assert.Equal(tt, "sandbox", kubeConfig.CurrentContext)
+		assert.Len(tt, kubeConfig.Clusters, 1)
+		assert.Equal(tt, "sandbox-333333", kubeConfig.Contexts["sandbox"].Cluster)
+		assert.Equal(tt, "iam-sandbox-333333", kubeConfig.Contexts["sandbox-333333"].AuthInfo)
+	})
+
+	t.Run("successfully exports a cluster by id", func(tt *testing.T) {
+		outputFile := newOutputFile(tt)
+		defer os.Remove(outputFile)
+
+		err := ExportClusters("sandbox-222222", "", outputFile, client)
+		assert.NoError(tt, err)
+
+		kubeConfig, err := configFromFile(outputFile)
+		require.NoError(tt, err)
+		assert.Equal(tt, "sandbox-222222", kubeConfig.CurrentContext)
+		assert.Len(tt, kubeConfig.Clusters, 1)
+		assert.Len(tt, kubeConfig.Contexts, 1)
+	})
+
+	t.Run("successfully exports the clusters matching a selector", func(tt *testing.T) {
+		outputFile := newOutputFile(tt)
+		defer os.Remove(outputFile)
+
+		err := ExportClusters("", "environment=sandbox", outputFile, client)
+		assert.NoError(tt, err)
+
+		kubeConfig, err := configFromFile(outputFile)
+		require.NoError(tt, err)
+		assert.Equal(tt, "", kubeConfig.CurrentContext)
+		assert.Len(tt, kubeConfig.Clusters, 2)
+		assert.Contains(tt, kubeConfig.Clusters, "sandbox-222222")
+		assert.Contains(tt, kubeConfig.Clusters, "sandbox-333333")
+		assert.NotContains(tt, kubeConfig.Clusters, "staging-555555")
+		assert.Equal(tt, "sandbox-333333", kubeConfig.Contexts["sandbox"].Cluster)
+	})
+
+	t.Run("errors when no clusters match a selector", func(tt *testing.T) {
+		err := ExportClusters("", "environment=production", "", client)
+		assert.Error(tt, err)
+		assert.Contains(tt, err.Error(), "no clusters found matching selector")
+	})
+
+	t.Run("errors with an invalid selector", func(tt *testing.T) {
+		err := ExportClusters("", "region=us-west-2", "", client)
+		assert.Error(tt, err)
+		assert.Contains(tt, err.Error(), "unsupported selector key")
+	})
+}
@@ -0,0 +1,139 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/fatih/color"
+	"github.com/lob/pharos/pkg/pharos/api"
+	"github.com/lob/pharos/pkg/util/model"
+	"github.com/pkg/errors"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// ImportClusters registers the clusters referenced by the contexts of an
+// existing kubeconfig file, such as an admin kubeconfig written by kops or
+// eksctl, with Pharos. If contextName is empty, every context is imported. If
+// env is empty, the environment is inferred from the cluster ID. It returns a
+// formatted summary of the clusters that were registered and skipped.
+func ImportClusters(kubeConfigFile string, contextName string, env string, client *api.Client) (string, error) {
+	kubeConfig, err := configFromFile(kubeConfigFile)
+	if err != nil {
+		return "", errors.Wrap(err, "unable to load kubeconfig file")
+	}
+
+	contexts := make([]string, 0, len(kubeConfig.Contexts))
+	if contextName != "" {
+		if _, ok := kubeConfig.Contexts[contextName]; !ok {
+			return "", fmt.Errorf("context %s not found", contextName)
+		}
+		contexts = append(contexts, contextName)
+	} else {
+		for name := range kubeConfig.Contexts {
+			contexts = append(contexts, name)
+		}
+		sort.Strings(contexts)
+	}
+	if len(contexts) == 0 {
+		return "", errors.New("no contexts found in kubeconfig file")
+	}
+
+	buf := new(bytes.Buffer)
+	w := tabwriter.NewWriter(buf, 0, 0, 3, ' ', 0)
+	cyan := color.New(color.FgCyan)
+
+	// Add spaces to prevent ANSI escape codes from breaking the tabwriter formatting.
+	_, err = cyan.Fprint(w, "CONTEXT\t     RESULT\t     CLUSTER_ID\t     DETAILS")
+	if err != nil {
+		return "", err
+	}
+
+	// Several contexts can refer to the same cluster, which should only be
+	// registered once.
+	imported := make(map[string]string)
+	registered, skipped := 0, 0
+	for _, name := range contexts {
+		clusterName := kubeConfig.Contexts[name].Cluster
+		id := clusterID(clusterName)
+
+		newCluster, err := importCluster(kubeConfig.Clusters, clusterName, id, env)
+		if err == nil {
+			if from, ok := imported[clusterName]; ok {
+				err = fmt.Errorf("already imported from context %s", from)
+			}
+		}
+		if err == nil {
+			_, err = client.CreateCluster(newCluster, "")
+		}
+		if err != nil {
+			skipped++
+			fmt.Fprintf(w, "\n%s\tskipped\t%s\t%s", name, id, err)
+			continue
+		}
+
+		imported[clusterName] = name
+		registered++
+		fmt.Fprintf(w, "\n%s\tregistered\t%s\tenvironment %s, server %s", name, id, newCluster.Environment, newCluster.ServerURL)
+	}
+
+	fmt.Fprintln(w, "")
+	if err := w.Flush(); err != nil {
+		return "", err
+	}
+
+	fmt.Fprintf(buf, "%s REGISTERED %d AND SKIPPED %d CLUSTERS FROM %s\n", color.GreenString("SUCCESS:"), registered, skipped, kubeConfigFile)
+	return buf.String(), nil
+}
+
+// importCluster returns the cluster to register for a kubeconfig cluster entry.
+func importCluster(clusters map[string]*clientcmdapi.Cluster, name string, id string, env string) (api.Cluster, error) {
+	cluster, ok := clusters[name]
+	if !ok {
+		return api.Cluster{}, fmt.Errorf("cluster %s not found", name)
+	}
+	if cluster.Server == "" {
+		return api.Cluster{}, errors.New("cluster has no server")
+	}
+
+	authorityData := cluster.CertificateAuthorityData
+	if len(authorityData) == 0 && cluster.CertificateAuthority != "" {
+		data, err := ioutil.ReadFile(cluster.CertificateAuthority)
+		if err != nil {
+			return api.Cluster{}, errors.Wrap(err, "unable to read certificate authority")
+		}
+		authorityData = data
+	}
+	if len(authorityData) == 0 {
+		return api.Cluster{}, errors.New("cluster has no certificate authority")
+	}
+
+	if env == "" {
+		env = model.EnvironmentFromID(id)
+	}
+
+	return api.Cluster{
+		ID:                   id,
+		Environment:          env,
+		ServerURL:            cluster.Server,
+		ClusterAuthorityData: base64.StdEncoding.EncodeToString(authorityData),
+	}, nil
+}
+
+// clusterID infers a Pharos cluster ID from the name of a kubeconfig cluster
+// entry. EKS ARNs (arn:aws:eks:us-west-2:123456789012:cluster/sandbox-111111)
+// are reduced to the cluster name, and domain names such as those used by kops
+// (sandbox-111111.k8s.local) or eksctl (sandbox-111111.us-west-2.eksctl.io) to
+// their first label.
+func clusterID(name string) string {
+	if strings.HasPrefix(name, "arn:") {
+		if i := strings.LastIndex(name, "/"); i >= 0 {
+			return name[i+1:]
+		}
+	}
+	return strings.SplitN(name, ".", 2)[0]
+}
@@ -0,0 +1,80 @@
+package cli
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lob/pharos/internal/test"
+	"github.com/lob/pharos/pkg/pharos/api"
+	configpkg "github.com/lob/pharos/pkg/pharos/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const importConfig = "../testdata/import"
+
+func TestImportClusters(t *testing.T) {
+	// Set up dummy server for testing that records the clusters it was asked to create.
+	var created []api.Cluster
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		var cluster api.Cluster
+		err := json.NewDecoder(r.Body).Decode(&cluster)
+		require.NoError(t, err)
+		created = append(created, cluster)
+
+		_, err = rw.Write([]byte(`{"id": "` + cluster.ID + `"}`))
+		require.NoError(t, err)
+	}))
+	defer srv.Close()
+	tokenGenerator := test.NewGenerator()
+
+	// Set BaseURL in config to be the url of the dummy server.
+	client := api.NewClient(&configpkg.Config{BaseURL: srv.URL}, tokenGenerator)
+
+	t.Run("successfully imports every context", func(tt *testing.T) {
+		created = nil
+
+		summary, err := ImportClusters(importConfig, "", "", client)
+		assert.NoError(tt, err)
+
+		require.Len(tt, created, 2)
+		assert.Equal(tt, api.Cluster{
+			ID:                   "sandbox-111111",
+			Environment:          "sandbox",
+			ServerURL:            "https://sandbox.us-west-2.eks.amazonaws.com",
+			ClusterAuthorityData: "dGVzdA==",
+		}, created[0])
+		assert.Equal(tt, "production-222222", created[1].ID)
+		assert.Equal(tt, "production", created[1].Environment)
+
+		assert.Regexp(tt, `admin@sandbox-111111.us-west-2.eksctl.io\s+registered\s+sandbox-111111`, summary)
+		assert.Regexp(tt, `production\s+skipped\s+production-222222\s+already imported`, summary)
+		assert.Regexp(tt, `staging\s+skipped\s+staging-333333\s+cluster has no certificate authority`, summary)
+		assert.Regexp(tt, `ghost\s+skipped\s+ghost-444444\s+cluster ghost-444444 not found`, summary)
+		assert.Contains(tt, summary, "REGISTERED 2 AND SKIPPED 3 CLUSTERS")
+	})
+
+	t.Run("successfully imports a single context into the given environment", func(tt *testing.T) {
+		created = nil
+
+		_, err := ImportClusters(importConfig, "production", "prod", client)
+		assert.NoError(tt, err)
+		require.Len(tt, created, 1)
+		assert.Equal(tt, "production-222222", created[0].ID)
+		assert.Equal(tt, "prod", created[0].Environment)
+	})
+
+	t.Run("errors when the context does not exist", func(tt *testing.T) {
+		_, err := ImportClusters(importConfig, "nonexistent", "", client)
+		assert.Error(tt, err)
+		assert.Contains(tt, err.Error(), "context nonexistent not found")
+	})
+
+	t.Run("errors when reading from malformed config file", func(tt *testing.T) {
+		_, err := ImportClusters(malformedConfig, "", "", client)
+		assert.Error(tt, err)
+		assert.Contains(tt, err.Error(), "unable to load kubeconfig file")
+	})
+}
@@ -1,13 +1,40 @@
 package cli
 
 import (
-	"encoding/base64"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 
-	"github.com/lob/pharos/pkg/util/model"
+	"github.com/lob/pharos/pkg/util/kubeconfig"
 	"k8s.io/client-go/tools/clientcmd"
 	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 )
 
+// DefaultManagedKubeConfig is the kubeconfig file that Pharos writes to when
+// it is set up to keep its entries apart from the rest of the kubeconfig.
+var DefaultManagedKubeConfig = filepath.Join(os.Getenv("HOME"), ".kube", "pharos", "kubeconfig.yaml")
+
+// ManagedKubeConfig is the kubeconfig file that new clusters, users and
+// contexts are written to when the kubeconfig files come from $KUBECONFIG. If
+// it is empty they are written to the first file, as kubectl does.
+var ManagedKubeConfig string
+
+// Naming names the kubeconfig entries written by Pharos.
+var Naming = kubeconfig.DefaultNaming
+
+// kubeConfigFiles is a kubeconfig that may be split over several files. It is
+// loaded with the same precedence rules as kubectl: the first file to define an
+// entry or a current context wins.
+type kubeConfigFiles struct {
+	paths   []string
+	configs map[string]*clientcmdapi.Config
+
+	// dest is the file that new entries are written to.
+	dest   string
+	unlock func()
+}
+
 // configFromFile returns a struct containing kubeconfig information from a file.
 // Does not differentiate between errors resulting from a missing file and errors
 // from reading from a malformed config.
@@ -20,43 +47,202 @@ func configFromFile(fileName string) (*clientcmdapi.Config, error) {
 	return kubeConfig, nil
 }
 
-// newContext returns a pointer to a new kubeconfig context with specified cluster and user.
-func newContext(id string, user string) *clientcmdapi.Context {
-	context := clientcmdapi.NewContext()
-	context.Cluster = id
-	context.AuthInfo = user
+// kubeConfigPaths returns the kubeconfig files to load, in order of precedence.
+// A kubeconfig file given explicitly is used on its own. Otherwise the files
+// listed in $KUBECONFIG, or $HOME/.kube/config if it is unset, are used,
+// followed by ManagedKubeConfig.
+func kubeConfigPaths(kubeConfigFile string) []string {
+	if kubeConfigFile != "" {
+		return []string{kubeConfigFile}
+	}
 
-	return context
+	paths := clientcmd.NewDefaultClientConfigLoadingRules().GetLoadingPrecedence()
+	if ManagedKubeConfig != "" {
+		for _, path := range paths {
+			if path == ManagedKubeConfig {
+				return paths
+			}
+		}
+		paths = append(paths, ManagedKubeConfig)
+	}
+	return paths
 }
 
-// newUser returns a pointer to a new kubeconfig user for a specified cluster.
-// The id given should always be of form "[environment]-[suffix]".
-func newUser(id string, environment string) *clientcmdapi.AuthInfo {
-	user := clientcmdapi.NewAuthInfo()
+// ResolveKubeConfigFile returns the given kubeconfig file, or the file that new
+// entries are written to if it is empty.
+func ResolveKubeConfigFile(kubeConfigFile string) string {
+	if kubeConfigFile == "" && ManagedKubeConfig != "" {
+		return ManagedKubeConfig
+	}
+	return kubeConfigPaths(kubeConfigFile)[0]
+}
 
-	// Add exec config.
-	var exec clientcmdapi.ExecConfig
-	exec.Command = "aws-iam-authenticator"
-	exec.APIVersion = "client.authentication.k8s.io/v1alpha1"
-	exec.Args = []string{"token", "-i", id}
-	user.Exec = &exec
+// loadKubeConfigs loads the kubeconfig files resolved by kubeConfigPaths.
+// Missing files are treated as empty, unless required is set and the file was
+// given explicitly. If lock is set, every file is locked until Close is called
+// so that the kubeconfig can be safely modified and written back.
+func loadKubeConfigs(kubeConfigFile string, lock bool, required bool) (*kubeConfigFiles, error) {
+	k := &kubeConfigFiles{
+		paths:   kubeConfigPaths(kubeConfigFile),
+		configs: make(map[string]*clientcmdapi.Config),
+		dest:    ResolveKubeConfigFile(kubeConfigFile),
+		unlock:  func() {},
+	}
 
-	// Add env variables to exec config.
-	var env clientcmdapi.ExecEnvVar
-	env.Name = "AWS_PROFILE"
-	env.Value = environment
-	exec.Env = []clientcmdapi.ExecEnvVar{env}
+	if lock {
+		// Always lock files in the same order so that concurrent commands can't
+		// deadlock.
+		sorted := append([]string{}, k.paths...)
+		sort.Strings(sorted)
 
-	return user
+		unlocks := make([]func(), 0, len(sorted))
+		k.unlock = func() {
+			for _, unlock := range unlocks {
+				unlock()
+			}
+		}
+		for _, path := range sorted {
+			unlock, err := lockKubeConfig(path)
+			if err != nil {
+				k.Close()
+				return nil, err
+			}
+			unlocks = append(unlocks, unlock)
+		}
+	}
+
+	for _, path := range k.paths {
+		kubeConfig, err := configFromFile(path)
+		if os.IsNotExist(err) && !(required && kubeConfigFile != "") {
+			kubeConfig = clientcmdapi.NewConfig()
+		} else if err != nil {
+			k.Close()
+			return nil, err
+		}
+		k.configs[path] = kubeConfig
+	}
+
+	return k, nil
 }
 
-// newCluster returns a pointer to a new clientcmdapi.Cluster containing
-// information from a cluster.
-func newCluster(c model.Cluster) *clientcmdapi.Cluster {
-	clusterAuthorityData, _ := base64.StdEncoding.DecodeString(c.ClusterAuthorityData)
+// Close releases the locks on the kubeconfig files.
+func (k *kubeConfigFiles) Close() {
+	k.unlock()
+}
+
+// String returns the kubeconfig files in the same form as $KUBECONFIG.
+func (k *kubeConfigFiles) String() string {
+	return strings.Join(k.paths, string(os.PathListSeparator))
+}
 
-	cluster := clientcmdapi.NewCluster()
-	cluster.Server = c.ServerURL
-	cluster.CertificateAuthorityData = clusterAuthorityData
-	return cluster
+// Merged returns a copy of the merged kubeconfig.
+func (k *kubeConfigFiles) Merged() *clientcmdapi.Config {
+	merged := clientcmdapi.NewConfig()
+	for _, path := range k.paths {
+		kubeConfig := k.configs[path]
+		if merged.CurrentContext == "" {
+			merged.CurrentContext = kubeConfig.CurrentContext
+		}
+		for name, cluster := range kubeConfig.Clusters {
+			if _, ok := merged.Clusters[name]; !ok {
+				merged.Clusters[name] = cluster.DeepCopy()
+			}
+		}
+		for name, user := range kubeConfig.AuthInfos {
+			if _, ok := merged.AuthInfos[name]; !ok {
+				merged.AuthInfos[name] = user.DeepCopy()
+			}
+		}
+		for name, context := range kubeConfig.Contexts {
+			if _, ok := merged.Contexts[name]; !ok {
+				merged.Contexts[name] = context.DeepCopy()
+			}
+		}
+	}
+	return merged
+}
+
+// Write writes the changes between the merged kubeconfig and the given one
+// back to the kubeconfig files. Changed and removed entries are written to the
+// file that defines them, and new entries to the destination file. It returns
+// the files that were written, or the destination file if nothing changed.
+func (k *kubeConfigFiles) Write(kubeConfig *clientcmdapi.Config) ([]string, error) {
+	diff, err := DiffConfigs(k.Merged(), kubeConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	changed := make(map[string]bool)
+
+	for _, name := range append(diff.Clusters.Added, diff.Clusters.Changed...) {
+		path := k.owner(func(c *clientcmdapi.Config) bool { _, ok := c.Clusters[name]; return ok })
+		k.configs[path].Clusters[name] = kubeConfig.Clusters[name]
+		changed[path] = true
+	}
+	for _, name := range diff.Clusters.Removed {
+		path := k.owner(func(c *clientcmdapi.Config) bool { _, ok := c.Clusters[name]; return ok })
+		delete(k.configs[path].Clusters, name)
+		changed[path] = true
+	}
+
+	for _, name := range append(diff.Users.Added, diff.Users.Changed...) {
+		path := k.owner(func(c *clientcmdapi.Config) bool { _, ok := c.AuthInfos[name]; return ok })
+		k.configs[path].AuthInfos[name] = kubeConfig.AuthInfos[name]
+		changed[path] = true
+	}
+	for _, name := range diff.Users.Removed {
+		path := k.owner(func(c *clientcmdapi.Config) bool { _, ok := c.AuthInfos[name]; return ok })
+		delete(k.configs[path].AuthInfos, name)
+		changed[path] = true
+	}
+
+	for _, name := range append(diff.Contexts.Added, diff.Contexts.Changed...) {
+		path := k.owner(func(c *clientcmdapi.Config) bool { _, ok := c.Contexts[name]; return ok })
+		k.configs[path].Contexts[name] = kubeConfig.Contexts[name]
+		changed[path] = true
+	}
+	for _, name := range diff.Contexts.Removed {
+		path := k.owner(func(c *clientcmdapi.Config) bool { _, ok := c.Contexts[name]; return ok })
+		delete(k.configs[path].Contexts, name)
+		changed[path] = true
+	}
+
+	if diff.CurrentContext != nil {
+		path := k.owner(func(c *clientcmdapi.Config) bool { return c.CurrentContext != "" })
+		k.configs[path].CurrentContext = kubeConfig.CurrentContext
+		changed[path] = true
+	}
+
+	written := make([]string, 0, len(changed))
+	for _, path := range k.paths {
+		if !changed[path] {
+			continue
+		}
+		if err := writeKubeConfig(*k.configs[path], path); err != nil {
+			return nil, err
+		}
+		written = append(written, path)
+	}
+
+	if len(written) == 0 {
+		return []string{k.dest}, nil
+	}
+	return written, nil
+}
+
+// Overwrite replaces the destination file with the given kubeconfig.
+func (k *kubeConfigFiles) Overwrite(kubeConfig *clientcmdapi.Config) error {
+	k.configs[k.dest] = kubeConfig
+	return writeKubeConfig(*kubeConfig, k.dest)
+}
+
+// owner returns the first file for which defines returns true, or the
+// destination file if there is none.
+func (k *kubeConfigFiles) owner(defines func(*clientcmdapi.Config) bool) string {
+	for _, path := range k.paths {
+		if defines(k.configs[path]) {
+			return path
+		}
+	}
+	return k.dest
 }
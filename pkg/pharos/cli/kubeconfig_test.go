@@ -1,29 +1,123 @@
 package cli
 
 import (
-	"reflect"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/lob/pharos/pkg/util/kubeconfig"
+	"github.com/lob/pharos/pkg/util/model"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/tools/clientcmd"
 	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 )
 
-func TestConfigFromFile(t *testing.T) {
-	t.Run("successfully loads from config file", func(tt *testing.T) {
-		kubeConfig, err := configFromFile(config)
-		assert.NoError(tt, err)
-		assert.NotNil(tt, kubeConfig)
+func TestKubeConfigFiles(t *testing.T) {
+	originalKubeConfig, originalManaged := os.Getenv("KUBECONFIG"), ManagedKubeConfig
+	defer func() {
+		os.Setenv("KUBECONFIG", originalKubeConfig)
+		ManagedKubeConfig = originalManaged
+	}()
+
+	// setup writes a kubeconfig with a sandbox cluster and a current context,
+	// and one with a production cluster, and lists both in $KUBECONFIG.
+	setup := func(tt *testing.T) (string, string, string) {
+		dir, err := ioutil.TempDir("", "pharos-kubeconfigs")
+		require.NoError(tt, err)
+
+		first := clientcmdapi.NewConfig()
+		_, err = kubeconfig.DefaultNaming.AddCluster(first, model.Cluster{ID: "sandbox-111111", Environment: "sandbox", ServerURL: "https://sandbox.com"})
+		require.NoError(tt, err)
+		first.CurrentContext = "sandbox-111111"
+		second := clientcmdapi.NewConfig()
+		_, err = kubeconfig.DefaultNaming.AddCluster(second, model.Cluster{ID: "production-222222", Environment: "production", ServerURL: "https://production.com"})
+		require.NoError(tt, err)
+
+		firstFile, secondFile := filepath.Join(dir, "first"), filepath.Join(dir, "second")
+		require.NoError(tt, clientcmd.WriteToFile(*first, firstFile))
+		require.NoError(tt, clientcmd.WriteToFile(*second, secondFile))
+
+		os.Setenv("KUBECONFIG", strings.Join([]string{firstFile, secondFile}, string(os.PathListSeparator)))
+		ManagedKubeConfig = ""
+		return dir, firstFile, secondFile
+	}
+
+	t.Run("uses an explicit kubeconfig file on its own", func(tt *testing.T) {
+		dir, _, _ := setup(tt)
+		defer os.RemoveAll(dir)
+
+		assert.Equal(tt, []string{config}, kubeConfigPaths(config))
+		assert.Equal(tt, config, ResolveKubeConfigFile(config))
+	})
+
+	t.Run("reads the merged view of $KUBECONFIG", func(tt *testing.T) {
+		dir, _, _ := setup(tt)
+		defer os.RemoveAll(dir)
+
+		current, err := CurrentCluster("")
+		require.NoError(tt, err)
+		assert.Equal(tt, "sandbox-111111", current)
+	})
+
+	t.Run("writes the current context to the file that sets it", func(tt *testing.T) {
+		dir, firstFile, secondFile := setup(tt)
+		defer os.RemoveAll(dir)
+
+		err := SwitchCluster("", "production-222222")
+		require.NoError(tt, err)
+
+		first, err := configFromFile(firstFile)
+		require.NoError(tt, err)
+		assert.Equal(tt, "production-222222", first.CurrentContext)
+
+		second, err := configFromFile(secondFile)
+		require.NoError(tt, err)
+		assert.Equal(tt, "", second.CurrentContext)
+		assert.NotContains(tt, first.Contexts, "production-222222")
 	})
 
-	t.Run("returns empty kubeconfig struct and no error when loading from empty config file", func(tt *testing.T) {
-		kubeConfig, err := configFromFile(emptyConfig)
-		assert.NoError(tt, err)
-		assert.True(tt, reflect.DeepEqual(kubeConfig, clientcmdapi.NewConfig()))
+	t.Run("writes changed entries to the file that owns them and new entries to the managed file", func(tt *testing.T) {
+		dir, firstFile, secondFile := setup(tt)
+		defer os.RemoveAll(dir)
+		ManagedKubeConfig = filepath.Join(dir, "managed")
+
+		kubeConfigs, err := loadKubeConfigs("", true, false)
+		require.NoError(tt, err)
+		defer kubeConfigs.Close()
+		assert.Equal(tt, []string{firstFile, secondFile, ManagedKubeConfig}, kubeConfigs.paths)
+
+		kubeConfig := kubeConfigs.Merged()
+		_, err = kubeconfig.DefaultNaming.AddCluster(kubeConfig, model.Cluster{ID: "production-222222", Environment: "production", ServerURL: "https://changed.com"})
+		require.NoError(tt, err)
+		_, err = kubeconfig.DefaultNaming.AddCluster(kubeConfig, model.Cluster{ID: "staging-333333", Environment: "staging", ServerURL: "https://staging.com"})
+		require.NoError(tt, err)
+
+		written, err := kubeConfigs.Write(kubeConfig)
+		require.NoError(tt, err)
+		assert.Equal(tt, []string{secondFile, ManagedKubeConfig}, written)
+
+		second, err := configFromFile(secondFile)
+		require.NoError(tt, err)
+		assert.Equal(tt, "https://changed.com", second.Clusters["production-222222"].Server)
+		assert.NotContains(tt, second.Clusters, "staging-333333")
+
+		managed, err := configFromFile(ManagedKubeConfig)
+		require.NoError(tt, err)
+		assert.Contains(tt, managed.Clusters, "staging-333333")
+		assert.Contains(tt, managed.AuthInfos, "iam-staging-333333")
+		assert.Contains(tt, managed.Contexts, "staging-333333")
 	})
 
-	t.Run("returns nil and error when loading from nonexistent file", func(tt *testing.T) {
-		kubeConfig, err := configFromFile(nonExistentConfig)
+	t.Run("errors when a file in $KUBECONFIG is malformed", func(tt *testing.T) {
+		dir, firstFile, _ := setup(tt)
+		defer os.RemoveAll(dir)
+		os.Setenv("KUBECONFIG", strings.Join([]string{firstFile, malformedConfig}, string(os.PathListSeparator)))
+
+		_, err := CurrentCluster("")
 		assert.Error(tt, err)
-		assert.Nil(tt, kubeConfig)
+		assert.Contains(tt, err.Error(), "illegal base64 data at input byte 1")
 	})
 }
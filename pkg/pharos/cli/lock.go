@@ -0,0 +1,116 @@
+package cli
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// lockTimeout is how long to wait for another process to release a kubeconfig
+// file lock, and lockRetryInterval is how often the lock is retried meanwhile.
+var (
+	lockTimeout       = 10 * time.Second
+	lockRetryInterval = 100 * time.Millisecond
+)
+
+// lockKubeConfig takes an advisory lock on a kubeconfig file by creating
+// "<file>.lock", the same lock file that client-go and kubectl use. It waits up
+// to lockTimeout for the lock to be released and returns a function that
+// releases it. Callers must hold the lock from loading the file until it has
+// been written so that concurrent updates are never lost.
+func lockKubeConfig(kubeConfigFile string) (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(kubeConfigFile), 0755); err != nil {
+		return nil, errors.Wrap(err, "unable to create kubeconfig directory")
+	}
+
+	lockFile := kubeConfigFile + ".lock"
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		f, err := os.OpenFile(lockFile, os.O_CREATE|os.O_EXCL, 0600)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(lockFile) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, errors.Wrap(err, "unable to lock kubeconfig file")
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out waiting for lock on %s, remove %s if no other process is using it", kubeConfigFile, lockFile)
+		}
+		time.Sleep(lockRetryInterval)
+	}
+}
+
+// writeFileAtomic replaces a file with the given contents by writing them to a
+// temporary file in the same directory, syncing it to disk and renaming it over
+// the original, so that readers never see a partially written file. Symlinks
+// are followed, so that the file they point to is replaced rather than the
+// link, and the permissions of an existing file are preserved.
+func writeFileAtomic(file string, raw []byte) error {
+	file, err := resolveSymlinks(file)
+	if err != nil {
+		return errors.Wrap(err, "unable to resolve kubeconfig file")
+	}
+
+	mode := os.FileMode(0600)
+	if info, err := os.Stat(file); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	dir := filepath.Dir(file)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return errors.Wrap(err, "unable to create kubeconfig directory")
+	}
+
+	tmp, err := ioutil.TempFile(dir, filepath.Base(file)+".tmp-")
+	if err != nil {
+		return errors.Wrap(err, "unable to create temporary kubeconfig file")
+	}
+	// Removing the temporary file fails harmlessly once it has been renamed.
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(raw); err != nil {
+		tmp.Close()
+		return errors.Wrap(err, "unable to write temporary kubeconfig file")
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return errors.Wrap(err, "unable to set kubeconfig file permissions")
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return errors.Wrap(err, "unable to sync temporary kubeconfig file")
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.Wrap(err, "unable to close temporary kubeconfig file")
+	}
+
+	return errors.Wrap(os.Rename(tmp.Name(), file), "unable to replace kubeconfig file")
+}
+
+// resolveSymlinks returns the path a file resolves to once every symlink in it
+// is followed. Files that don't exist yet, including ones that a dangling
+// symlink points to, resolve to where they would be created.
+func resolveSymlinks(file string) (string, error) {
+	resolved, err := filepath.EvalSymlinks(file)
+	if err == nil {
+		return resolved, nil
+	}
+	if !os.IsNotExist(err) {
+		return "", err
+	}
+
+	target, err := os.Readlink(file)
+	if err != nil {
+		// The file doesn't exist and isn't a symlink.
+		return file, nil
+	}
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(filepath.Dir(file), target)
+	}
+	return resolveSymlinks(target)
+}
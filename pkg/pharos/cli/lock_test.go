@@ -0,0 +1,148 @@
+package cli
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lob/pharos/internal/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLockKubeConfig(t *testing.T) {
+	originalTimeout := lockTimeout
+	defer func() { lockTimeout = originalTimeout }()
+	lockTimeout = 500 * time.Millisecond
+
+	t.Run("creates and removes the client-go lock file", func(tt *testing.T) {
+		configFile := test.CopyTestFile(tt, "../testdata", "lock", config)
+		defer os.Remove(configFile)
+
+		unlock, err := lockKubeConfig(configFile)
+		require.NoError(tt, err)
+		assert.FileExists(tt, configFile+".lock")
+
+		unlock()
+		_, err = os.Stat(configFile + ".lock")
+		assert.True(tt, os.IsNotExist(err))
+	})
+
+	t.Run("errors when the lock is not released in time", func(tt *testing.T) {
+		configFile := test.CopyTestFile(tt, "../testdata", "lock", config)
+		defer os.Remove(configFile)
+
+		unlock, err := lockKubeConfig(configFile)
+		require.NoError(tt, err)
+		defer unlock()
+
+		err = SwitchCluster(configFile, "sandbox-a61631")
+		assert.Error(tt, err)
+		assert.Contains(tt, err.Error(), "timed out waiting for lock")
+	})
+
+	t.Run("waits for the lock to be released", func(tt *testing.T) {
+		configFile := test.CopyTestFile(tt, "../testdata", "lock", config)
+		defer os.Remove(configFile)
+
+		unlock, err := lockKubeConfig(configFile)
+		require.NoError(tt, err)
+
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(tt, SwitchCluster(configFile, "sandbox-a61631"))
+		}()
+
+		time.Sleep(2 * lockRetryInterval)
+		unlock()
+		wg.Wait()
+
+		current, err := CurrentCluster(configFile)
+		require.NoError(tt, err)
+		assert.Equal(tt, "sandbox-a61631", current)
+	})
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pharos-write")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	t.Run("creates new files that only the owner can read", func(tt *testing.T) {
+		file := filepath.Join(dir, "new", "config")
+
+		err := writeFileAtomic(file, []byte("new"))
+		require.NoError(tt, err)
+
+		raw, err := ioutil.ReadFile(file)
+		require.NoError(tt, err)
+		assert.Equal(tt, "new", string(raw))
+
+		info, err := os.Stat(file)
+		require.NoError(tt, err)
+		assert.Equal(tt, os.FileMode(0600), info.Mode().Perm())
+	})
+
+	t.Run("preserves the permissions of existing files", func(tt *testing.T) {
+		file := filepath.Join(dir, "existing")
+		require.NoError(tt, ioutil.WriteFile(file, []byte("old"), 0640))
+		require.NoError(tt, os.Chmod(file, 0640))
+
+		err := writeFileAtomic(file, []byte("new"))
+		require.NoError(tt, err)
+
+		raw, err := ioutil.ReadFile(file)
+		require.NoError(tt, err)
+		assert.Equal(tt, "new", string(raw))
+
+		info, err := os.Stat(file)
+		require.NoError(tt, err)
+		assert.Equal(tt, os.FileMode(0640), info.Mode().Perm())
+
+		// No temporary files are left behind.
+		files, err := ioutil.ReadDir(dir)
+		require.NoError(tt, err)
+		assert.Len(tt, files, 2)
+	})
+	t.Run("writes through symlinks to the file they point to", func(tt *testing.T) {
+		target := filepath.Join(dir, "dotfiles", "config")
+		require.NoError(tt, os.MkdirAll(filepath.Dir(target), 0755))
+		require.NoError(tt, ioutil.WriteFile(target, []byte("old"), 0644))
+		require.NoError(tt, os.Chmod(target, 0644))
+		link := filepath.Join(dir, "link")
+		require.NoError(tt, os.Symlink(filepath.Join("dotfiles", "config"), link))
+
+		err := writeFileAtomic(link, []byte("new"))
+		require.NoError(tt, err)
+
+		info, err := os.Lstat(link)
+		require.NoError(tt, err)
+		assert.True(tt, info.Mode()&os.ModeSymlink != 0, "symlink was replaced")
+
+		raw, err := ioutil.ReadFile(target)
+		require.NoError(tt, err)
+		assert.Equal(tt, "new", string(raw))
+
+		info, err = os.Stat(target)
+		require.NoError(tt, err)
+		assert.Equal(tt, os.FileMode(0644), info.Mode().Perm())
+	})
+
+	t.Run("creates the file a dangling symlink points to", func(tt *testing.T) {
+		target := filepath.Join(dir, "dotfiles", "missing")
+		link := filepath.Join(dir, "dangling")
+		require.NoError(tt, os.Symlink(target, link))
+
+		err := writeFileAtomic(link, []byte("new"))
+		require.NoError(tt, err)
+
+		raw, err := ioutil.ReadFile(target)
+		require.NoError(tt, err)
+		assert.Equal(tt, "new", string(raw))
+	})
+}
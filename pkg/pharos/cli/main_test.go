@@ -0,0 +1,21 @@
+package cli
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+// TestMain keeps kubeconfig backups taken during tests out of the home
+// directory of whoever runs them.
+func TestMain(m *testing.M) {
+	dir, err := ioutil.TempDir("", "pharos-backups")
+	if err != nil {
+		panic(err)
+	}
+	BackupDir = dir
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
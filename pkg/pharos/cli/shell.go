@@ -0,0 +1,70 @@
+package cli
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// shellIntegrationMarker marks the shell integration snippet so that it is
+// only installed once.
+const shellIntegrationMarker = "# pharos shell integration"
+
+// ShellIntegration returns the snippet that appends the Pharos-managed
+// kubeconfig file to $KUBECONFIG in the given shell, and the startup file of
+// that shell that it can be installed in. The file is only appended if
+// $KUBECONFIG doesn't already contain it, so that nested shells don't list it
+// again. bash, zsh and fish are supported.
+func ShellIntegration(shell string, managedKubeConfig string) (string, string, error) {
+	home := os.Getenv("HOME")
+
+	switch filepath.Base(shell) {
+	case "bash":
+		return posixShellIntegration(managedKubeConfig), filepath.Join(home, ".bashrc"), nil
+	case "zsh":
+		return posixShellIntegration(managedKubeConfig), filepath.Join(home, ".zshrc"), nil
+	case "fish":
+		snippet := fmt.Sprintf("%s\nset -q KUBECONFIG; or set -gx KUBECONFIG $HOME/.kube/config\ncontains -- %s (string split : -- $KUBECONFIG); or set -gx KUBECONFIG \"$KUBECONFIG:%s\"\n", shellIntegrationMarker, managedKubeConfig, managedKubeConfig)
+		return snippet, filepath.Join(home, ".config", "fish", "config.fish"), nil
+	default:
+		return "", "", fmt.Errorf("unsupported shell %q, must be bash, zsh or fish", shell)
+	}
+}
+
+func posixShellIntegration(managedKubeConfig string) string {
+	return fmt.Sprintf("%s\ncase \":${KUBECONFIG:-$HOME/.kube/config}:\" in\n  *\":%s:\"*) ;;\n  *) export KUBECONFIG=\"${KUBECONFIG:-$HOME/.kube/config}:%s\" ;;\nesac\n", shellIntegrationMarker, managedKubeConfig, managedKubeConfig)
+}
+
+// InstallShellIntegration appends a shell integration snippet to a shell
+// startup file, unless it has already been installed there. It reports whether
+// the snippet was installed.
+func InstallShellIntegration(snippet string, startupFile string) (bool, error) {
+	raw, err := ioutil.ReadFile(startupFile)
+	if err != nil && !os.IsNotExist(err) {
+		return false, errors.Wrap(err, "unable to read shell startup file")
+	}
+	if strings.Contains(string(raw), shellIntegrationMarker) {
+		return false, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(startupFile), 0755); err != nil {
+		return false, errors.Wrap(err, "unable to create shell startup file directory")
+	}
+	f, err := os.OpenFile(startupFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return false, errors.Wrap(err, "unable to open shell startup file")
+	}
+	defer f.Close()
+
+	if len(raw) > 0 && !strings.HasSuffix(string(raw), "\n") {
+		snippet = "\n" + snippet
+	}
+	if _, err := f.WriteString("\n" + snippet); err != nil {
+		return false, errors.Wrap(err, "unable to write shell startup file")
+	}
+	return true, nil
+}
@@ -0,0 +1,80 @@
+package cli
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShellIntegration(t *testing.T) {
+	t.Run("appends the managed kubeconfig to KUBECONFIG", func(tt *testing.T) {
+		snippet, startupFile, err := ShellIntegration("/bin/zsh", "/home/pharos/.kube/pharos/kubeconfig.yaml")
+		require.NoError(tt, err)
+		assert.Contains(tt, snippet, `export KUBECONFIG="${KUBECONFIG:-$HOME/.kube/config}:/home/pharos/.kube/pharos/kubeconfig.yaml"`)
+		assert.Equal(tt, ".zshrc", filepath.Base(startupFile))
+
+		snippet, startupFile, err = ShellIntegration("fish", "/home/pharos/.kube/pharos/kubeconfig.yaml")
+		require.NoError(tt, err)
+		assert.Contains(tt, snippet, `contains -- /home/pharos/.kube/pharos/kubeconfig.yaml (string split : -- $KUBECONFIG); or set -gx KUBECONFIG "$KUBECONFIG:/home/pharos/.kube/pharos/kubeconfig.yaml"`)
+		assert.Equal(tt, "config.fish", filepath.Base(startupFile))
+	})
+
+	t.Run("appends the managed kubeconfig only once in nested shells", func(tt *testing.T) {
+		sh, err := exec.LookPath("sh")
+		if err != nil {
+			tt.Skip("sh isn't installed")
+		}
+
+		snippet, _, err := ShellIntegration("bash", "/home/pharos/.kube/pharos/kubeconfig.yaml")
+		require.NoError(tt, err)
+
+		cmd := exec.Command(sh, "-c", snippet+snippet+`printf %s "$KUBECONFIG"`)
+		cmd.Env = []string{"HOME=/home/pharos", "KUBECONFIG=/home/pharos/.kube/config:/home/pharos/.kube/other"}
+		out, err := cmd.Output()
+		require.NoError(tt, err)
+		assert.Equal(tt, "/home/pharos/.kube/config:/home/pharos/.kube/other:/home/pharos/.kube/pharos/kubeconfig.yaml", string(out))
+
+		cmd = exec.Command(sh, "-c", snippet+snippet+`printf %s "$KUBECONFIG"`)
+		cmd.Env = []string{"HOME=/home/pharos"}
+		out, err = cmd.Output()
+		require.NoError(tt, err)
+		assert.Equal(tt, "/home/pharos/.kube/config:/home/pharos/.kube/pharos/kubeconfig.yaml", string(out))
+	})
+
+	t.Run("errors on unsupported shells", func(tt *testing.T) {
+		_, _, err := ShellIntegration("/bin/tcsh", "kubeconfig.yaml")
+		assert.Error(tt, err)
+		assert.Contains(tt, err.Error(), "unsupported shell")
+	})
+
+	t.Run("installs the snippet only once", func(tt *testing.T) {
+		dir, err := ioutil.TempDir("", "pharos-shell")
+		require.NoError(tt, err)
+		defer os.RemoveAll(dir)
+
+		startupFile := filepath.Join(dir, ".bashrc")
+		require.NoError(tt, ioutil.WriteFile(startupFile, []byte("alias k=kubectl"), 0644))
+
+		snippet, _, err := ShellIntegration("bash", "kubeconfig.yaml")
+		require.NoError(tt, err)
+
+		installed, err := InstallShellIntegration(snippet, startupFile)
+		require.NoError(tt, err)
+		assert.True(tt, installed)
+
+		installed, err = InstallShellIntegration(snippet, startupFile)
+		require.NoError(tt, err)
+		assert.False(tt, installed)
+
+		raw, err := ioutil.ReadFile(startupFile)
+		require.NoError(tt, err)
+		assert.True(tt, strings.HasPrefix(string(raw), "alias k=kubectl\n"))
+		assert.Equal(tt, 1, strings.Count(string(raw), shellIntegrationMarker))
+	})
+}
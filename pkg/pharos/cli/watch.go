@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/lob/pharos/pkg/pharos/api"
+	"github.com/lob/pharos/pkg/util/model"
+)
+
+// WatchTimeout is how long the Pharos API waits for clusters to change before
+// responding to a watch.
+var WatchTimeout = 30 * time.Second
+
+// watchRetryInterval is how long to wait before watching again after a failed
+// watch or sync.
+var watchRetryInterval = 5 * time.Second
+
+type watchResult struct {
+	clusters []model.Cluster
+	err      error
+}
+
+// WatchClusters keeps a kubeconfig file synced with the clusters in Pharos
+// until stop is closed. The clusters are synced as by SyncClusters when the
+// watch starts and whenever any of them is created, changed or deleted.
+// Failures are reported as warnings and retried, so that the watch can run
+// unattended.
+func WatchClusters(kubeConfigFile string, inactive bool, overwrite bool, prune bool, client *api.Client, stop <-chan struct{}) error {
+	var version int64
+	for {
+		// Watch in the background so that stopping doesn't wait for the Pharos
+		// API to respond.
+		results := make(chan watchResult, 1)
+		go func(since int64) {
+			clusters, err := client.WatchClusters(since, WatchTimeout)
+			results <- watchResult{clusters, err}
+		}(version)
+
+		var result watchResult
+		select {
+		case <-stop:
+			return nil
+		case result = <-results:
+		}
+
+		err := result.err
+		if err == nil && len(result.clusters) > 0 {
+			err = SyncClusters(kubeConfigFile, inactive, false, "", overwrite, prune, client)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s %s\n", color.YellowString("WARNING:"), err)
+			select {
+			case <-stop:
+				return nil
+			case <-time.After(watchRetryInterval):
+			}
+			continue
+		}
+
+		for _, cluster := range result.clusters {
+			if cluster.ResourceVersion > version {
+				version = cluster.ResourceVersion
+			}
+		}
+	}
+}
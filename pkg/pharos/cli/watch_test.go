@@ -0,0 +1,92 @@
+package cli
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lob/pharos/internal/test"
+	"github.com/lob/pharos/pkg/pharos/api"
+	configpkg "github.com/lob/pharos/pkg/pharos/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatchClusters(t *testing.T) {
+	watchRetryInterval = 10 * time.Millisecond
+	defer func() { watchRetryInterval = 5 * time.Second }()
+
+	// Set up dummy server for testing.
+	listResponse := []byte(`[{
+		"id":                     "staging-555555",
+		"environment":            "staging",
+		"cluster_authority_data": "LS0tLS1CRUdJTiBDR...",
+		"server_url":             "https://test.elb.us-west-2.amazonaws.com:6443",
+		"object":                 "cluster",
+		"active":                 true,
+		"resource_version":       5
+	}]`)
+
+	var (
+		mu      sync.Mutex
+		since   []string
+		failed  bool
+		stopped bool
+	)
+	stop := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		response := listResponse
+		if r.URL.Query().Get("watch") == "true" {
+			since = append(since, r.URL.Query().Get("since"))
+			switch {
+			case r.URL.Query().Get("since") == "0":
+			case !failed:
+				// Fail once to check that the watch is retried.
+				failed = true
+				rw.WriteHeader(http.StatusInternalServerError)
+				response = []byte(`{"error":{"message":"internal server error","status_code":500}}`)
+			default:
+				if !stopped {
+					stopped = true
+					close(stop)
+				}
+				response = []byte(`[]`)
+			}
+		}
+		_, err := rw.Write(response)
+		require.NoError(t, err)
+	}))
+	defer srv.Close()
+	tokenGenerator := test.NewGenerator()
+
+	// Set BaseURL in config to be the url of the dummy server.
+	client := api.NewClient(&configpkg.Config{BaseURL: srv.URL}, tokenGenerator)
+
+	t.Run("syncs clusters until stopped", func(tt *testing.T) {
+		// Create temporary test config file and defer cleanup.
+		configFile := test.CopyTestFile(tt, "../testdata", "watch", emptyConfig)
+		defer os.Remove(configFile)
+
+		err := WatchClusters(configFile, false, false, false, client, stop)
+		assert.NoError(tt, err)
+
+		// The watch resumes from the latest resource version it has seen.
+		mu.Lock()
+		require.True(tt, len(since) >= 3)
+		assert.Equal(tt, []string{"0", "5", "5"}, since[:3])
+		mu.Unlock()
+
+		kubeConfig, err := configFromFile(configFile)
+		require.NoError(tt, err)
+		_, ok := kubeConfig.Contexts["staging-555555"]
+		assert.True(tt, ok)
+		_, ok = kubeConfig.Contexts["staging"]
+		assert.True(tt, ok)
+	})
+}
@@ -0,0 +1,71 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/fatih/color"
+	"github.com/lob/pharos/pkg/pharos/api"
+)
+
+// ListWebhooks retrieves webhooks and returns a formatted string of webhooks.
+func ListWebhooks(client *api.Client) (string, error) {
+	webhooks, err := client.ListWebhooks()
+	if err != nil {
+		return "", err
+	}
+
+	// List webhook attributes in organized columns.
+	buf := new(bytes.Buffer)
+	w := tabwriter.NewWriter(buf, 0, 0, 3, ' ', 0)
+	cyan := color.New(color.FgCyan)
+
+	// Add spaces to prevent ANSI escape codes from breaking the tabwriter formatting.
+	_, err = cyan.Fprint(w, "WEBHOOK_ID\t     EVENTS\t     URL")
+	if err != nil {
+		return "", err
+	}
+
+	for _, webhook := range webhooks {
+		fmt.Fprintf(w, "\n%d\t%s\t%s", webhook.ID, strings.Join(webhook.Events, ","), webhook.URL)
+	}
+
+	fmt.Fprintln(w, "")
+	if err := w.Flush(); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// ListDeadLetters retrieves the events that could not be delivered to a
+// webhook and returns them as a formatted string.
+func ListDeadLetters(id string, client *api.Client) (string, error) {
+	deadLetters, err := client.ListDeadLetters(id)
+	if err != nil {
+		return "", err
+	}
+
+	buf := new(bytes.Buffer)
+	w := tabwriter.NewWriter(buf, 0, 0, 3, ' ', 0)
+	cyan := color.New(color.FgCyan)
+
+	// Add spaces to prevent ANSI escape codes from breaking the tabwriter formatting.
+	_, err = cyan.Fprint(w, "EVENT\t     ATTEMPTS\t     FAILED\t     LAST_ERROR")
+	if err != nil {
+		return "", err
+	}
+
+	for _, deadLetter := range deadLetters {
+		fmt.Fprintf(w, "\n%s\t%d\t%s\t%s", deadLetter.Event, deadLetter.Attempts, deadLetter.DateFailed.Local().Format("2006-01-02 15:04:05"), deadLetter.LastError)
+	}
+
+	fmt.Fprintln(w, "")
+	if err := w.Flush(); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
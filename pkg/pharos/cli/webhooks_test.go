@@ -0,0 +1,60 @@
+package cli
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lob/pharos/internal/test"
+	"github.com/lob/pharos/pkg/pharos/api"
+	configpkg "github.com/lob/pharos/pkg/pharos/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhooks(t *testing.T) {
+	// Set up dummy server for testing.
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		var response []byte
+		switch r.URL.String() {
+		case "/v1/webhooks":
+			response = []byte(`[{
+				"id":     1,
+				"url":    "https://hooks.example.com/pharos",
+				"events": ["cluster.created", "cluster.activated"]
+			}]`)
+		case "/v1/webhooks/1/dead_letters":
+			response = []byte(`[{
+				"id":          7,
+				"webhook_id":  1,
+				"event":       "cluster.created",
+				"payload":     "{}",
+				"attempts":    10,
+				"last_error":  "webhook responded with 500 Internal Server Error",
+				"date_failed": "2019-08-12T10:00:00Z"
+			}]`)
+		}
+		_, err := rw.Write(response)
+		require.NoError(t, err)
+	}))
+	defer srv.Close()
+	tokenGenerator := test.NewGenerator()
+
+	// Set BaseURL in config to be the url of the dummy server.
+	client := api.NewClient(&configpkg.Config{BaseURL: srv.URL}, tokenGenerator)
+
+	t.Run("successfully lists webhooks", func(tt *testing.T) {
+		webhooks, err := ListWebhooks(client)
+		assert.NoError(tt, err)
+		assert.Contains(tt, webhooks, "WEBHOOK_ID")
+		assert.Contains(tt, webhooks, "cluster.created,cluster.activated")
+		assert.Contains(tt, webhooks, "https://hooks.example.com/pharos")
+	})
+
+	t.Run("successfully lists dead letters", func(tt *testing.T) {
+		deadLetters, err := ListDeadLetters("1", client)
+		assert.NoError(tt, err)
+		assert.Contains(tt, deadLetters, "cluster.created")
+		assert.Contains(tt, deadLetters, "webhook responded with 500 Internal Server Error")
+	})
+}
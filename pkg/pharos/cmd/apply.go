@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/fatih/color"
+	"github.com/lob/pharos/pkg/pharos/api"
+	"github.com/lob/pharos/pkg/pharos/cli"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// Declare some variables to be used as flags.
+var manifestFile string
+
+// ApplyCmd implements a CLI command that allows users to reconcile the clusters
+// in Pharos with a manifest.
+var ApplyCmd = &cobra.Command{
+	Use:   "apply",
+	Short: "Applies a cluster manifest to Pharos",
+	Long:  "Compares a YAML or JSON manifest of clusters and environments with Pharos, prints the clusters that need to be created, updated, deleted or activated, and makes those changes.",
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return cobra.MarkFlagRequired(cmd.Flags(), "file")
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := api.ClientFromConfig(pharosConfig)
+		if err != nil {
+			return errors.Wrap(err, "unable to create client from pharos config file")
+		}
+		return runApply(manifestFile, dryRun, client)
+	},
+}
+
+func runApply(file string, dryRun bool, client *api.Client) error {
+	manifest, err := cli.LoadManifest(file)
+	if err != nil {
+		return err
+	}
+
+	changes, err := cli.PlanManifest(manifest, client)
+	if err != nil {
+		return errors.Wrap(err, "failed to plan changes")
+	}
+	plan, err := cli.FormatPlan(changes)
+	if err != nil {
+		return err
+	}
+	fmt.Print(plan)
+
+	if dryRun || len(changes) == 0 {
+		return nil
+	}
+
+	if err := cli.ApplyPlan(changes, client); err != nil {
+		return errors.Wrap(err, "failed to apply changes")
+	}
+	fmt.Printf("%s APPLIED %d CHANGES\n", color.GreenString("SUCCESS:"), len(changes))
+	return nil
+}
+
+func init() {
+	ApplyCmd.Flags().StringVarP(&manifestFile, "file", "f", "", "manifest of clusters and environments (required)")
+	ApplyCmd.Flags().BoolVarP(&dryRun, "dry-run", "d", false, "prints the changes without making them")
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lob/pharos/internal/test"
+	"github.com/lob/pharos/pkg/pharos/api"
+	configpkg "github.com/lob/pharos/pkg/pharos/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const manifestTestFile = "../testdata/manifest"
+
+func TestRunApply(t *testing.T) {
+	t.Run("applies a manifest", func(tt *testing.T) {
+		// Set up dummy server for testing.
+		var writes []string
+		srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodGet {
+				_, err := rw.Write([]byte(`[]`))
+				require.NoError(tt, err)
+				return
+			}
+			writes = append(writes, r.Method+" "+r.URL.Path)
+			_, err := rw.Write([]byte(`{"id": "production-111111", "resource_version": 1}`))
+			require.NoError(tt, err)
+		}))
+		defer srv.Close()
+		tokenGenerator := test.NewGenerator()
+
+		// Set BaseURL in config to be the url of the dummy server.
+		client := api.NewClient(&configpkg.Config{BaseURL: srv.URL}, tokenGenerator)
+
+		err := runApply(manifestTestFile, true, client)
+		assert.NoError(tt, err)
+		assert.Empty(tt, writes)
+
+		err = runApply(manifestTestFile, false, client)
+		assert.NoError(tt, err)
+		assert.Equal(tt, []string{
+			"PUT /v1/clusters/production-111111",
+			"PUT /v1/clusters/production-222222",
+			"PUT /v1/clusters/sandbox-333333",
+			"POST /v1/clusters/production-222222",
+		}, writes)
+	})
+
+	t.Run("errors when the manifest can't be loaded", func(tt *testing.T) {
+		client := api.NewClient(&configpkg.Config{BaseURL: ""}, test.NewGenerator())
+
+		err := runApply("../testdata/missing", false, client)
+		assert.Error(tt, err)
+		assert.Contains(tt, err.Error(), "unable to read manifest")
+	})
+}
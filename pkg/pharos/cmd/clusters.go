@@ -16,7 +16,10 @@ func NewClustersCmd() *cobra.Command {
 	cmd.AddCommand(CreateCmd)
 	cmd.AddCommand(CurrentCmd)
 	cmd.AddCommand(DeleteCmd)
+	cmd.AddCommand(ExportCmd)
+	cmd.AddCommand(ExtendCmd)
 	cmd.AddCommand(GetCmd)
+	cmd.AddCommand(ImportCmd)
 	cmd.AddCommand(ListCmd)
 	cmd.AddCommand(SwitchCmd)
 	cmd.AddCommand(SyncCmd)
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/fatih/color"
 	"github.com/lob/pharos/pkg/pharos/api"
@@ -12,14 +13,17 @@ import (
 // Declare some variables to be used as flags.
 var (
 	clusterAuthorityData string
+	idempotencyKey       string
 	server               string
+	ttl                  time.Duration
+	upsert               bool
 )
 
 // CreateCmd implements a CLI command that allows users to create a cluster in Pharos.
 var CreateCmd = &cobra.Command{
 	Use:     "create <cluster_id>",
 	Short:   "Creates the specified cluster",
-	Long:    "Creates the specified cluster in Pharos.",
+	Long:    "Creates the specified cluster in Pharos. With --upsert, an existing cluster's registration is replaced instead, so that registering a cluster is safe to repeat. With --ttl, the cluster is deleted automatically once it expires.",
 	Args:    func(cmd *cobra.Command, args []string) error { return argID(args) },
 	PreRunE: func(cmd *cobra.Command, args []string) error { return markFlagsRequired(cmd) },
 	RunE: func(cmd *cobra.Command, args []string) error {
@@ -27,18 +31,39 @@ var CreateCmd = &cobra.Command{
 		if err != nil {
 			return errors.Wrap(err, "unable to create client from pharos config file")
 		}
-		return runCreate(args[0], environment, clusterAuthorityData, server, client)
+		return runCreate(args[0], environment, clusterAuthorityData, server, ttl, idempotencyKey, upsert, client)
 	},
 }
 
-func runCreate(id string, env string, authorityData string, server string, client *api.Client) error {
+func runCreate(id string, env string, authorityData string, server string, ttl time.Duration, key string, upsert bool, client *api.Client) error {
+	if upsert && key != "" {
+		return errors.New("--idempotency-key can't be used with --upsert, which is already safe to repeat")
+	}
+	if ttl < 0 {
+		return errors.New("--ttl must be a positive duration such as 72h")
+	}
+
 	newCluster := api.Cluster{
 		ID:                   id,
 		Environment:          env,
 		ClusterAuthorityData: authorityData,
 		ServerURL:            server,
 	}
-	cluster, err := client.CreateCluster(newCluster)
+	if ttl > 0 {
+		expiresAt := time.Now().Add(ttl)
+		newCluster.ExpiresAt = &expiresAt
+	}
+
+	if upsert {
+		cluster, err := client.UpsertCluster(newCluster, 0)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s REGISTERED CLUSTER %s\n", color.GreenString("SUCCESS:"), cluster.ID)
+		return nil
+	}
+
+	cluster, err := client.CreateCluster(newCluster, key)
 	if err != nil {
 		return err
 	}
@@ -64,4 +89,7 @@ func init() {
 	CreateCmd.Flags().StringVarP(&environment, "environment", "e", "", "environment of the cluster (required)")
 	CreateCmd.Flags().StringVarP(&clusterAuthorityData, "cluster-authority-data", "d", "", "cluster authority data of the cluster (required)")
 	CreateCmd.Flags().StringVarP(&server, "server", "s", "", "server url of the cluster (required)")
+	CreateCmd.Flags().StringVarP(&idempotencyKey, "idempotency-key", "k", "", "key that makes retries of the same create return the cluster created by the first one")
+	CreateCmd.Flags().BoolVarP(&upsert, "upsert", "u", false, "create the cluster, or replace the registration of an existing one")
+	CreateCmd.Flags().DurationVarP(&ttl, "ttl", "t", 0, "delete the cluster automatically after this long, such as 72h")
 }
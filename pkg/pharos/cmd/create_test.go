@@ -1,9 +1,11 @@
 package cmd
 
 import (
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/lob/pharos/internal/test"
 	"github.com/lob/pharos/pkg/pharos/api"
@@ -35,7 +37,57 @@ func TestRunCreate(t *testing.T) {
 		// Set BaseURL in config to be the url of the dummy server.
 		client := api.NewClient(&configpkg.Config{BaseURL: srv.URL}, tokenGenerator)
 
-		err := runCreate("sandbox-333333", "sandbox", "LS0tLS1CRUdJTiBDR", "https://test.elb.us-west-2.amazonaws.com:6443", client)
+		err := runCreate("sandbox-333333", "sandbox", "LS0tLS1CRUdJTiBDR", "https://test.elb.us-west-2.amazonaws.com:6443", 0, "", false, client)
 		assert.NoError(tt, err)
 	})
+
+	t.Run("upserts a cluster", func(tt *testing.T) {
+		// Set up dummy server for testing.
+		var method, path string
+		srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			method, path = r.Method, r.URL.Path
+			rw.WriteHeader(http.StatusCreated)
+			_, err := rw.Write([]byte(`{"id": "sandbox-333333", "environment": "sandbox"}`))
+			require.NoError(tt, err)
+		}))
+		defer srv.Close()
+		tokenGenerator := test.NewGenerator()
+
+		// Set BaseURL in config to be the url of the dummy server.
+		client := api.NewClient(&configpkg.Config{BaseURL: srv.URL}, tokenGenerator)
+
+		err := runCreate("sandbox-333333", "sandbox", "LS0tLS1CRUdJTiBDR", "https://test.elb.us-west-2.amazonaws.com:6443", 0, "", true, client)
+		assert.NoError(tt, err)
+		assert.Equal(tt, http.MethodPut, method)
+		assert.Equal(tt, "/v1/clusters/sandbox-333333", path)
+
+		err = runCreate("sandbox-333333", "sandbox", "LS0tLS1CRUdJTiBDR", "https://test.elb.us-west-2.amazonaws.com:6443", 0, "key", true, client)
+		assert.Error(tt, err)
+		assert.Contains(tt, err.Error(), "--idempotency-key can't be used with --upsert")
+	})
+
+	t.Run("creates a cluster that expires after its TTL", func(tt *testing.T) {
+		// Set up dummy server for testing.
+		var created api.Cluster
+		srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			err := json.NewDecoder(r.Body).Decode(&created)
+			require.NoError(tt, err)
+			_, err = rw.Write([]byte(`{"id": "preview-333333", "environment": "preview"}`))
+			require.NoError(tt, err)
+		}))
+		defer srv.Close()
+		tokenGenerator := test.NewGenerator()
+
+		// Set BaseURL in config to be the url of the dummy server.
+		client := api.NewClient(&configpkg.Config{BaseURL: srv.URL}, tokenGenerator)
+
+		err := runCreate("preview-333333", "preview", "LS0tLS1CRUdJTiBDR", "https://test.elb.us-west-2.amazonaws.com:6443", 72*time.Hour, "", false, client)
+		require.NoError(tt, err)
+		require.NotNil(tt, created.ExpiresAt)
+		assert.WithinDuration(tt, time.Now().Add(72*time.Hour), *created.ExpiresAt, time.Minute)
+
+		err = runCreate("preview-333333", "preview", "LS0tLS1CRUdJTiBDR", "https://test.elb.us-west-2.amazonaws.com:6443", -time.Hour, "", false, client)
+		assert.Error(tt, err)
+		assert.Contains(tt, err.Error(), "--ttl must be a positive duration")
+	})
 }
@@ -2,7 +2,6 @@ package cmd
 
 import (
 	"fmt"
-	"os"
 
 	"github.com/lob/pharos/pkg/pharos/cli"
 	"github.com/pkg/errors"
@@ -27,5 +26,5 @@ func runCurrent(kubeConfigFile string) error {
 }
 
 func init() {
-	CurrentCmd.Flags().StringVarP(&file, "file", "f", os.Getenv("HOME")+"/.kube/config", "specify kubeconfig file (defaults to $HOME/.kube/config)")
+	CurrentCmd.Flags().StringVarP(&file, "file", "f", "", "specify kubeconfig file (defaults to $KUBECONFIG or $HOME/.kube/config)")
 }
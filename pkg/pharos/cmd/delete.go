@@ -5,31 +5,144 @@ import (
 
 	"github.com/fatih/color"
 	"github.com/lob/pharos/pkg/pharos/api"
+	"github.com/lob/pharos/pkg/util/model"
+	"github.com/lob/pharos/pkg/util/selector"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
 
-// DeleteCmd implements a CLI command that allows users to mark a cluster
+// DeleteCmd implements a CLI command that allows users to mark clusters
 // as deleted in the Pharos database.
 var DeleteCmd = &cobra.Command{
-	Use:   "delete <cluster_id>",
-	Short: "Deletes the specified cluster",
-	Long:  "Marks the specified cluster as deleted in Pharos.",
-	Args:  func(cmd *cobra.Command, args []string) error { return argID(args) },
+	Use:   "delete <cluster_id>...",
+	Short: "Deletes the specified clusters",
+	Long:  "Marks the clusters with the specified IDs, or the clusters matching a selector, as deleted in Pharos. Environment names aren't accepted, so that the cluster deleted is never one that became active in the meantime. Several clusters are deleted together, so that either all of them or none are deleted. More than 100 clusters are deleted in batches of 100, each of which is deleted entirely or not at all.",
+	Args: func(cmd *cobra.Command, args []string) error {
+		if clusterSelect != "" {
+			if len(args) > 0 {
+				return errors.New("cluster names or ids can't be given together with --selector")
+			}
+			return nil
+		}
+		if len(args) < 1 {
+			return errors.New("requires a cluster id argument")
+		}
+		return nil
+	},
 	RunE: func(cmd *cobra.Command, args []string) error {
 		client, err := api.ClientFromConfig(pharosConfig)
 		if err != nil {
 			return errors.Wrap(err, "unable to create client from pharos config file")
 		}
-		return runDelete(args[0], client)
+		if clusterSelect == "" && len(args) == 1 {
+			return runDelete(args[0], client)
+		}
+		return runDeleteMany(args, clusterSelect, client)
 	},
 }
 
-func runDelete(id string, client *api.Client) error {
-	cluster, err := client.DeleteCluster(id)
+func runDelete(name string, client *api.Client) error {
+	cluster, err := resolveClusterID(name, client)
+	if err != nil {
+		return err
+	}
+
+	// Deleting is not retried if the cluster changed since it was resolved, as
+	// it may no longer be the cluster that was meant to be deleted.
+	cluster, err = client.DeleteCluster(cluster.ID, cluster.ResourceVersion)
+	if api.IsPreconditionFailed(err) {
+		return errors.Errorf("cluster %s changed while it was being deleted, check it and try again", name)
+	}
 	if err != nil {
 		return err
 	}
 	fmt.Printf("%s DELETED CLUSTER %s\n", color.GreenString("SUCCESS:"), cluster.ID)
 	return nil
 }
+
+// resolveClusterID returns the cluster with the given ID. It errors if the ID is
+// the name of an environment, which would resolve to whichever cluster is
+// active in it, as that's not specific enough for deleting a cluster.
+func resolveClusterID(id string, client *api.Client) (model.Cluster, error) {
+	cluster, err := client.ResolveCluster(id)
+	if err != nil {
+		return cluster, err
+	}
+	if cluster.ID != id {
+		return cluster, errors.Errorf("%s is an environment, give the id of the cluster to delete instead, such as %s", id, cluster.ID)
+	}
+	return cluster, nil
+}
+
+// runDeleteMany deletes the clusters with the given names, or the clusters
+// matching a selector, in batches of at most model.MaxBatchOperations. A batch
+// that fails stops the deletion, leaving the earlier batches deleted.
+func runDeleteMany(names []string, sel string, client *api.Client) error {
+	var clusters []model.Cluster
+	if sel != "" {
+		s, err := selector.Parse(sel)
+		if err != nil {
+			return err
+		}
+
+		all, err := client.ListClusters(nil)
+		if err != nil {
+			return err
+		}
+		clusters = s.Filter(all)
+		if len(clusters) == 0 {
+			return fmt.Errorf("no clusters found matching selector %s", sel)
+		}
+	} else {
+		for _, name := range names {
+			cluster, err := resolveClusterID(name, client)
+			if err != nil {
+				return err
+			}
+			clusters = append(clusters, cluster)
+		}
+	}
+
+	// Names that resolve to the same cluster only delete it once.
+	seen := make(map[string]bool, len(clusters))
+	operations := make([]model.BatchOperation, 0, len(clusters))
+	for _, cluster := range clusters {
+		if seen[cluster.ID] {
+			continue
+		}
+		seen[cluster.ID] = true
+		operations = append(operations, model.BatchOperation{
+			Action:  model.BatchDelete,
+			ID:      cluster.ID,
+			Version: cluster.ResourceVersion,
+		})
+	}
+
+	deleted := 0
+	for start := 0; start < len(operations); start += model.MaxBatchOperations {
+		end := start + model.MaxBatchOperations
+		if end > len(operations) {
+			end = len(operations)
+		}
+
+		result, err := client.Batch(operations[start:end])
+		if err != nil {
+			if api.IsPreconditionFailed(err) {
+				err = errors.New("clusters changed while they were being deleted, check them and try again")
+			}
+			if deleted > 0 {
+				return errors.Wrapf(err, "deleted %d of %d clusters", deleted, len(operations))
+			}
+			return err
+		}
+		for _, res := range result.Results {
+			fmt.Printf("%s DELETED CLUSTER %s\n", color.GreenString("SUCCESS:"), res.ID)
+		}
+		deleted += len(result.Results)
+	}
+	return nil
+}
+
+func init() {
+	DeleteCmd.Flags().StringVarP(&clusterSelect, "selector", "l", "", "delete the clusters matching a selector, such as environment=sandbox,source=eks")
+}
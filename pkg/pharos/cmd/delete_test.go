@@ -1,13 +1,18 @@
 package cmd
 
 import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/lob/pharos/internal/test"
 	"github.com/lob/pharos/pkg/pharos/api"
 	configpkg "github.com/lob/pharos/pkg/pharos/config"
+	"github.com/lob/pharos/pkg/util/model"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -39,6 +44,37 @@ func TestRunDelete(t *testing.T) {
 		assert.NoError(tt, err)
 	})
 
+	t.Run("refuses to delete the active cluster of an environment", func(tt *testing.T) {
+		// Set up dummy server for testing.
+		cluster := []byte(`{
+			"id":                     "sandbox-6906ce",
+			"environment":            "sandbox",
+			"cluster_authority_data": "LS0tLS1CRUdJTiBDR...",
+			"server_url":             "https://test.elb.us-west-2.amazonaws.com:6443",
+			"object":                 "cluster",
+			"active":                 true
+		}`)
+
+		var deleted string
+		srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodDelete {
+				deleted = r.URL.Path
+			}
+			_, err := rw.Write(cluster)
+			require.NoError(tt, err)
+		}))
+		defer srv.Close()
+		tokenGenerator := test.NewGenerator()
+
+		// Set BaseURL in config to be the url of the dummy server.
+		client := api.NewClient(&configpkg.Config{BaseURL: srv.URL}, tokenGenerator)
+
+		err := runDelete("sandbox", client)
+		require.Error(tt, err)
+		assert.Contains(tt, err.Error(), "sandbox is an environment, give the id of the cluster to delete instead, such as sandbox-6906ce")
+		assert.Empty(tt, deleted)
+	})
+
 	t.Run("errors when attempting to delete a nonexistent cluster", func(tt *testing.T) {
 		// Set up dummy server for testing.
 		srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
@@ -54,7 +90,211 @@ func TestRunDelete(t *testing.T) {
 
 		err := runDelete("sandbox-egg", client)
 		assert.Error(tt, err)
-		assert.Contains(tt, err.Error(), "failed to delete cluster sandbox-egg")
+		assert.Contains(tt, err.Error(), "failed to resolve cluster sandbox-egg")
 		assert.Contains(tt, err.Error(), "cluster not found")
 	})
+
+	t.Run("reports a cluster that changed while it was being deleted", func(tt *testing.T) {
+		// Set up dummy server for testing.
+		cluster := []byte(`{
+			"id":                     "sandbox-333333",
+			"environment":            "sandbox",
+			"cluster_authority_data": "LS0tLS1CRUdJTiBDR...",
+			"server_url":             "https://test.elb.us-west-2.amazonaws.com:6443",
+			"object":                 "cluster",
+			"active":                 true,
+			"resource_version":       5
+		}`)
+
+		var ifMatch []string
+		srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodDelete {
+				ifMatch = append(ifMatch, r.Header.Get("If-Match"))
+				rw.WriteHeader(http.StatusPreconditionFailed)
+				_, err := rw.Write([]byte(`{"error":{"message":"cluster has been modified","status_code":412}}`))
+				require.NoError(tt, err)
+				return
+			}
+			_, err := rw.Write(cluster)
+			require.NoError(tt, err)
+		}))
+		defer srv.Close()
+		tokenGenerator := test.NewGenerator()
+
+		// Set BaseURL in config to be the url of the dummy server.
+		client := api.NewClient(&configpkg.Config{BaseURL: srv.URL}, tokenGenerator)
+
+		err := runDelete("sandbox-333333", client)
+		assert.Error(tt, err)
+		assert.Contains(tt, err.Error(), "cluster sandbox-333333 changed while it was being deleted")
+		assert.Equal(tt, []string{`"5"`}, ifMatch)
+	})
+}
+
+func TestRunDeleteMany(t *testing.T) {
+	clusters := map[string]string{
+		"sandbox-111111": `{"id": "sandbox-111111", "environment": "sandbox", "source": "eks", "resource_version": 3}`,
+		"sandbox-222222": `{"id": "sandbox-222222", "environment": "sandbox", "source": "manual", "resource_version": 4}`,
+		"staging-333333": `{"id": "staging-333333", "environment": "staging", "source": "eks", "resource_version": 5}`,
+	}
+
+	newServer := func(tt *testing.T, batch *string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			var response string
+			switch {
+			case r.URL.Path == "/v1/clusters/batch":
+				body, err := ioutil.ReadAll(r.Body)
+				require.NoError(tt, err)
+				*batch = string(body)
+				response = `{"applied": true, "results": [{"action": "delete", "id": "sandbox-111111", "status_code": 200}, {"action": "delete", "id": "staging-333333", "status_code": 200}]}`
+			case r.URL.Path == "/v1/clusters":
+				response = fmt.Sprintf("[%s, %s, %s]", clusters["sandbox-111111"], clusters["sandbox-222222"], clusters["staging-333333"])
+			case strings.HasPrefix(r.URL.Path, "/v1/resolve/"):
+				name := strings.TrimPrefix(r.URL.Path, "/v1/resolve/")
+				if name == "sandbox" {
+					// Environments resolve to their active cluster.
+					name = "sandbox-111111"
+				}
+				cluster, ok := clusters[name]
+				if !ok {
+					rw.WriteHeader(http.StatusNotFound)
+					cluster = `{"error": {"code": "cluster_not_found", "message": "cluster not found", "status_code": 404}}`
+				}
+				response = cluster
+			}
+			_, err := rw.Write([]byte(response))
+			require.NoError(tt, err)
+		}))
+	}
+	tokenGenerator := test.NewGenerator()
+
+	t.Run("deletes several clusters in one batch", func(tt *testing.T) {
+		var batch string
+		srv := newServer(tt, &batch)
+		defer srv.Close()
+		client := api.NewClient(&configpkg.Config{BaseURL: srv.URL}, tokenGenerator)
+
+		err := runDeleteMany([]string{"sandbox-111111", "staging-333333", "sandbox-111111"}, "", client)
+		require.NoError(tt, err)
+		assert.JSONEq(tt, `{"operations": [
+			{"action": "delete", "id": "sandbox-111111", "version": 3},
+			{"action": "delete", "id": "staging-333333", "version": 5}
+		]}`, batch)
+	})
+
+	t.Run("deletes the clusters matching a selector", func(tt *testing.T) {
+		var batch string
+		srv := newServer(tt, &batch)
+		defer srv.Close()
+		client := api.NewClient(&configpkg.Config{BaseURL: srv.URL}, tokenGenerator)
+
+		err := runDeleteMany(nil, "source=eks", client)
+		require.NoError(tt, err)
+		assert.JSONEq(tt, `{"operations": [
+			{"action": "delete", "id": "sandbox-111111", "version": 3},
+			{"action": "delete", "id": "staging-333333", "version": 5}
+		]}`, batch)
+	})
+
+	t.Run("errors when no clusters match a selector", func(tt *testing.T) {
+		var batch string
+		srv := newServer(tt, &batch)
+		defer srv.Close()
+		client := api.NewClient(&configpkg.Config{BaseURL: srv.URL}, tokenGenerator)
+
+		err := runDeleteMany(nil, "environment=production", client)
+		require.Error(tt, err)
+		assert.Contains(tt, err.Error(), "no clusters found matching selector environment=production")
+		assert.Empty(tt, batch)
+	})
+
+	t.Run("deletes nothing when a cluster can't be resolved", func(tt *testing.T) {
+		var batch string
+		srv := newServer(tt, &batch)
+		defer srv.Close()
+		client := api.NewClient(&configpkg.Config{BaseURL: srv.URL}, tokenGenerator)
+
+		err := runDeleteMany([]string{"sandbox-111111", "sandbox-egg"}, "", client)
+		require.Error(tt, err)
+		assert.Contains(tt, err.Error(), "failed to resolve cluster sandbox-egg")
+		assert.Empty(tt, batch)
+	})
+
+	t.Run("deletes nothing when given an environment", func(tt *testing.T) {
+		var batch string
+		srv := newServer(tt, &batch)
+		defer srv.Close()
+		client := api.NewClient(&configpkg.Config{BaseURL: srv.URL}, tokenGenerator)
+
+		err := runDeleteMany([]string{"staging-333333", "sandbox"}, "", client)
+		require.Error(tt, err)
+		assert.Contains(tt, err.Error(), "sandbox is an environment")
+		assert.Empty(tt, batch)
+	})
+
+	t.Run("reports clusters that changed while they were being deleted", func(tt *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			response := clusters["sandbox-111111"]
+			if r.URL.Path == "/v1/clusters/batch" {
+				response = `{"applied": false, "results": [{"action": "delete", "id": "sandbox-111111", "status_code": 412, "error": {"code": "precondition_failed", "message": "cluster has been modified", "status_code": 412}}]}`
+			}
+			_, err := rw.Write([]byte(response))
+			require.NoError(tt, err)
+		}))
+		defer srv.Close()
+		client := api.NewClient(&configpkg.Config{BaseURL: srv.URL}, tokenGenerator)
+
+		err := runDeleteMany([]string{"sandbox-111111", "sandbox-111111"}, "", client)
+		require.Error(tt, err)
+		assert.Contains(tt, err.Error(), "clusters changed while they were being deleted")
+	})
+
+	t.Run("deletes more clusters than fit in one batch in several batches", func(tt *testing.T) {
+		var sizes []int
+		failAt := 0
+		srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/v1/clusters" {
+				all := make([]model.Cluster, 250)
+				for i := range all {
+					all[i] = model.Cluster{ID: fmt.Sprintf("sandbox-%06d", i), Environment: "sandbox", Source: model.SourceEKS}
+				}
+				err := json.NewEncoder(rw).Encode(all)
+				require.NoError(tt, err)
+				return
+			}
+
+			var params struct {
+				Operations []model.BatchOperation `json:"operations"`
+			}
+			err := json.NewDecoder(r.Body).Decode(&params)
+			require.NoError(tt, err)
+			sizes = append(sizes, len(params.Operations))
+
+			result := model.BatchResult{Applied: len(sizes) != failAt}
+			for _, op := range params.Operations {
+				result.Results = append(result.Results, model.BatchOperationResult{Action: op.Action, ID: op.ID, StatusCode: http.StatusOK})
+			}
+			if !result.Applied {
+				result.Results = result.Results[:1]
+				result.Results[0].StatusCode = http.StatusNotFound
+				result.Results[0].Error = &model.Error{Code: model.CodeClusterNotFound, Message: "cluster not found", StatusCode: http.StatusNotFound}
+			}
+			err = json.NewEncoder(rw).Encode(result)
+			require.NoError(tt, err)
+		}))
+		defer srv.Close()
+		client := api.NewClient(&configpkg.Config{BaseURL: srv.URL}, tokenGenerator)
+
+		err := runDeleteMany(nil, "source=eks", client)
+		require.NoError(tt, err)
+		assert.Equal(tt, []int{100, 100, 50}, sizes)
+
+		// A batch that fails stops the deletion.
+		sizes = nil
+		failAt = 2
+		err = runDeleteMany(nil, "source=eks", client)
+		require.Error(tt, err)
+		assert.Contains(tt, err.Error(), "deleted 100 of 250 clusters")
+		assert.Equal(tt, []int{100, 100}, sizes)
+	})
 }
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/lob/pharos/pkg/pharos/api"
+	"github.com/lob/pharos/pkg/pharos/cli"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// DiffCmd implements a CLI command that allows users to check whether the
+// clusters in Pharos match a manifest, for example in CI.
+var DiffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Compares a cluster manifest with Pharos",
+	Long:  "Compares a YAML or JSON manifest of clusters and environments with Pharos, prints the changes pharos apply would make, and exits with an error if there are any.",
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return cobra.MarkFlagRequired(cmd.Flags(), "file")
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := api.ClientFromConfig(pharosConfig)
+		if err != nil {
+			return errors.Wrap(err, "unable to create client from pharos config file")
+		}
+		return runDiff(manifestFile, client)
+	},
+}
+
+func runDiff(file string, client *api.Client) error {
+	manifest, err := cli.LoadManifest(file)
+	if err != nil {
+		return err
+	}
+
+	changes, err := cli.PlanManifest(manifest, client)
+	if err != nil {
+		return errors.Wrap(err, "failed to plan changes")
+	}
+	plan, err := cli.FormatPlan(changes)
+	if err != nil {
+		return err
+	}
+	fmt.Print(plan)
+
+	if len(changes) > 0 {
+		return errors.Errorf("pharos differs from the manifest by %d changes", len(changes))
+	}
+	return nil
+}
+
+func init() {
+	DiffCmd.Flags().StringVarP(&manifestFile, "file", "f", "", "manifest of clusters and environments (required)")
+}
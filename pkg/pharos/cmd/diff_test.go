@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lob/pharos/internal/test"
+	"github.com/lob/pharos/pkg/pharos/api"
+	configpkg "github.com/lob/pharos/pkg/pharos/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunDiff(t *testing.T) {
+	t.Run("succeeds when Pharos matches the manifest", func(tt *testing.T) {
+		// Set up dummy server for testing.
+		srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			_, err := rw.Write([]byte(`[
+				{"id": "production-111111", "environment": "production", "server_url": "https://production-111111.example.com", "cluster_authority_data": "YWJj"},
+				{"id": "production-222222", "environment": "production", "server_url": "https://production-222222.example.com", "cluster_authority_data": "YWJj", "active": true},
+				{"id": "sandbox-333333", "environment": "sandbox", "server_url": "https://sandbox-333333.example.com", "cluster_authority_data": "YWJj"}
+			]`))
+			require.NoError(tt, err)
+		}))
+		defer srv.Close()
+		tokenGenerator := test.NewGenerator()
+
+		// Set BaseURL in config to be the url of the dummy server.
+		client := api.NewClient(&configpkg.Config{BaseURL: srv.URL}, tokenGenerator)
+
+		err := runDiff(manifestTestFile, client)
+		assert.NoError(tt, err)
+	})
+
+	t.Run("errors when Pharos differs from the manifest", func(tt *testing.T) {
+		// Set up dummy server for testing.
+		srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			_, err := rw.Write([]byte(`[]`))
+			require.NoError(tt, err)
+		}))
+		defer srv.Close()
+		tokenGenerator := test.NewGenerator()
+
+		// Set BaseURL in config to be the url of the dummy server.
+		client := api.NewClient(&configpkg.Config{BaseURL: srv.URL}, tokenGenerator)
+
+		err := runDiff(manifestTestFile, client)
+		assert.Error(tt, err)
+		assert.Contains(tt, err.Error(), "pharos differs from the manifest by 4 changes")
+	})
+}
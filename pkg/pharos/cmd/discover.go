@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/lob/pharos/pkg/pharos/api"
+	"github.com/lob/pharos/pkg/pharos/cli"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// DiscoverCmd implements a CLI command that allows users to register clusters
+// discovered by the Pharos API server in AWS EKS.
+var DiscoverCmd = &cobra.Command{
+	Use:   "discover",
+	Short: "Discovers clusters in AWS EKS",
+	Long:  "Discovers clusters in the AWS accounts and regions configured on the Pharos API server, registers new clusters, updates existing ones and flags clusters that no longer exist.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := api.ClientFromConfig(pharosConfig)
+		if err != nil {
+			return errors.Wrap(err, "unable to create client from pharos config file")
+		}
+		return runDiscover(dryRun, client)
+	},
+}
+
+func runDiscover(dryRun bool, client *api.Client) error {
+	summary, err := cli.DiscoverClusters(dryRun, client)
+	if err != nil {
+		return errors.Wrap(err, "failed to discover clusters")
+	}
+	fmt.Print(summary)
+	return nil
+}
+
+func init() {
+	DiscoverCmd.Flags().BoolVarP(&dryRun, "dry-run", "d", false, "prints the changes discovery would make without making them")
+}
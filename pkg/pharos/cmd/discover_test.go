@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lob/pharos/internal/test"
+	"github.com/lob/pharos/pkg/pharos/api"
+	configpkg "github.com/lob/pharos/pkg/pharos/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunDiscover(t *testing.T) {
+	t.Run("successfully discovers clusters", func(tt *testing.T) {
+		// Set up dummy server for testing.
+		discoverResponse := []byte(`{
+			"dry_run":   true,
+			"created":   [{"id": "sandbox-111111", "environment": "sandbox", "source": "eks"}],
+			"updated":   [],
+			"unchanged": [],
+			"missing":   []
+		}`)
+
+		srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			_, err := rw.Write(discoverResponse)
+			require.NoError(tt, err)
+		}))
+		defer srv.Close()
+		tokenGenerator := test.NewGenerator()
+
+		// Set BaseURL in config to be the url of the dummy server.
+		client := api.NewClient(&configpkg.Config{BaseURL: srv.URL}, tokenGenerator)
+
+		err := runDiscover(true, client)
+		assert.NoError(tt, err)
+	})
+
+	t.Run("errors when the api server fails to discover clusters", func(tt *testing.T) {
+		// Set up dummy server for testing.
+		srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			rw.WriteHeader(http.StatusServiceUnavailable)
+			_, err := rw.Write([]byte(`{"error": {"message": "cluster discovery is not configured", "status_code": 503}}`))
+			require.NoError(tt, err)
+		}))
+		defer srv.Close()
+		tokenGenerator := test.NewGenerator()
+
+		// Set BaseURL in config to be the url of the dummy server.
+		client := api.NewClient(&configpkg.Config{BaseURL: srv.URL}, tokenGenerator)
+
+		err := runDiscover(true, client)
+		assert.Error(tt, err)
+		assert.Contains(tt, err.Error(), "cluster discovery is not configured")
+	})
+}
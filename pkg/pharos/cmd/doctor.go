@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/lob/pharos/pkg/pharos/api"
+	"github.com/lob/pharos/pkg/pharos/cli"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// DoctorCmd implements a CLI command that allows users to check the clusters
+// in Pharos for problems, such as environments with more than one active
+// cluster.
+var DoctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Checks the clusters in Pharos for problems",
+	Long:  "Checks the clusters registered in Pharos for problems, such as environments with more than one active cluster, and exits with an error if any are found.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := api.ClientFromConfig(pharosConfig)
+		if err != nil {
+			return errors.Wrap(err, "unable to create client from pharos config file")
+		}
+		return runDoctor(client)
+	},
+}
+
+func runDoctor(client *api.Client) error {
+	report, problems, err := cli.Doctor(client)
+	if err != nil {
+		return errors.Wrap(err, "failed to check clusters")
+	}
+	fmt.Print(report)
+	if problems > 0 {
+		return errors.Errorf("found %d problems", problems)
+	}
+	return nil
+}
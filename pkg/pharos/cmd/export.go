@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"github.com/lob/pharos/pkg/pharos/api"
+	"github.com/lob/pharos/pkg/pharos/cli"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// Declare some variables to be used as flags.
+var (
+	output        string
+	clusterSelect string
+)
+
+// ExportCmd implements a CLI command that allows users to write a standalone
+// kubeconfig file containing only the requested clusters.
+var ExportCmd = &cobra.Command{
+	Use:   "export [<cluster_id>]",
+	Short: "Exports a standalone kubeconfig for the specified clusters",
+	Long:  "Exports a standalone kubeconfig containing only the specified cluster, the active cluster of an environment or the clusters matching a selector to stdout or a file.",
+	Args: func(cmd *cobra.Command, args []string) error {
+		if clusterSelect != "" {
+			if len(args) > 0 {
+				return errors.New("a cluster name or id can't be given together with --selector")
+			}
+			return nil
+		}
+		return argID(args)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := api.ClientFromConfig(pharosConfig)
+		if err != nil {
+			return errors.Wrap(err, "unable to create client from pharos config file")
+		}
+		id := ""
+		if len(args) > 0 {
+			id = args[0]
+		}
+		return runExport(id, clusterSelect, output, client)
+	},
+}
+
+func runExport(id string, selector string, outputFile string, client *api.Client) error {
+	err := cli.ExportClusters(id, selector, outputFile, client)
+	if err != nil {
+		return errors.Wrap(err, "failed to export clusters")
+	}
+	return nil
+}
+
+func init() {
+	ExportCmd.Flags().StringVarP(&clusterSelect, "selector", "l", "", "export the clusters matching a selector, such as environment=sandbox,active=true")
+	ExportCmd.Flags().StringVarP(&output, "output", "o", "", "write the kubeconfig to a file instead of stdout")
+}
@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lob/pharos/internal/test"
+	"github.com/lob/pharos/pkg/pharos/api"
+	configpkg "github.com/lob/pharos/pkg/pharos/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunExport(t *testing.T) {
+	// Set up dummy server for testing.
+	testResponse := []byte(`[{
+		"id":                     "staging-555555",
+		"environment":            "staging",
+		"cluster_authority_data": "LS0tLS1CRUdJTiBDR...",
+		"server_url":             "https://test.elb.us-west-2.amazonaws.com:6443",
+		"active":                 true
+	}]`)
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		_, err := rw.Write(testResponse)
+		require.NoError(t, err)
+	}))
+	defer srv.Close()
+	tokenGenerator := test.NewGenerator()
+
+	// Set BaseURL in config to be the url of the dummy server.
+	client := api.NewClient(&configpkg.Config{BaseURL: srv.URL}, tokenGenerator)
+
+	t.Run("successfully exports clusters to stdout", func(tt *testing.T) {
+		err := runExport("", "environment=staging", "", client)
+		assert.NoError(tt, err)
+	})
+
+	t.Run("errors when exporting with an invalid selector", func(tt *testing.T) {
+		err := runExport("", "environment", "", client)
+		assert.Error(tt, err)
+		assert.Contains(tt, err.Error(), "failed to export clusters")
+	})
+}
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/lob/pharos/pkg/pharos/api"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// ExtendCmd implements a CLI command that allows users to change when a
+// cluster expires in Pharos.
+var ExtendCmd = &cobra.Command{
+	Use:   "extend <cluster_id> --ttl <duration>",
+	Short: "Extends the expiry of a cluster",
+	Long:  "Makes the specified cluster, or the active cluster of the specified environment, expire after the given TTL from now instead of when it would have.",
+	Args:  func(cmd *cobra.Command, args []string) error { return argID(args) },
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return cobra.MarkFlagRequired(cmd.Flags(), "ttl")
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := api.ClientFromConfig(pharosConfig)
+		if err != nil {
+			return errors.Wrap(err, "unable to create client from pharos config file")
+		}
+		return runExtend(args[0], ttl, client)
+	},
+}
+
+func runExtend(name string, ttl time.Duration, client *api.Client) error {
+	if ttl <= 0 {
+		return errors.New("--ttl must be a positive duration such as 72h")
+	}
+
+	cluster, err := client.ResolveCluster(name)
+	if err != nil {
+		return err
+	}
+
+	// Extending is retried if the cluster changed since it was fetched, as
+	// setting its expiry again is safe. The cluster is fetched again by its ID,
+	// rather than resolved again, so that the same cluster is extended.
+	for attempt := 1; ; attempt++ {
+		extended, err := client.ExtendCluster(cluster.ID, time.Now().Add(ttl), cluster.ResourceVersion)
+		if api.IsPreconditionFailed(err) && attempt < updateAttempts {
+			cluster, err = client.GetCluster(cluster.ID)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if api.IsPreconditionFailed(err) {
+			return errors.Errorf("cluster %s kept changing while it was being extended, try again", name)
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s CLUSTER %s NOW EXPIRES AT %s\n", color.GreenString("SUCCESS:"), extended.ID, extended.ExpiresAt.Format(time.RFC3339))
+		return nil
+	}
+}
+
+func init() {
+	ExtendCmd.Flags().DurationVarP(&ttl, "ttl", "t", 0, "expire the cluster after this long from now, such as 72h (required)")
+}
@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/lob/pharos/internal/test"
+	"github.com/lob/pharos/pkg/pharos/api"
+	configpkg "github.com/lob/pharos/pkg/pharos/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunExtend(t *testing.T) {
+	t.Run("extends the expiry of a cluster", func(tt *testing.T) {
+		// Set up dummy server for testing.
+		var update struct {
+			ExpiresAt time.Time `json:"expires_at"`
+		}
+		var ifMatch string
+		srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodPost {
+				ifMatch = r.Header.Get("If-Match")
+				err := json.NewDecoder(r.Body).Decode(&update)
+				require.NoError(tt, err)
+				_, err = rw.Write([]byte(`{"id": "preview-333333", "environment": "preview", "expires_at": "2019-09-12T10:00:00Z"}`))
+				require.NoError(tt, err)
+				return
+			}
+			_, err := rw.Write([]byte(`{"id": "preview-333333", "environment": "preview", "resource_version": 4}`))
+			require.NoError(tt, err)
+		}))
+		defer srv.Close()
+		tokenGenerator := test.NewGenerator()
+
+		// Set BaseURL in config to be the url of the dummy server.
+		client := api.NewClient(&configpkg.Config{BaseURL: srv.URL}, tokenGenerator)
+
+		err := runExtend("preview-333333", 48*time.Hour, client)
+		require.NoError(tt, err)
+		assert.Equal(tt, `"4"`, ifMatch)
+		assert.WithinDuration(tt, time.Now().Add(48*time.Hour), update.ExpiresAt, time.Minute)
+	})
+
+	t.Run("retries with the cluster fetched by its ID and then reports a cluster that keeps changing", func(tt *testing.T) {
+		// Set up dummy server for testing.
+		var requests, ifMatch []string
+		version := 4
+		srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			requests = append(requests, r.Method+" "+r.URL.Path)
+			if r.Method == http.MethodPost {
+				ifMatch = append(ifMatch, r.Header.Get("If-Match"))
+				version++
+				rw.WriteHeader(http.StatusPreconditionFailed)
+				_, err := rw.Write([]byte(`{"error":{"code":"precondition_failed","message":"cluster has been modified","status_code":412}}`))
+				require.NoError(tt, err)
+				return
+			}
+			_, err := fmt.Fprintf(rw, `{"id": "preview-333333", "environment": "preview", "resource_version": %d}`, version)
+			require.NoError(tt, err)
+		}))
+		defer srv.Close()
+		tokenGenerator := test.NewGenerator()
+
+		// Set BaseURL in config to be the url of the dummy server.
+		client := api.NewClient(&configpkg.Config{BaseURL: srv.URL}, tokenGenerator)
+
+		err := runExtend("preview", 48*time.Hour, client)
+		require.Error(tt, err)
+		assert.Contains(tt, err.Error(), "cluster preview kept changing while it was being extended")
+		assert.Equal(tt, []string{`"4"`, `"5"`, `"6"`}, ifMatch)
+		assert.Equal(tt, []string{
+			"GET /v1/resolve/preview",
+			"POST /v1/clusters/preview-333333",
+			"GET /v1/clusters/preview-333333",
+			"POST /v1/clusters/preview-333333",
+			"GET /v1/clusters/preview-333333",
+			"POST /v1/clusters/preview-333333",
+		}, requests)
+	})
+
+	t.Run("errors without a positive TTL", func(tt *testing.T) {
+		err := runExtend("preview-333333", 0, nil)
+		assert.Error(tt, err)
+		assert.Contains(tt, err.Error(), "--ttl must be a positive duration")
+	})
+}
@@ -1,9 +1,6 @@
 package cmd
 
 import (
-	"fmt"
-	"os"
-
 	"github.com/lob/pharos/pkg/pharos/api"
 	"github.com/lob/pharos/pkg/pharos/cli"
 	"github.com/pkg/errors"
@@ -15,19 +12,19 @@ import (
 var GetCmd = &cobra.Command{
 	Use:   "get <cluster_id>",
 	Short: "Retrieves information about the specified cluster",
-	Long:  "Retrieves information about the specified cluster and merges it into designated kubeconfig file.",
+	Long:  "Retrieves information about the specified cluster, or the active cluster of the specified environment, and merges it into designated kubeconfig file.",
 	Args:  func(cmd *cobra.Command, args []string) error { return argID(args) },
 	RunE: func(cmd *cobra.Command, args []string) error {
 		client, err := api.ClientFromConfig(pharosConfig)
 		if err != nil {
 			return errors.Wrap(err, "unable to create client from pharos config file")
 		}
-		return runGet(args[0], file, dryRun, client)
+		return runGet(args[0], file, dryRun, diff, client)
 	},
 }
 
-func runGet(cluster string, kubeConfigFile string, dryRun bool, client *api.Client) error {
-	err := cli.GetCluster(cluster, kubeConfigFile, dryRun, client)
+func runGet(cluster string, kubeConfigFile string, dryRun bool, diff string, client *api.Client) error {
+	err := cli.GetCluster(cluster, kubeConfigFile, dryRun, diff, client)
 	if err != nil {
 		return errors.Wrap(err, "failed to get cluster information")
 	}
@@ -36,5 +33,7 @@ func runGet(cluster string, kubeConfigFile string, dryRun bool, client *api.Clie
 
 func init() {
 	GetCmd.Flags().BoolVarP(&dryRun, "dry-run", "d", false, "prints the resulting kubeconfig to terminal without any other action")
-	GetCmd.Flags().StringVarP(&file, "file", "f", fmt.Sprintf("%s/.kube/config", os.Getenv("HOME")), "specify kubeconfig file to merge into")
+	GetCmd.Flags().StringVar(&diff, "diff", "", "prints the changes to the kubeconfig file as a text or json diff without writing them")
+	GetCmd.Flags().Lookup("diff").NoOptDefVal = cli.DiffText
+	GetCmd.Flags().StringVarP(&file, "file", "f", "", "specify kubeconfig file to merge into (defaults to $KUBECONFIG or $HOME/.kube/config)")
 }
@@ -17,14 +17,14 @@ import (
 func TestRunGet(t *testing.T) {
 	t.Run("successfully merges information from a cluster into a kubeconfig file", func(tt *testing.T) {
 		// Set up dummy server for testing.
-		testResponse := []byte(`[{
+		testResponse := []byte(`{
 			"id": "sandbox-161616",
 			"environment": "sandbox",
 			"cluster_authority_data": "LS0tLS1CRUdJTiBDR...",
 			"server_url": "https://test.elb.us-west-2.amazonaws.com:6443",
 			"object": "cluster",
 			"active": false
-		}]`)
+		}`)
 		srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
 			_, err := rw.Write(testResponse)
 			require.NoError(tt, err)
@@ -40,7 +40,7 @@ func TestRunGet(t *testing.T) {
 		defer os.Remove(configFile)
 
 		// Merge cluster information from active cluster for sandbox into configFile.
-		err := runGet("sandbox", configFile, false, client)
+		err := runGet("sandbox", configFile, false, "", client)
 		assert.NoError(tt, err)
 
 		// Check that current context has not been modified.
@@ -69,7 +69,7 @@ func TestRunGet(t *testing.T) {
 		client := api.NewClient(&configpkg.Config{BaseURL: srv.URL}, tokenGenerator)
 
 		// Attempt to merge new cluster into configFile but this should fail because no cluster has been returned.
-		err := runGet("sandbox", config, false, client)
+		err := runGet("sandbox", config, false, "", client)
 		assert.Error(tt, err)
 		assert.Contains(tt, err.Error(), "failed to get cluster information")
 	})
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/lob/pharos/pkg/pharos/api"
+	"github.com/lob/pharos/pkg/pharos/cli"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// Declare some variables to be used as flags.
+var (
+	importContext    string
+	importKubeConfig string
+)
+
+// ImportCmd implements a CLI command that allows users to register clusters in
+// Pharos from an existing kubeconfig file.
+var ImportCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Registers clusters from a kubeconfig file",
+	Long:  "Registers the clusters referenced by the contexts of an existing kubeconfig file, such as an admin kubeconfig written by kops or eksctl, in Pharos.",
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return cobra.MarkFlagRequired(cmd.Flags(), "kubeconfig")
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := api.ClientFromConfig(pharosConfig)
+		if err != nil {
+			return errors.Wrap(err, "unable to create client from pharos config file")
+		}
+		return runImport(importKubeConfig, importContext, environment, client)
+	},
+}
+
+func runImport(kubeConfigFile string, context string, env string, client *api.Client) error {
+	summary, err := cli.ImportClusters(kubeConfigFile, context, env, client)
+	if err != nil {
+		return errors.Wrap(err, "failed to import clusters")
+	}
+	fmt.Print(summary)
+	return nil
+}
+
+func init() {
+	ImportCmd.Flags().StringVarP(&importKubeConfig, "kubeconfig", "k", "", "kubeconfig file to import clusters from (required)")
+	ImportCmd.Flags().StringVar(&importContext, "context", "", "only import the cluster of this context")
+	ImportCmd.Flags().StringVarP(&environment, "environment", "e", "", "environment of the imported clusters (inferred from the cluster ID by default)")
+}
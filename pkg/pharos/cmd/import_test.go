@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lob/pharos/internal/test"
+	"github.com/lob/pharos/pkg/pharos/api"
+	configpkg "github.com/lob/pharos/pkg/pharos/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunImport(t *testing.T) {
+	// Set up dummy server for testing.
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		_, err := rw.Write([]byte(`{"id": "sandbox-111111"}`))
+		require.NoError(t, err)
+	}))
+	defer srv.Close()
+	tokenGenerator := test.NewGenerator()
+
+	// Set BaseURL in config to be the url of the dummy server.
+	client := api.NewClient(&configpkg.Config{BaseURL: srv.URL}, tokenGenerator)
+
+	t.Run("successfully imports clusters from a kubeconfig file", func(tt *testing.T) {
+		err := runImport("../testdata/import", "", "", client)
+		assert.NoError(tt, err)
+	})
+
+	t.Run("errors when importing from a nonexistent kubeconfig file", func(tt *testing.T) {
+		err := runImport("../testdata/nonexistent", "", "", client)
+		assert.Error(tt, err)
+		assert.Contains(tt, err.Error(), "failed to import clusters")
+	})
+}
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/fatih/color"
+	"github.com/lob/pharos/pkg/pharos/cli"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// NewKubeconfigCmd returns a new cobra.Command with all the necessary kubeconfig
+// sub-commands attached to it.
+func NewKubeconfigCmd() *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "kubeconfig",
+		Short: `Commands for kubeconfig management (run "pharos kubeconfig -h" for a full list of kubeconfig commands)`,
+		Long:  "Commands for managing the kubeconfig files written by Pharos.",
+	}
+
+	cmd.AddCommand(BackupsCmd)
+	cmd.AddCommand(RestoreCmd)
+
+	return cmd
+}
+
+// BackupsCmd implements a CLI command that lists the backups Pharos has taken
+// of a kubeconfig file.
+var BackupsCmd = &cobra.Command{
+	Use:   "backups",
+	Short: "Lists kubeconfig backups",
+	Long:  "Lists the backups Pharos took of the designated kubeconfig file before modifying it, newest first.",
+	RunE:  func(cmd *cobra.Command, args []string) error { return runBackups(file) },
+}
+
+func runBackups(kubeConfigFile string) error {
+	kubeConfigFile = cli.ResolveKubeConfigFile(kubeConfigFile)
+	backups, err := cli.ListBackups(kubeConfigFile)
+	if err != nil {
+		return errors.Wrap(err, "failed to list backups")
+	}
+
+	buf := new(bytes.Buffer)
+	w := tabwriter.NewWriter(buf, 0, 0, 3, ' ', 0)
+	cyan := color.New(color.FgCyan)
+
+	// Add spaces to prevent ANSI escape codes from breaking the tabwriter formatting.
+	_, err = cyan.Fprint(w, "TIMESTAMP\t     TAKEN\t     PATH")
+	if err != nil {
+		return err
+	}
+	for _, backup := range backups {
+		fmt.Fprintf(w, "\n%s\t%s\t%s", backup.Timestamp, backup.Time.Local().Format("2006-01-02 15:04:05"), backup.Path)
+	}
+	fmt.Fprintln(w, "")
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Print(buf.String())
+	return nil
+}
+
+// RestoreCmd implements a CLI command that restores a kubeconfig file from one
+// of its backups.
+var RestoreCmd = &cobra.Command{
+	Use:   "restore [<timestamp>]",
+	Short: "Restores a kubeconfig backup",
+	Long:  "Restores the designated kubeconfig file from the backup with the given timestamp, or from its most recent backup.",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		timestamp := ""
+		if len(args) > 0 {
+			timestamp = args[0]
+		}
+		return runRestore(file, timestamp)
+	},
+}
+
+func runRestore(kubeConfigFile string, timestamp string) error {
+	kubeConfigFile = cli.ResolveKubeConfigFile(kubeConfigFile)
+	backup, err := cli.RestoreBackup(kubeConfigFile, timestamp)
+	if err != nil {
+		return errors.Wrap(err, "failed to restore backup")
+	}
+	fmt.Printf("%s RESTORED %s FROM BACKUP %s\n", color.GreenString("SUCCESS:"), kubeConfigFile, backup.Timestamp)
+	return nil
+}
+
+func init() {
+	BackupsCmd.Flags().StringVarP(&file, "file", "f", "", "specify kubeconfig file to list backups of (defaults to the file Pharos writes new clusters to)")
+	RestoreCmd.Flags().StringVarP(&file, "file", "f", "", "specify kubeconfig file to restore (defaults to the file Pharos writes new clusters to)")
+}
@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"os"
+	"testing"
+
+	"github.com/lob/pharos/internal/test"
+	"github.com/lob/pharos/pkg/pharos/cli"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunRestore(t *testing.T) {
+	t.Run("successfully lists and restores backups", func(tt *testing.T) {
+		// Create temporary test config file and defer cleanup.
+		configFile := test.CopyTestFile(tt, "../testdata", "restore", config)
+		defer os.Remove(configFile)
+
+		err := runSwitch(configFile, "sandbox-111111")
+		require.NoError(tt, err)
+
+		err = runBackups(configFile)
+		assert.NoError(tt, err)
+
+		err = runRestore(configFile, "")
+		assert.NoError(tt, err)
+
+		clusterName, err := cli.CurrentCluster(configFile)
+		assert.NoError(tt, err)
+		assert.Equal(tt, "sandbox", clusterName)
+	})
+
+	t.Run("errors when restoring a backup that does not exist", func(tt *testing.T) {
+		configFile := test.CopyTestFile(tt, "../testdata", "restore", config)
+		defer os.Remove(configFile)
+
+		err := runRestore(configFile, "20190101T000000.000")
+		assert.Error(tt, err)
+		assert.Contains(tt, err.Error(), "failed to restore backup")
+	})
+}
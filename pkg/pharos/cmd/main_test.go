@@ -0,0 +1,23 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/lob/pharos/pkg/pharos/cli"
+)
+
+// TestMain keeps kubeconfig backups taken during tests out of the home
+// directory of whoever runs them.
+func TestMain(m *testing.M) {
+	dir, err := ioutil.TempDir("", "pharos-backups")
+	if err != nil {
+		panic(err)
+	}
+	cli.BackupDir = dir
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
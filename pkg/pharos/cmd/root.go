@@ -4,12 +4,17 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/fatih/color"
+	"github.com/lob/pharos/pkg/pharos/api"
+	"github.com/lob/pharos/pkg/pharos/cli"
+	configpkg "github.com/lob/pharos/pkg/pharos/config"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
 
 // Declare some variables to be used as flags in various commands.
 var (
+	diff          string
 	dryRun        bool
 	environment   string
 	file          string
@@ -24,6 +29,10 @@ var rootCmd = &cobra.Command{
 	Short:   "A tool for managing kubeconfig files.",
 	Long:    "Pharos is a tool for cluster discovery and distribution of kubeconfig files.",
 	Version: pharosVersion,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		loadSettings(pharosConfig)
+		return nil
+	},
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
@@ -38,6 +47,9 @@ func init() {
 		rootCmd.Version = "0.0.0"
 	}
 
+	// Send the version to the Pharos API, unless this is a development build.
+	api.Version = pharosVersion
+
 	rootCmd.Flags().BoolP("version", "v", false, "print Pharos version number")
 	rootCmd.PersistentFlags().StringVarP(&pharosConfig, "config", "c", fmt.Sprintf("%s/.kube/pharos/config", os.Getenv("HOME")), "Pharos config file")
 
@@ -45,9 +57,15 @@ func init() {
 	rootCmd.SilenceUsage = true
 
 	// Add child commands.
+	rootCmd.AddCommand(ApplyCmd)
 	rootCmd.AddCommand(completionCmd)
 	rootCmd.AddCommand(NewClustersCmd())
+	rootCmd.AddCommand(NewKubeconfigCmd())
+	rootCmd.AddCommand(DiffCmd)
+	rootCmd.AddCommand(DiscoverCmd)
+	rootCmd.AddCommand(DoctorCmd)
 	rootCmd.AddCommand(SetupCmd)
+	rootCmd.AddCommand(NewWebhooksCmd())
 }
 
 // argID prevents commands from being run unless exactly one argument (a cluster name or id)
@@ -61,3 +79,30 @@ func argID(args []string) error {
 	}
 	return nil
 }
+
+// loadSettings applies the CLI settings from the Pharos config file. Commands
+// that don't talk to the Pharos API server work without a config file, so the
+// defaults are kept if it can't be loaded.
+func loadSettings(pharosConfig string) {
+	c, err := configpkg.New(pharosConfig)
+	if err != nil {
+		return
+	}
+	if err := c.Load(); err != nil {
+		return
+	}
+
+	if c.BackupLimit > 0 {
+		cli.BackupLimit = c.BackupLimit
+	}
+	if c.ManagedKubeConfig != "" {
+		cli.ManagedKubeConfig = c.ManagedKubeConfig
+	}
+	if c.Naming != nil {
+		if err := c.Naming.Validate(); err != nil {
+			fmt.Fprintf(os.Stderr, "%s IGNORING NAMING TEMPLATES IN %s: %s\n", color.YellowString("WARNING:"), pharosConfig, err)
+		} else {
+			cli.Naming = *c.Naming
+		}
+	}
+}
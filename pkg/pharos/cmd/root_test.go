@@ -1,8 +1,14 @@
 package cmd
 
 import (
+	"os"
 	"testing"
 
+	"github.com/lob/pharos/internal/test"
+	"github.com/lob/pharos/pkg/pharos/cli"
+	configpkg "github.com/lob/pharos/pkg/pharos/config"
+	"github.com/lob/pharos/pkg/util/kubeconfig"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -30,3 +36,34 @@ func TestArgID(t *testing.T) {
 		require.Error(tt, err)
 	})
 }
+
+func TestLoadSettings(t *testing.T) {
+	defer func() { cli.BackupLimit, cli.Naming = cli.DefaultBackupLimit, kubeconfig.DefaultNaming }()
+
+	t.Run("applies the backup limit from the pharos config file", func(tt *testing.T) {
+		configFile := test.CopyTestFile(tt, "../testdata", "settings", cliConfig)
+		defer os.Remove(configFile)
+		err := runSetup(configFile, configpkg.Config{BackupLimit: 3})
+		require.NoError(tt, err)
+
+		loadSettings(configFile)
+		assert.Equal(tt, 3, cli.BackupLimit)
+	})
+
+	t.Run("applies the naming templates from the pharos config file", func(tt *testing.T) {
+		configFile := test.CopyTestFile(tt, "../testdata", "settings", cliConfig)
+		defer os.Remove(configFile)
+		err := runSetup(configFile, configpkg.Config{Naming: &kubeconfig.Naming{Context: "{{.Environment}}-{{short .Region}}"}})
+		require.NoError(tt, err)
+
+		loadSettings(configFile)
+		assert.Equal(tt, "{{.Environment}}-{{short .Region}}", cli.Naming.Context)
+	})
+
+	t.Run("keeps the defaults when the pharos config file does not exist", func(tt *testing.T) {
+		cli.BackupLimit = cli.DefaultBackupLimit
+
+		loadSettings("../testdata/nonexistent")
+		assert.Equal(tt, cli.DefaultBackupLimit, cli.BackupLimit)
+	})
+}
@@ -2,18 +2,25 @@ package cmd
 
 import (
 	"fmt"
+	"os"
 
 	"github.com/fatih/color"
+	"github.com/lob/pharos/pkg/pharos/cli"
 	configpkg "github.com/lob/pharos/pkg/pharos/config"
+	"github.com/lob/pharos/pkg/util/kubeconfig"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
 
 // Declare variables to be used as flags.
 var (
-	awsProfile string
-	awsRoleARN string
-	pharosURL  string
+	awsProfile        string
+	awsRoleARN        string
+	backupLimit       int
+	managedKubeConfig string
+	naming            kubeconfig.Naming
+	pharosURL         string
+	shellIntegration  string
 )
 
 // SetupCmd is the pharos setup command.
@@ -22,11 +29,23 @@ var SetupCmd = &cobra.Command{
 	Short: "Setup Pharos config",
 	Long:  "Setup Pharos configuration file. Overwrites previously saved configuration.",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		return runSetup(pharosConfig, pharosURL, awsProfile, awsRoleARN)
+		if shellIntegration != "" {
+			return runShellIntegration(shellIntegration, os.Getenv("SHELL"))
+		}
+		return runSetup(pharosConfig, configpkg.Config{
+			BaseURL:           pharosURL,
+			AWSProfile:        awsProfile,
+			AssumeRoleARN:     awsRoleARN,
+			BackupLimit:       backupLimit,
+			ManagedKubeConfig: managedKubeConfig,
+			Naming:            &naming,
+		})
 	},
 }
 
-func runSetup(pharosConfig, url, profile, arn string) error {
+// runSetup saves the settings that are set in the given config to the Pharos
+// config file, keeping the settings that were saved before.
+func runSetup(pharosConfig string, settings configpkg.Config) error {
 	c, err := configpkg.New(pharosConfig)
 	if err != nil {
 		return errors.Wrap(err, "unable to create reference to config file")
@@ -39,14 +58,26 @@ func runSetup(pharosConfig, url, profile, arn string) error {
 		fmt.Println("CREATING PHAROS CONFIG FILE...")
 	}
 
-	if url != "" {
-		c.BaseURL = url
+	if settings.BaseURL != "" {
+		c.BaseURL = settings.BaseURL
 	}
-	if profile != "" {
-		c.AWSProfile = profile
+	if settings.AWSProfile != "" {
+		c.AWSProfile = settings.AWSProfile
 	}
-	if arn != "" {
-		c.AssumeRoleARN = arn
+	if settings.AssumeRoleARN != "" {
+		c.AssumeRoleARN = settings.AssumeRoleARN
+	}
+	if settings.ManagedKubeConfig != "" {
+		c.ManagedKubeConfig = settings.ManagedKubeConfig
+	}
+	if settings.BackupLimit > 0 {
+		c.BackupLimit = settings.BackupLimit
+	}
+	if settings.Naming != nil {
+		c.Naming = mergeNaming(c.Naming, *settings.Naming)
+		if err := c.Naming.Validate(); err != nil {
+			return err
+		}
 	}
 
 	err = c.Save()
@@ -57,8 +88,83 @@ func runSetup(pharosConfig, url, profile, arn string) error {
 	return nil
 }
 
+// mergeNaming overrides the saved naming templates and namespaces with the
+// ones that are set. It returns nil if nothing is set at all.
+func mergeNaming(saved *kubeconfig.Naming, n kubeconfig.Naming) *kubeconfig.Naming {
+	var merged kubeconfig.Naming
+	if saved != nil {
+		merged = *saved
+	}
+
+	if n.Cluster != "" {
+		merged.Cluster = n.Cluster
+	}
+	if n.User != "" {
+		merged.User = n.User
+	}
+	if n.Context != "" {
+		merged.Context = n.Context
+	}
+	for environment, namespace := range n.Namespaces {
+		if merged.Namespaces == nil {
+			merged.Namespaces = make(map[string]string)
+		}
+		if namespace == "" {
+			delete(merged.Namespaces, environment)
+		} else {
+			merged.Namespaces[environment] = namespace
+		}
+	}
+
+	if merged.Cluster == "" && merged.User == "" && merged.Context == "" && len(merged.Namespaces) == 0 {
+		return nil
+	}
+	return &merged
+}
+
+// runShellIntegration prints the snippet that adds the Pharos-managed kubeconfig
+// file to $KUBECONFIG, or installs it in the startup file of the given shell.
+func runShellIntegration(mode string, shell string) error {
+	managed := cli.ManagedKubeConfig
+	if managed == "" {
+		managed = cli.DefaultManagedKubeConfig
+	}
+
+	snippet, startupFile, err := cli.ShellIntegration(shell, managed)
+	if err != nil {
+		return errors.Wrap(err, "unable to set up shell integration")
+	}
+
+	switch mode {
+	case "print":
+		fmt.Print(snippet)
+	case "install":
+		installed, err := cli.InstallShellIntegration(snippet, startupFile)
+		if err != nil {
+			return errors.Wrap(err, "unable to install shell integration")
+		}
+		if !installed {
+			fmt.Printf("SHELL INTEGRATION IS ALREADY INSTALLED IN %s\n", startupFile)
+			return nil
+		}
+		fmt.Printf("%s INSTALLED SHELL INTEGRATION IN %s\n", color.GreenString("SUCCESS:"), startupFile)
+	default:
+		return fmt.Errorf("unknown shell integration mode %s, must be print or install", mode)
+	}
+	return nil
+}
+
 func init() {
 	SetupCmd.Flags().StringVarP(&awsProfile, "aws-profile", "p", "", "specify aws profile to use")
 	SetupCmd.Flags().StringVarP(&awsRoleARN, "aws-role-arn", "r", "", "specify aws role ARN to use")
+	SetupCmd.Flags().IntVar(&backupLimit, "backup-limit", 0, fmt.Sprintf("specify number of kubeconfig backups to keep (defaults to %d)", cli.DefaultBackupLimit))
+	SetupCmd.Flags().StringVar(&managedKubeConfig, "managed-kubeconfig", "", fmt.Sprintf("write new clusters to a kubeconfig file of their own (defaults to %s)", cli.DefaultManagedKubeConfig))
+	SetupCmd.Flags().Lookup("managed-kubeconfig").NoOptDefVal = cli.DefaultManagedKubeConfig
+	SetupCmd.Flags().StringVar(&shellIntegration, "shell-integration", "", "print, or install with --shell-integration=install, the snippet that adds the pharos kubeconfig file to $KUBECONFIG")
+	SetupCmd.Flags().Lookup("shell-integration").NoOptDefVal = "print"
+	SetupCmd.Flags().StringVar(&naming.Cluster, "cluster-name-template", "", `specify template to name kubeconfig clusters with, such as "{{.Environment}}-{{short .Region}}" (defaults to "{{.ID}}")`)
+	SetupCmd.Flags().StringVar(&naming.User, "user-name-template", "", `specify template to name kubeconfig users with (defaults to "iam-{{.ID}}")`)
+	SetupCmd.Flags().StringVar(&naming.Context, "context-name-template", "", `specify template to name kubeconfig contexts with (defaults to "{{.ID}}")`)
+	SetupCmd.Flags().StringToStringVar(&naming.Namespaces, "namespace", nil, "specify default namespace of the contexts of an environment as environment=namespace, leaving the namespace empty to remove it")
 	SetupCmd.Flags().StringVarP(&pharosURL, "pharos-url", "u", "", "specify URL of the Pharos server")
 }
@@ -1,12 +1,17 @@
 package cmd
 
 import (
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/lob/pharos/internal/test"
+	"github.com/lob/pharos/pkg/pharos/cli"
 	configpkg "github.com/lob/pharos/pkg/pharos/config"
+	"github.com/lob/pharos/pkg/util/kubeconfig"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestRunSetup(t *testing.T) {
@@ -16,7 +21,7 @@ func TestRunSetup(t *testing.T) {
 		defer os.Remove(configFile)
 
 		// Setup file.
-		err := runSetup(configFile, "egg", "hello", "")
+		err := runSetup(configFile, configpkg.Config{BaseURL: "egg", AWSProfile: "hello"})
 		assert.NoError(tt, err)
 
 		// Check that file setup was successful.
@@ -30,7 +35,7 @@ func TestRunSetup(t *testing.T) {
 		assert.Equal(tt, "", c.AssumeRoleARN)
 
 		// Check that setup doesn't overwrite file.
-		err = runSetup(configFile, "", "blah", "test")
+		err = runSetup(configFile, configpkg.Config{AWSProfile: "blah", AssumeRoleARN: "test", BackupLimit: 5})
 		assert.NoError(tt, err)
 
 		c, err = configpkg.New(configFile)
@@ -41,5 +46,78 @@ func TestRunSetup(t *testing.T) {
 		assert.Equal(tt, "egg", c.BaseURL)
 		assert.Equal(tt, "blah", c.AWSProfile)
 		assert.Equal(tt, "test", c.AssumeRoleARN)
+		assert.Equal(tt, 5, c.BackupLimit)
+	})
+}
+
+func TestRunSetupNaming(t *testing.T) {
+	configFile := test.CopyTestFile(t, "../testdata", "setup", cliConfig)
+	defer os.Remove(configFile)
+
+	load := func(tt *testing.T) *configpkg.Config {
+		c, err := configpkg.New(configFile)
+		require.NoError(tt, err)
+		require.NoError(tt, c.Load())
+		return c
+	}
+
+	t.Run("saves naming templates and namespaces", func(tt *testing.T) {
+		err := runSetup(configFile, configpkg.Config{Naming: &kubeconfig.Naming{
+			Cluster:    "{{.Environment}}-{{short .Region}}",
+			Namespaces: map[string]string{"sandbox": "dev", "production": "web"},
+		}})
+		require.NoError(tt, err)
+
+		err = runSetup(configFile, configpkg.Config{Naming: &kubeconfig.Naming{
+			Context:    "{{.Environment}}-{{short .Region}}",
+			Namespaces: map[string]string{"production": ""},
+		}})
+		require.NoError(tt, err)
+
+		c := load(tt)
+		require.NotNil(tt, c.Naming)
+		assert.Equal(tt, "{{.Environment}}-{{short .Region}}", c.Naming.Cluster)
+		assert.Equal(tt, "{{.Environment}}-{{short .Region}}", c.Naming.Context)
+		assert.Equal(tt, map[string]string{"sandbox": "dev"}, c.Naming.Namespaces)
+	})
+
+	t.Run("errors on invalid naming templates", func(tt *testing.T) {
+		err := runSetup(configFile, configpkg.Config{Naming: &kubeconfig.Naming{User: "{{.ID"}})
+		assert.Error(tt, err)
+		assert.Contains(tt, err.Error(), "invalid user naming template")
+	})
+}
+
+func TestRunShellIntegration(t *testing.T) {
+	originalHome := os.Getenv("HOME")
+	defer os.Setenv("HOME", originalHome)
+
+	t.Run("prints the shell integration snippet", func(tt *testing.T) {
+		err := runShellIntegration("print", "/bin/bash")
+		assert.NoError(tt, err)
+	})
+
+	t.Run("installs the shell integration snippet", func(tt *testing.T) {
+		dir, err := ioutil.TempDir("", "pharos-home")
+		require.NoError(tt, err)
+		defer os.RemoveAll(dir)
+		os.Setenv("HOME", dir)
+
+		err = runShellIntegration("install", "/bin/bash")
+		require.NoError(tt, err)
+
+		raw, err := ioutil.ReadFile(filepath.Join(dir, ".bashrc"))
+		require.NoError(tt, err)
+		assert.Contains(tt, string(raw), cli.DefaultManagedKubeConfig)
+	})
+
+	t.Run("errors on unknown modes and shells", func(tt *testing.T) {
+		err := runShellIntegration("egg", "/bin/bash")
+		assert.Error(tt, err)
+		assert.Contains(tt, err.Error(), "unknown shell integration mode")
+
+		err = runShellIntegration("print", "/bin/tcsh")
+		assert.Error(tt, err)
+		assert.Contains(tt, err.Error(), "unsupported shell")
 	})
 }
@@ -2,7 +2,6 @@ package cmd
 
 import (
 	"fmt"
-	"os"
 
 	"github.com/fatih/color"
 	"github.com/lob/pharos/pkg/pharos/cli"
@@ -32,5 +31,5 @@ func runSwitch(kubeConfigFile string, context string) error {
 }
 
 func init() {
-	SwitchCmd.Flags().StringVarP(&file, "file", "f", fmt.Sprintf("%s/.kube/config", os.Getenv("HOME")), "specify designated kubeconfig file")
+	SwitchCmd.Flags().StringVarP(&file, "file", "f", "", "specify designated kubeconfig file (defaults to $KUBECONFIG or $HOME/.kube/config)")
 }
@@ -1,8 +1,9 @@
 package cmd
 
 import (
-	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/lob/pharos/pkg/pharos/api"
 	"github.com/lob/pharos/pkg/pharos/cli"
@@ -10,8 +11,12 @@ import (
 	"github.com/spf13/cobra"
 )
 
-// Declare variable to be used as a flag.
-var overwrite bool
+// Declare variables to be used as flags.
+var (
+	overwrite bool
+	prune     bool
+	watch     bool
+)
 
 // SyncCmd implements a CLI command that allows users to get cluster information
 // from all currently existing clusters in Pharos and merge it into an existing kubeconfig file.
@@ -24,21 +29,52 @@ var SyncCmd = &cobra.Command{
 		if err != nil {
 			return errors.Wrap(err, "unable to create client from pharos config file")
 		}
-		return runSync(file, inactive, dryRun, overwrite, client)
+		if watch {
+			return runWatch(file, inactive, dryRun, diff, overwrite, prune, client, stopOnSignal())
+		}
+		return runSync(file, inactive, dryRun, diff, overwrite, prune, client)
 	},
 }
 
-func runSync(kubeConfigFile string, inactive bool, dryRun bool, overwrite bool, client *api.Client) error {
-	err := cli.SyncClusters(kubeConfigFile, inactive, dryRun, overwrite, client)
+func runSync(kubeConfigFile string, inactive bool, dryRun bool, diff string, overwrite bool, prune bool, client *api.Client) error {
+	err := cli.SyncClusters(kubeConfigFile, inactive, dryRun, diff, overwrite, prune, client)
 	if err != nil {
 		return errors.Wrap(err, "failed to sync clusters")
 	}
 	return nil
 }
 
+func runWatch(kubeConfigFile string, inactive bool, dryRun bool, diff string, overwrite bool, prune bool, client *api.Client, stop <-chan struct{}) error {
+	if dryRun || diff != "" {
+		return errors.New("--watch can't be used with --dry-run or --diff")
+	}
+	err := cli.WatchClusters(kubeConfigFile, inactive, overwrite, prune, client, stop)
+	if err != nil {
+		return errors.Wrap(err, "failed to watch clusters")
+	}
+	return nil
+}
+
+// stopOnSignal returns a channel that is closed when the process is
+// interrupted or terminated.
+func stopOnSignal() <-chan struct{} {
+	stop := make(chan struct{})
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-signals
+		close(stop)
+	}()
+	return stop
+}
+
 func init() {
 	SyncCmd.Flags().BoolVarP(&inactive, "inactive", "i", false, "specify whether to sync inactive clusters")
 	SyncCmd.Flags().BoolVarP(&dryRun, "dry-run", "d", false, "prints the resulting kubeconfig to terminal without any other action")
+	SyncCmd.Flags().StringVar(&diff, "diff", "", "prints the changes to the kubeconfig file as a text or json diff without writing them")
+	SyncCmd.Flags().Lookup("diff").NoOptDefVal = cli.DiffText
 	SyncCmd.Flags().BoolVarP(&overwrite, "overwrite", "o", false, "overwrite the kubeconfig file with retrieved clusters")
-	SyncCmd.Flags().StringVarP(&file, "file", "f", fmt.Sprintf("%s/.kube/config", os.Getenv("HOME")), "specify kubeconfig file to merge into")
+	SyncCmd.Flags().BoolVar(&prune, "prune", false, "remove entries written by pharos for clusters that were not retrieved")
+	SyncCmd.Flags().BoolVarP(&watch, "watch", "w", false, "keep syncing clusters whenever they change until interrupted")
+	SyncCmd.Flags().StringVarP(&file, "file", "f", "", "specify kubeconfig file to merge into (defaults to $KUBECONFIG or $HOME/.kube/config)")
 }
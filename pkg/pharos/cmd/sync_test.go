@@ -47,7 +47,7 @@ func TestRunSync(t *testing.T) {
 		defer os.Remove(configFile)
 
 		// Merge cluster information from active cluster for sandbox into configFile.
-		err := runSync(configFile, false, false, false, client)
+		err := runSync(configFile, false, false, "", false, false, client)
 		assert.NoError(tt, err)
 
 		// Check that current context has not been modified.
@@ -76,8 +76,19 @@ func TestRunSync(t *testing.T) {
 		client := api.NewClient(&configpkg.Config{BaseURL: srv.URL}, tokenGenerator)
 
 		// Attempt to merge new cluster into configFile but this should fail because no cluster has been returned.
-		err := runSync(config, false, false, false, client)
+		err := runSync(config, false, false, "", false, false, client)
 		assert.Error(tt, err)
 		assert.Contains(tt, err.Error(), "failed to sync clusters")
 	})
+
+	t.Run("errors when watching with --dry-run or --diff", func(tt *testing.T) {
+		client := api.NewClient(&configpkg.Config{BaseURL: ""}, test.NewGenerator())
+
+		err := runWatch(config, false, true, "", false, false, client, nil)
+		assert.Error(tt, err)
+		assert.Contains(tt, err.Error(), "--watch can't be used with --dry-run or --diff")
+
+		err = runWatch(config, false, false, cli.DiffText, false, false, client, nil)
+		assert.Error(tt, err)
+	})
 }
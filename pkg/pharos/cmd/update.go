@@ -5,6 +5,7 @@ import (
 
 	"github.com/fatih/color"
 	"github.com/lob/pharos/pkg/pharos/api"
+	"github.com/lob/pharos/pkg/util/model"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
@@ -17,7 +18,7 @@ var active bool
 var UpdateCmd = &cobra.Command{
 	Use:   "update <cluster_id>",
 	Short: "Updates the status of a cluster",
-	Long:  "Updates the status of the specified cluster in Pharos.",
+	Long:  "Updates the status of the specified cluster, or the active cluster of the specified environment, in Pharos.",
 	Args:  func(cmd *cobra.Command, args []string) error { return argID(args) },
 	RunE: func(cmd *cobra.Command, args []string) error {
 		client, err := api.ClientFromConfig(pharosConfig)
@@ -28,15 +29,49 @@ var UpdateCmd = &cobra.Command{
 	},
 }
 
-func runUpdate(id string, active bool, client *api.Client) error {
-	cluster, err := client.UpdateCluster(id, active)
+// updateAttempts is how many times a change that is safe to repeat, such as
+// extending the expiry of a cluster, is attempted when the cluster keeps being
+// modified by someone else in the meantime.
+const updateAttempts = 3
+
+func runUpdate(name string, active bool, client *api.Client) error {
+	cluster, err := client.ResolveCluster(name)
+	if err != nil {
+		return err
+	}
+
+	// Updating is not retried if the cluster, or the active cluster of its
+	// environment, changed since it was resolved, as activating the cluster
+	// again would undo a change the user hasn't seen.
+	updated, err := client.UpdateCluster(cluster.ID, active, cluster.ResourceVersion)
+	if api.IsPreconditionFailed(err) {
+		return updateConflict(name, cluster, err, client)
+	}
 	if err != nil {
 		return err
 	}
-	fmt.Printf("%s UPDATED CLUSTER %s ACTIVE STATUS TO %t\n", color.GreenString("SUCCESS:"), cluster.ID, cluster.Active)
+	fmt.Printf("%s UPDATED CLUSTER %s ACTIVE STATUS TO %t\n", color.GreenString("SUCCESS:"), updated.ID, updated.Active)
 	return nil
 }
 
+// updateConflict returns the error for an update that was refused because the
+// cluster changed since it was resolved, with the reason the API gave and the
+// current active cluster of its environment, so that the user can check the
+// change before trying again.
+func updateConflict(name string, cluster model.Cluster, err error, client *api.Client) error {
+	reason := "cluster has been modified"
+	var apiErr *model.Error
+	if errors.As(err, &apiErr) {
+		reason = apiErr.Message
+	}
+
+	current := "has no active cluster"
+	if active, err := client.ResolveCluster(cluster.Environment); err == nil {
+		current = fmt.Sprintf("now has active cluster %s", active.ID)
+	}
+	return errors.Errorf("cluster %s changed while it was being updated (%s), environment %s %s, check it and try again", name, reason, cluster.Environment, current)
+}
+
 func init() {
 	UpdateCmd.Flags().BoolVarP(&active, "active", "a", true, "specify whether to set the cluster status to active")
 }
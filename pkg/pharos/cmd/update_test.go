@@ -54,4 +54,45 @@ func TestRunUpdate(t *testing.T) {
 		err := runUpdate("sandbox-333333", false, client)
 		assert.Error(tt, err)
 	})
+
+	t.Run("reports a conflict without retrying", func(tt *testing.T) {
+		// Set up dummy server for testing.
+		cluster := []byte(`{
+			"id":                     "sandbox-333333",
+			"environment":            "sandbox",
+			"cluster_authority_data": "LS0tLS1CRUdJTiBDR...",
+			"server_url":             "https://test.elb.us-west-2.amazonaws.com:6443",
+			"object":                 "cluster",
+			"active":                 false,
+			"resource_version":       5
+		}`)
+
+		var ifMatch []string
+		srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			var err error
+			switch r.Method + " " + r.URL.Path {
+			case "POST /v1/clusters/sandbox-333333":
+				ifMatch = append(ifMatch, r.Header.Get("If-Match"))
+				rw.WriteHeader(http.StatusPreconditionFailed)
+				_, err = rw.Write([]byte(`{"error":{"code":"precondition_failed","message":"active cluster sandbox-444444 of environment sandbox has been modified","status_code":412}}`))
+			case "GET /v1/resolve/sandbox":
+				_, err = rw.Write([]byte(`{"id": "sandbox-444444", "environment": "sandbox", "active": true, "resource_version": 7}`))
+			default:
+				_, err = rw.Write(cluster)
+			}
+			require.NoError(tt, err)
+		}))
+		defer srv.Close()
+		tokenGenerator := test.NewGenerator()
+
+		// Set BaseURL in config to be the url of the dummy server.
+		client := api.NewClient(&configpkg.Config{BaseURL: srv.URL}, tokenGenerator)
+
+		err := runUpdate("sandbox-333333", true, client)
+		assert.Error(tt, err)
+		assert.Contains(tt, err.Error(), "cluster sandbox-333333 changed while it was being updated")
+		assert.Contains(tt, err.Error(), "active cluster sandbox-444444 of environment sandbox has been modified")
+		assert.Contains(tt, err.Error(), "environment sandbox now has active cluster sandbox-444444")
+		assert.Equal(tt, []string{`"5"`}, ifMatch)
+	})
 }
@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/fatih/color"
+	"github.com/lob/pharos/pkg/pharos/api"
+	"github.com/lob/pharos/pkg/pharos/cli"
+	"github.com/lob/pharos/pkg/util/model"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// Declare some variables to be used as flags.
+var (
+	events []string
+	secret string
+)
+
+// NewWebhooksCmd returns a new cobra.Command with all the necessary webhooks
+// sub-commands attached to it.
+func NewWebhooksCmd() *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "webhooks",
+		Short: `Commands for webhook management (run "pharos webhooks -h" for a full list of webhook commands)`,
+		Long:  "Commands for managing the webhooks that Pharos notifies of cluster lifecycle events.",
+	}
+
+	cmd.AddCommand(WebhooksCreateCmd)
+	cmd.AddCommand(WebhooksDeadLettersCmd)
+	cmd.AddCommand(WebhooksDeleteCmd)
+	cmd.AddCommand(WebhooksListCmd)
+
+	return cmd
+}
+
+// WebhooksListCmd implements a CLI command that lists the webhooks registered
+// with Pharos.
+var WebhooksListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Retrieves a list of all webhooks",
+	Long:  "Retrieves a list of all webhooks registered with Pharos and the events they are subscribed to.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := api.ClientFromConfig(pharosConfig)
+		if err != nil {
+			return errors.Wrap(err, "unable to create client from pharos config file")
+		}
+		return runWebhooksList(client)
+	},
+}
+
+func runWebhooksList(client *api.Client) error {
+	webhooks, err := cli.ListWebhooks(client)
+	if err != nil {
+		return errors.Wrap(err, "failed to list webhooks")
+	}
+	fmt.Print(webhooks)
+	return nil
+}
+
+// WebhooksCreateCmd implements a CLI command that subscribes a URL to cluster
+// lifecycle events.
+var WebhooksCreateCmd = &cobra.Command{
+	Use:   "create <url>",
+	Short: "Creates a webhook",
+	Long:  "Creates a webhook that Pharos POSTs the specified cluster lifecycle events to, signed with its secret.",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := api.ClientFromConfig(pharosConfig)
+		if err != nil {
+			return errors.Wrap(err, "unable to create client from pharos config file")
+		}
+		return runWebhooksCreate(args[0], events, secret, client)
+	},
+}
+
+func runWebhooksCreate(url string, events []string, secret string, client *api.Client) error {
+	webhook, err := client.CreateWebhook(api.Webhook{URL: url, Events: events, Secret: secret})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s CREATED WEBHOOK %d\n", color.GreenString("SUCCESS:"), webhook.ID)
+	fmt.Printf("Payloads are signed with the secret %s\n", webhook.Secret)
+	return nil
+}
+
+// WebhooksDeleteCmd implements a CLI command that deletes a webhook.
+var WebhooksDeleteCmd = &cobra.Command{
+	Use:   "delete <webhook_id>",
+	Short: "Deletes the specified webhook",
+	Long:  "Deletes the specified webhook along with its pending deliveries and dead letters.",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := api.ClientFromConfig(pharosConfig)
+		if err != nil {
+			return errors.Wrap(err, "unable to create client from pharos config file")
+		}
+		return runWebhooksDelete(args[0], client)
+	},
+}
+
+func runWebhooksDelete(id string, client *api.Client) error {
+	webhook, err := client.DeleteWebhook(id)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s DELETED WEBHOOK %d\n", color.GreenString("SUCCESS:"), webhook.ID)
+	return nil
+}
+
+// WebhooksDeadLettersCmd implements a CLI command that lists the events that
+// could not be delivered to a webhook.
+var WebhooksDeadLettersCmd = &cobra.Command{
+	Use:   "dead-letters <webhook_id>",
+	Short: "Lists undeliverable events",
+	Long:  "Lists the events that could not be delivered to the specified webhook after every retry, newest first.",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := api.ClientFromConfig(pharosConfig)
+		if err != nil {
+			return errors.Wrap(err, "unable to create client from pharos config file")
+		}
+		return runWebhooksDeadLetters(args[0], client)
+	},
+}
+
+func runWebhooksDeadLetters(id string, client *api.Client) error {
+	deadLetters, err := cli.ListDeadLetters(id, client)
+	if err != nil {
+		return errors.Wrap(err, "failed to list dead letters")
+	}
+	fmt.Print(deadLetters)
+	return nil
+}
+
+func init() {
+	WebhooksCreateCmd.Flags().StringSliceVarP(&events, "event", "e", model.Events, "events to subscribe to")
+	WebhooksCreateCmd.Flags().StringVarP(&secret, "secret", "s", "", "secret to sign payloads with (generated if not given)")
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lob/pharos/internal/test"
+	"github.com/lob/pharos/pkg/pharos/api"
+	configpkg "github.com/lob/pharos/pkg/pharos/config"
+	"github.com/lob/pharos/pkg/util/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunWebhooks(t *testing.T) {
+	// Set up dummy server for testing.
+	var created api.Webhook
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			err := json.NewDecoder(r.Body).Decode(&created)
+			require.NoError(t, err)
+		}
+
+		response := []byte(`{"id": 1, "url": "https://hooks.example.com/pharos", "events": ["cluster.created"], "secret": "secret"}`)
+		if r.Method == http.MethodGet {
+			response = []byte(`[]`)
+		}
+		_, err := rw.Write(response)
+		require.NoError(t, err)
+	}))
+	defer srv.Close()
+	tokenGenerator := test.NewGenerator()
+
+	// Set BaseURL in config to be the url of the dummy server.
+	client := api.NewClient(&configpkg.Config{BaseURL: srv.URL}, tokenGenerator)
+
+	t.Run("successfully creates a webhook", func(tt *testing.T) {
+		err := runWebhooksCreate("https://hooks.example.com/pharos", []string{model.EventClusterCreated}, "", client)
+		assert.NoError(tt, err)
+		assert.Equal(tt, api.Webhook{URL: "https://hooks.example.com/pharos", Events: []string{model.EventClusterCreated}}, created)
+	})
+
+	t.Run("successfully lists webhooks and dead letters", func(tt *testing.T) {
+		err := runWebhooksList(client)
+		assert.NoError(tt, err)
+
+		err = runWebhooksDeadLetters("1", client)
+		assert.NoError(tt, err)
+	})
+
+	t.Run("successfully deletes a webhook", func(tt *testing.T) {
+		err := runWebhooksDelete("1", client)
+		assert.NoError(tt, err)
+	})
+}
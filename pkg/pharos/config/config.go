@@ -7,6 +7,8 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+
+	"github.com/lob/pharos/pkg/util/kubeconfig"
 )
 
 // Config contains the configuration for this CLI.
@@ -15,7 +17,17 @@ type Config struct {
 	BaseURL       string `json:"base_url"`
 	AWSProfile    string `json:"aws_profile"`
 	AssumeRoleARN string `json:"assume_role_arn"`
-	filePath      string
+	BackupLimit   int    `json:"backup_limit,omitempty"`
+
+	// ManagedKubeConfig is the kubeconfig file that Pharos writes new clusters
+	// to when kubeconfig files are listed in $KUBECONFIG.
+	ManagedKubeConfig string `json:"managed_kubeconfig,omitempty"`
+
+	// Naming holds the templates that kubeconfig entries are named with and
+	// the default namespace of each environment.
+	Naming *kubeconfig.Naming `json:"naming,omitempty"`
+
+	filePath string
 }
 
 const (
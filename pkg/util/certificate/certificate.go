@@ -0,0 +1,61 @@
+// Package certificate contains helpers for inspecting the certificate
+// authority data stored with each cluster.
+package certificate
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Parse decodes base64 encoded, PEM formatted cluster authority data into the
+// x509 certificates it contains.
+func Parse(clusterAuthorityData string) ([]*x509.Certificate, error) {
+	data, err := base64.StdEncoding.DecodeString(clusterAuthorityData)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to decode cluster authority data")
+	}
+
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to parse certificate")
+		}
+		certs = append(certs, cert)
+	}
+
+	if len(certs) == 0 {
+		return nil, errors.New("no certificates found in cluster authority data")
+	}
+	return certs, nil
+}
+
+// EarliestExpiry returns the earliest NotAfter time of the certificates in the
+// given cluster authority data.
+func EarliestExpiry(clusterAuthorityData string) (time.Time, error) {
+	certs, err := Parse(clusterAuthorityData)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	earliest := certs[0].NotAfter
+	for _, cert := range certs[1:] {
+		if cert.NotAfter.Before(earliest) {
+			earliest = cert.NotAfter
+		}
+	}
+	return earliest.UTC(), nil
+}
@@ -0,0 +1,38 @@
+package certificate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lob/pharos/internal/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEarliestExpiry(t *testing.T) {
+	soon := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second)
+	later := time.Now().Add(365 * 24 * time.Hour).UTC().Truncate(time.Second)
+
+	t.Run("returns the expiry of a single certificate", func(tt *testing.T) {
+		expiry, err := EarliestExpiry(test.NewClusterAuthorityData(tt, later))
+		assert.NoError(tt, err)
+		assert.Equal(tt, later, expiry)
+	})
+
+	t.Run("returns the earliest expiry of a certificate bundle", func(tt *testing.T) {
+		expiry, err := EarliestExpiry(test.NewClusterAuthorityData(tt, later, soon))
+		assert.NoError(tt, err)
+		assert.Equal(tt, soon, expiry)
+	})
+
+	t.Run("errors when the data is not base64 encoded", func(tt *testing.T) {
+		_, err := EarliestExpiry("!@#$")
+		assert.Error(tt, err)
+		assert.Contains(tt, err.Error(), "unable to decode cluster authority data")
+	})
+
+	t.Run("errors when the data does not contain certificates", func(tt *testing.T) {
+		_, err := EarliestExpiry("dGVzdA==")
+		assert.Error(tt, err)
+		assert.Contains(tt, err.Error(), "no certificates found")
+	})
+}
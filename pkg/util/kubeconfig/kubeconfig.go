@@ -0,0 +1,146 @@
+// Package kubeconfig renders kubeconfig entries for Pharos clusters. It is
+// shared by the Pharos CLI and the /kubeconfig endpoint of the Pharos API
+// server so that both produce the same kubeconfig for the same clusters.
+package kubeconfig
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"sort"
+
+	"github.com/lob/pharos/pkg/util/model"
+	"k8s.io/apimachinery/pkg/conversion"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"k8s.io/client-go/tools/clientcmd/api/latest"
+)
+
+// ExtensionName is the name of the extension that marks the kubeconfig entries
+// written by Pharos.
+const ExtensionName = "pharos"
+
+// Extension identifies the cluster that a kubeconfig entry was written for,
+// and whether the entry is an alias such as an environment context. Its fields
+// are in alphabetical order so that it serializes the same way after a round
+// trip through a YAML kubeconfig file.
+type Extension struct {
+	Alias       bool   `json:"alias,omitempty"`
+	ClusterID   string `json:"cluster_id"`
+	Environment string `json:"environment"`
+}
+
+func init() {
+	// client-go decodes kubeconfig extensions into *runtime.Unknown but can't
+	// convert them back when writing a kubeconfig, so register the conversion.
+	utilruntime.Must(latest.Scheme.AddConversionFunc((*runtime.Unknown)(nil), (*runtime.RawExtension)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		b.(*runtime.RawExtension).Raw = a.(*runtime.Unknown).Raw
+		return nil
+	}))
+}
+
+// Prune removes the clusters, users and contexts written by Pharos for clusters
+// other than the given ones, and returns the IDs of the removed clusters. Entries
+// that weren't written by Pharos are never removed, and neither is the cluster
+// of the current context.
+func Prune(kubeConfig *clientcmdapi.Config, clusters []model.Cluster) []string {
+	keep := make(map[string]bool, len(clusters))
+	for _, cluster := range clusters {
+		keep[cluster.ID] = true
+	}
+	if context, ok := kubeConfig.Contexts[kubeConfig.CurrentContext]; ok {
+		if ext, ok := Managed(context.Extensions); ok {
+			keep[ext.ClusterID] = true
+		}
+	}
+
+	pruned := make(map[string]bool)
+	for name, cluster := range kubeConfig.Clusters {
+		if ext, ok := Managed(cluster.Extensions); ok && !keep[ext.ClusterID] {
+			delete(kubeConfig.Clusters, name)
+			pruned[ext.ClusterID] = true
+		}
+	}
+	for name, user := range kubeConfig.AuthInfos {
+		if ext, ok := Managed(user.Extensions); ok && !keep[ext.ClusterID] {
+			delete(kubeConfig.AuthInfos, name)
+			pruned[ext.ClusterID] = true
+		}
+	}
+	for name, context := range kubeConfig.Contexts {
+		if ext, ok := Managed(context.Extensions); ok && !keep[ext.ClusterID] {
+			delete(kubeConfig.Contexts, name)
+			pruned[ext.ClusterID] = true
+		}
+	}
+
+	ids := make([]string, 0, len(pruned))
+	for id := range pruned {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Managed returns the Extension of a kubeconfig entry written by Pharos. It
+// returns false for entries that weren't written by Pharos.
+func Managed(extensions map[string]runtime.Object) (Extension, bool) {
+	var ext Extension
+
+	unknown, ok := extensions[ExtensionName].(*runtime.Unknown)
+	if !ok {
+		return ext, false
+	}
+	if err := json.Unmarshal(unknown.Raw, &ext); err != nil || ext.ClusterID == "" {
+		return ext, false
+	}
+	return ext, true
+}
+
+// annotate adds an Extension for a cluster to the extensions of a kubeconfig
+// entry.
+func annotate(extensions map[string]runtime.Object, cluster model.Cluster, alias bool) {
+	raw, _ := json.Marshal(Extension{Alias: alias, ClusterID: cluster.ID, Environment: cluster.Environment})
+	extensions[ExtensionName] = &runtime.Unknown{Raw: raw, ContentType: runtime.ContentTypeJSON}
+}
+
+// NewContext returns a pointer to a new kubeconfig context with specified cluster and user.
+func NewContext(id string, user string) *clientcmdapi.Context {
+	context := clientcmdapi.NewContext()
+	context.Cluster = id
+	context.AuthInfo = user
+
+	return context
+}
+
+// NewUser returns a pointer to a new kubeconfig user for a specified cluster.
+// The id given should always be of form "[environment]-[suffix]".
+func NewUser(id string, environment string) *clientcmdapi.AuthInfo {
+	user := clientcmdapi.NewAuthInfo()
+
+	// Add exec config.
+	var exec clientcmdapi.ExecConfig
+	exec.Command = "aws-iam-authenticator"
+	exec.APIVersion = "client.authentication.k8s.io/v1alpha1"
+	exec.Args = []string{"token", "-i", id}
+	user.Exec = &exec
+
+	// Add env variables to exec config.
+	var env clientcmdapi.ExecEnvVar
+	env.Name = "AWS_PROFILE"
+	env.Value = environment
+	exec.Env = []clientcmdapi.ExecEnvVar{env}
+
+	return user
+}
+
+// NewCluster returns a pointer to a new clientcmdapi.Cluster containing
+// information from a cluster.
+func NewCluster(c model.Cluster) *clientcmdapi.Cluster {
+	clusterAuthorityData, _ := base64.StdEncoding.DecodeString(c.ClusterAuthorityData)
+
+	cluster := clientcmdapi.NewCluster()
+	cluster.Server = c.ServerURL
+	cluster.CertificateAuthorityData = clusterAuthorityData
+	return cluster
+}
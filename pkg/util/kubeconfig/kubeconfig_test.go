@@ -0,0 +1,99 @@
+package kubeconfig
+
+import (
+	"testing"
+
+	"github.com/lob/pharos/pkg/util/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestNew(t *testing.T) {
+	clusters := []model.Cluster{
+		{ID: "sandbox-111111", Environment: "sandbox", ServerURL: "https://sandbox-111111.example.com", ClusterAuthorityData: "dGVzdA==", Active: true},
+		{ID: "sandbox-222222", Environment: "sandbox", ServerURL: "https://sandbox-222222.example.com", ClusterAuthorityData: "dGVzdA=="},
+	}
+
+	kubeConfig, err := DefaultNaming.New(clusters)
+	require.NoError(t, err)
+
+	t.Run("adds a cluster, user and context for each cluster", func(tt *testing.T) {
+		require.Contains(tt, kubeConfig.Clusters, "sandbox-222222")
+		assert.Equal(tt, "https://sandbox-222222.example.com", kubeConfig.Clusters["sandbox-222222"].Server)
+		assert.Equal(tt, []byte("test"), kubeConfig.Clusters["sandbox-222222"].CertificateAuthorityData)
+
+		require.Contains(tt, kubeConfig.AuthInfos, "iam-sandbox-222222")
+		user := kubeConfig.AuthInfos["iam-sandbox-222222"]
+		assert.Equal(tt, "aws-iam-authenticator", user.Exec.Command)
+		assert.Equal(tt, []string{"token", "-i", "sandbox-222222"}, user.Exec.Args)
+		assert.Equal(tt, "sandbox", user.Exec.Env[0].Value)
+
+		require.Contains(tt, kubeConfig.Contexts, "sandbox-222222")
+		assert.Equal(tt, "sandbox-222222", kubeConfig.Contexts["sandbox-222222"].Cluster)
+		assert.Equal(tt, "iam-sandbox-222222", kubeConfig.Contexts["sandbox-222222"].AuthInfo)
+	})
+
+	t.Run("adds an environment context for active clusters", func(tt *testing.T) {
+		assert.Len(tt, kubeConfig.Contexts, 3)
+		require.Contains(tt, kubeConfig.Contexts, "sandbox")
+		assert.Equal(tt, "sandbox-111111", kubeConfig.Contexts["sandbox"].Cluster)
+	})
+}
+
+func TestManaged(t *testing.T) {
+	cluster := model.Cluster{ID: "sandbox-111111", Environment: "sandbox", ServerURL: "https://sandbox-111111.example.com", ClusterAuthorityData: "dGVzdA==", Active: true}
+
+	t.Run("annotates entries with the cluster they were written for", func(tt *testing.T) {
+		kubeConfig, err := DefaultNaming.New([]model.Cluster{cluster})
+		require.NoError(tt, err)
+
+		ext, ok := Managed(kubeConfig.Clusters["sandbox-111111"].Extensions)
+		assert.True(tt, ok)
+		assert.Equal(tt, Extension{ClusterID: "sandbox-111111", Environment: "sandbox"}, ext)
+
+		_, ok = Managed(kubeConfig.AuthInfos["iam-sandbox-111111"].Extensions)
+		assert.True(tt, ok)
+		_, ok = Managed(kubeConfig.Contexts["sandbox"].Extensions)
+		assert.True(tt, ok)
+	})
+
+	t.Run("keeps annotations through a round trip through a kubeconfig file", func(tt *testing.T) {
+		kubeConfig, err := DefaultNaming.New([]model.Cluster{cluster})
+		require.NoError(tt, err)
+		raw, err := clientcmd.Write(*kubeConfig)
+		require.NoError(tt, err)
+		kubeConfig, err = clientcmd.Load(raw)
+		require.NoError(tt, err)
+
+		ext, ok := Managed(kubeConfig.Clusters["sandbox-111111"].Extensions)
+		assert.True(tt, ok)
+		assert.Equal(tt, "sandbox-111111", ext.ClusterID)
+	})
+
+	t.Run("does not report entries written by others as managed", func(tt *testing.T) {
+		_, ok := Managed(clientcmdapi.NewCluster().Extensions)
+		assert.False(tt, ok)
+	})
+}
+
+func TestPrune(t *testing.T) {
+	active := model.Cluster{ID: "sandbox-111111", Environment: "sandbox", Active: true}
+	removed := model.Cluster{ID: "sandbox-222222", Environment: "sandbox"}
+	current := model.Cluster{ID: "production-333333", Environment: "production"}
+
+	kubeConfig, err := DefaultNaming.New([]model.Cluster{active, removed, current})
+	require.NoError(t, err)
+	kubeConfig.CurrentContext = "production-333333"
+	kubeConfig.Clusters["other"] = clientcmdapi.NewCluster()
+
+	pruned := Prune(kubeConfig, []model.Cluster{active})
+	assert.Equal(t, []string{"sandbox-222222"}, pruned)
+	assert.Contains(t, kubeConfig.Clusters, "sandbox-111111")
+	assert.NotContains(t, kubeConfig.Clusters, "sandbox-222222")
+	assert.NotContains(t, kubeConfig.AuthInfos, "iam-sandbox-222222")
+	assert.NotContains(t, kubeConfig.Contexts, "sandbox-222222")
+	assert.Contains(t, kubeConfig.Clusters, "production-333333")
+	assert.Contains(t, kubeConfig.Clusters, "other")
+}
@@ -0,0 +1,326 @@
+package kubeconfig
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/lob/pharos/pkg/util/model"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// DefaultNaming names clusters and contexts after the cluster ID and users
+// "iam-<cluster ID>".
+var DefaultNaming = Naming{
+	Cluster: "{{.ID}}",
+	User:    "iam-{{.ID}}",
+	Context: "{{.ID}}",
+}
+
+// regionPattern matches the AWS region in the server URL of an EKS cluster.
+var regionPattern = regexp.MustCompile(`[a-z]{2}(-gov)?-[a-z]+-\d`)
+
+// regionAbbreviations shortens the directions in AWS region names.
+var regionAbbreviations = map[string]string{
+	"central":   "c",
+	"east":      "e",
+	"north":     "n",
+	"northeast": "ne",
+	"northwest": "nw",
+	"south":     "s",
+	"southeast": "se",
+	"southwest": "sw",
+	"west":      "w",
+}
+
+// Naming holds the text/template templates that the clusters, users and
+// contexts written to kubeconfigs are named with, and the default namespace
+// of the contexts of each environment. Templates are executed with NameData,
+// and can shorten regions with the short function, so that
+// "{{.Environment}}-{{short .Region}}" names a production cluster in us-west-2
+// "production-usw2". Empty templates fall back to DefaultNaming.
+type Naming struct {
+	Cluster    string            `json:"cluster,omitempty"`
+	User       string            `json:"user,omitempty"`
+	Context    string            `json:"context,omitempty"`
+	Namespaces map[string]string `json:"namespaces,omitempty"`
+}
+
+// NameData is the data that naming templates are executed with.
+type NameData struct {
+	ID          string
+	Environment string
+	Region      string
+}
+
+// Names are the names of the kubeconfig entries written for a cluster.
+type Names struct {
+	Cluster string
+	User    string
+	Context string
+}
+
+// Validate checks that every naming template can be parsed.
+func (n Naming) Validate() error {
+	n = n.withDefaults()
+	for kind, text := range map[string]string{"cluster": n.Cluster, "user": n.User, "context": n.Context} {
+		if _, err := newTemplate(kind, text); err != nil {
+			return fmt.Errorf("invalid %s naming template: %s", kind, err)
+		}
+	}
+	return nil
+}
+
+// Names returns the names of the kubeconfig entries for a cluster.
+func (n Naming) Names(cluster model.Cluster) (Names, error) {
+	n = n.withDefaults()
+	data := NameData{cluster.ID, cluster.Environment, Region(cluster)}
+
+	var names Names
+	for _, t := range []struct {
+		kind string
+		text string
+		name *string
+	}{
+		{"cluster", n.Cluster, &names.Cluster},
+		{"user", n.User, &names.User},
+		{"context", n.Context, &names.Context},
+	} {
+		tmpl, err := newTemplate(t.kind, t.text)
+		if err != nil {
+			return names, fmt.Errorf("invalid %s naming template: %s", t.kind, err)
+		}
+
+		buf := new(bytes.Buffer)
+		if err := tmpl.Execute(buf, data); err != nil {
+			return names, fmt.Errorf("unable to name %s of cluster %s: %s", t.kind, cluster.ID, err)
+		}
+		if buf.Len() == 0 {
+			return names, fmt.Errorf("%s naming template gives cluster %s an empty name", t.kind, cluster.ID)
+		}
+		*t.name = buf.String()
+	}
+	return names, nil
+}
+
+// New returns a new kubeconfig containing the given clusters.
+func (n Naming) New(clusters []model.Cluster) (*clientcmdapi.Config, error) {
+	kubeConfig := clientcmdapi.NewConfig()
+	return kubeConfig, n.Merge(kubeConfig, clusters)
+}
+
+// Merge adds a cluster, user and context for each of the given clusters to a
+// kubeconfig. Active clusters also get a context named after their
+// environment. There should never be more than one active cluster for each
+// environment. It errors without changing the kubeconfig if the naming
+// templates give two of the clusters the same name, or give one of them the
+// name of an entry written for another cluster.
+func (n Naming) Merge(kubeConfig *clientcmdapi.Config, clusters []model.Cluster) error {
+	merging := make(map[string]bool, len(clusters))
+	for _, cluster := range clusters {
+		merging[cluster.ID] = true
+	}
+
+	all := make([]Names, len(clusters))
+	owners := make(map[string]string)
+	claim := func(cluster model.Cluster, kind, name string) error {
+		key := kind + " " + name
+		if other, ok := owners[key]; ok && other != cluster.ID {
+			return duplicateName(other, cluster.ID, kind, name)
+		}
+		owners[key] = cluster.ID
+		return nil
+	}
+	for i, cluster := range clusters {
+		names, err := n.Names(cluster)
+		if err != nil {
+			return err
+		}
+		if err := checkNames(kubeConfig, cluster.ID, names, merging); err != nil {
+			return err
+		}
+		all[i] = names
+		for _, entry := range []struct{ kind, name string }{
+			{"cluster", names.Cluster},
+			{"user", names.User},
+			{"context", names.Context},
+		} {
+			if err := claim(cluster, entry.kind, entry.name); err != nil {
+				return err
+			}
+		}
+		if cluster.Active {
+			if err := claim(cluster, "context", cluster.Environment); err != nil {
+				return err
+			}
+		}
+	}
+
+	for i, cluster := range clusters {
+		context := n.add(kubeConfig, cluster, all[i])
+		if cluster.Active {
+			kubeConfig.Contexts[cluster.Environment] = Alias(context, cluster)
+		}
+	}
+	return nil
+}
+
+// AddCluster adds a cluster, user and context for a cluster to a kubeconfig
+// and returns the context. Each of them is annotated with an Extension.
+// Entries previously written for the cluster under other names, because the
+// naming templates have changed, are removed, and the current context follows
+// its renamed context. It errors if one of the names is taken by an entry
+// written for another cluster.
+func (n Naming) AddCluster(kubeConfig *clientcmdapi.Config, cluster model.Cluster) (*clientcmdapi.Context, error) {
+	names, err := n.Names(cluster)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkNames(kubeConfig, cluster.ID, names, nil); err != nil {
+		return nil, err
+	}
+	return n.add(kubeConfig, cluster, names), nil
+}
+
+// add writes the entries of a cluster under the given names, and removes the
+// entries previously written for it under other names.
+func (n Naming) add(kubeConfig *clientcmdapi.Config, cluster model.Cluster, names Names) *clientcmdapi.Context {
+	rename(kubeConfig, cluster.ID, names)
+
+	c := NewCluster(cluster)
+	annotate(c.Extensions, cluster, false)
+	kubeConfig.Clusters[names.Cluster] = c
+
+	user := NewUser(cluster.ID, cluster.Environment)
+	annotate(user.Extensions, cluster, false)
+	kubeConfig.AuthInfos[names.User] = user
+
+	context := NewContext(names.Cluster, names.User)
+	context.Namespace = n.Namespaces[cluster.Environment]
+	annotate(context.Extensions, cluster, false)
+	kubeConfig.Contexts[names.Context] = context
+
+	return context
+}
+
+// checkNames errors if any of the names of a cluster's entries is taken by an
+// entry written for another cluster. Entries of the clusters being merged are
+// ignored, as they are renamed by the merge.
+func checkNames(kubeConfig *clientcmdapi.Config, id string, names Names, merging map[string]bool) error {
+	taken := func(kind, name string, extensions map[string]runtime.Object) error {
+		ext, ok := Managed(extensions)
+		if !ok || ext.Alias || ext.ClusterID == id || merging[ext.ClusterID] {
+			return nil
+		}
+		return duplicateName(ext.ClusterID, id, kind, name)
+	}
+
+	if c, ok := kubeConfig.Clusters[names.Cluster]; ok {
+		if err := taken("cluster", names.Cluster, c.Extensions); err != nil {
+			return err
+		}
+	}
+	if u, ok := kubeConfig.AuthInfos[names.User]; ok {
+		if err := taken("user", names.User, u.Extensions); err != nil {
+			return err
+		}
+	}
+	if c, ok := kubeConfig.Contexts[names.Context]; ok {
+		if err := taken("context", names.Context, c.Extensions); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// duplicateName returns the error for two clusters whose entries the naming
+// templates give the same name.
+func duplicateName(first, second, kind, name string) error {
+	return fmt.Errorf("clusters %s and %s are both given the %s name %s, change the naming templates so that every cluster is named differently", first, second, kind, name)
+}
+
+// Alias returns a copy of a cluster's context to add under another name, such
+// as the name of its environment. Aliases are never renamed, but are pointed
+// at the cluster's entries when those are.
+func Alias(context *clientcmdapi.Context, cluster model.Cluster) *clientcmdapi.Context {
+	alias := context.DeepCopy()
+	annotate(alias.Extensions, cluster, true)
+	return alias
+}
+
+// Region returns the AWS region of a cluster, taken from the ARN of an EKS
+// cluster or from its server URL. It is empty if neither contains a region.
+func Region(cluster model.Cluster) string {
+	if a, err := arn.Parse(cluster.SourceRef); err == nil && a.Region != "" {
+		return a.Region
+	}
+	return regionPattern.FindString(cluster.ServerURL)
+}
+
+// shortRegion abbreviates an AWS region, so that "us-west-2" becomes "usw2"
+// and "ap-southeast-1" becomes "apse1".
+func shortRegion(region string) string {
+	parts := strings.Split(region, "-")
+	for i, part := range parts[1:] {
+		if abbreviation, ok := regionAbbreviations[part]; ok {
+			parts[i+1] = abbreviation
+		}
+	}
+	return strings.Join(parts, "")
+}
+
+func newTemplate(kind, text string) (*template.Template, error) {
+	return template.New(kind).Funcs(template.FuncMap{"short": shortRegion}).Parse(text)
+}
+
+// rename removes the entries written for a cluster that aren't named as given,
+// and points the cluster's aliases at its entries under their new names.
+func rename(kubeConfig *clientcmdapi.Config, id string, names Names) {
+	stale := func(name, want string, ext Extension, ok bool) bool {
+		return ok && ext.ClusterID == id && !ext.Alias && name != want
+	}
+
+	for name, cluster := range kubeConfig.Clusters {
+		if ext, ok := Managed(cluster.Extensions); stale(name, names.Cluster, ext, ok) {
+			delete(kubeConfig.Clusters, name)
+		}
+	}
+	for name, user := range kubeConfig.AuthInfos {
+		if ext, ok := Managed(user.Extensions); stale(name, names.User, ext, ok) {
+			delete(kubeConfig.AuthInfos, name)
+		}
+	}
+	for name, context := range kubeConfig.Contexts {
+		ext, ok := Managed(context.Extensions)
+		if ok && ext.ClusterID == id && ext.Alias {
+			context.Cluster = names.Cluster
+			context.AuthInfo = names.User
+			continue
+		}
+		if !stale(name, names.Context, ext, ok) {
+			continue
+		}
+		delete(kubeConfig.Contexts, name)
+		if kubeConfig.CurrentContext == name {
+			kubeConfig.CurrentContext = names.Context
+		}
+	}
+}
+
+// withDefaults fills in empty templates from DefaultNaming.
+func (n Naming) withDefaults() Naming {
+	if n.Cluster == "" {
+		n.Cluster = DefaultNaming.Cluster
+	}
+	if n.User == "" {
+		n.User = DefaultNaming.User
+	}
+	if n.Context == "" {
+		n.Context = DefaultNaming.Context
+	}
+	return n
+}
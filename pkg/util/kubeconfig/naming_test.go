@@ -0,0 +1,160 @@
+package kubeconfig
+
+import (
+	"testing"
+
+	"github.com/lob/pharos/pkg/util/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestNaming(t *testing.T) {
+	cluster := model.Cluster{
+		ID:          "production-111111",
+		Environment: "production",
+		ServerURL:   "https://ABCDEF.yl4.us-west-2.eks.amazonaws.com",
+		Active:      true,
+	}
+	naming := Naming{
+		Cluster:    "{{.Environment}}-{{short .Region}}",
+		Context:    "{{.Environment}}-{{short .Region}}",
+		Namespaces: map[string]string{"production": "web"},
+	}
+
+	t.Run("names entries with the templates and defaults", func(tt *testing.T) {
+		names, err := naming.Names(cluster)
+		require.NoError(tt, err)
+		assert.Equal(tt, Names{Cluster: "production-usw2", User: "iam-production-111111", Context: "production-usw2"}, names)
+
+		names, err = DefaultNaming.Names(cluster)
+		require.NoError(tt, err)
+		assert.Equal(tt, Names{Cluster: "production-111111", User: "iam-production-111111", Context: "production-111111"}, names)
+	})
+
+	t.Run("takes the region from the ARN of EKS clusters", func(tt *testing.T) {
+		c := cluster
+		c.SourceRef = "arn:aws:eks:ap-southeast-1:123456789012:cluster/production-111111"
+		assert.Equal(tt, "ap-southeast-1", Region(c))
+		assert.Equal(tt, "apse1", shortRegion(Region(c)))
+	})
+
+	t.Run("sets the default namespace of the environment", func(tt *testing.T) {
+		kubeConfig, err := naming.New([]model.Cluster{cluster})
+		require.NoError(tt, err)
+
+		require.Contains(tt, kubeConfig.Contexts, "production-usw2")
+		assert.Equal(tt, "production-usw2", kubeConfig.Contexts["production-usw2"].Cluster)
+		assert.Equal(tt, "iam-production-111111", kubeConfig.Contexts["production-usw2"].AuthInfo)
+		assert.Equal(tt, "web", kubeConfig.Contexts["production-usw2"].Namespace)
+		assert.Equal(tt, "web", kubeConfig.Contexts["production"].Namespace)
+	})
+
+	t.Run("renames managed entries when the templates change", func(tt *testing.T) {
+		kubeConfig, err := DefaultNaming.New([]model.Cluster{cluster})
+		require.NoError(tt, err)
+		kubeConfig.CurrentContext = "production-111111"
+		kubeConfig.Clusters["unmanaged"] = clientcmdapi.NewCluster()
+
+		err = naming.Merge(kubeConfig, []model.Cluster{cluster})
+		require.NoError(tt, err)
+
+		assert.NotContains(tt, kubeConfig.Clusters, "production-111111")
+		assert.Contains(tt, kubeConfig.Clusters, "production-usw2")
+		assert.Contains(tt, kubeConfig.Clusters, "unmanaged")
+		assert.NotContains(tt, kubeConfig.Contexts, "production-111111")
+		assert.Contains(tt, kubeConfig.Contexts, "production-usw2")
+		assert.Equal(tt, "production-usw2", kubeConfig.Contexts["production"].Cluster)
+		assert.Equal(tt, "production-usw2", kubeConfig.CurrentContext)
+	})
+
+	t.Run("points the aliases of renamed clusters at their new entries", func(tt *testing.T) {
+		inactive := model.Cluster{
+			ID:          "production-222222",
+			Environment: "production",
+			ServerURL:   "https://GHIJKL.yl4.us-east-1.eks.amazonaws.com",
+		}
+		kubeConfig, err := DefaultNaming.New([]model.Cluster{cluster, inactive})
+		require.NoError(tt, err)
+
+		// Aliases added for an inactive cluster, such as the ones added by
+		// resolving its ID, aren't rewritten by merging.
+		context, err := DefaultNaming.AddCluster(kubeConfig, inactive)
+		require.NoError(tt, err)
+		kubeConfig.Contexts["legacy"] = Alias(context, inactive)
+
+		err = naming.Merge(kubeConfig, []model.Cluster{cluster, inactive})
+		require.NoError(tt, err)
+
+		assert.Equal(tt, "production-use1", kubeConfig.Contexts["legacy"].Cluster)
+		assert.Equal(tt, "iam-production-222222", kubeConfig.Contexts["legacy"].AuthInfo)
+		assert.Equal(tt, "production-usw2", kubeConfig.Contexts["production"].Cluster)
+		assert.NoError(tt, clientcmd.Validate(*kubeConfig))
+
+		// Aliases follow clusters renamed one at a time too.
+		_, err = Naming{Cluster: "renamed-{{.ID}}", User: "renamed-{{.ID}}"}.AddCluster(kubeConfig, inactive)
+		require.NoError(tt, err)
+		assert.Equal(tt, "renamed-production-222222", kubeConfig.Contexts["legacy"].Cluster)
+		assert.Equal(tt, "renamed-production-222222", kubeConfig.Contexts["legacy"].AuthInfo)
+		assert.NoError(tt, clientcmd.Validate(*kubeConfig))
+	})
+
+	t.Run("errors when clusters are given the same name", func(tt *testing.T) {
+		other := model.Cluster{
+			ID:          "production-222222",
+			Environment: "production",
+			ServerURL:   "https://GHIJKL.yl4.us-west-2.eks.amazonaws.com",
+		}
+
+		kubeConfig := clientcmdapi.NewConfig()
+		err := naming.Merge(kubeConfig, []model.Cluster{cluster, other})
+		require.Error(tt, err)
+		assert.Contains(tt, err.Error(), "clusters production-111111 and production-222222 are both given the cluster name production-usw2")
+		assert.Empty(tt, kubeConfig.Clusters)
+	})
+
+	t.Run("errors when a name is taken by another cluster's entry", func(tt *testing.T) {
+		other := model.Cluster{
+			ID:          "production-222222",
+			Environment: "production",
+			ServerURL:   "https://GHIJKL.yl4.us-west-2.eks.amazonaws.com",
+		}
+
+		kubeConfig, err := naming.New([]model.Cluster{cluster})
+		require.NoError(tt, err)
+
+		err = naming.Merge(kubeConfig, []model.Cluster{other})
+		require.Error(tt, err)
+		assert.Contains(tt, err.Error(), "clusters production-111111 and production-222222")
+
+		_, err = naming.AddCluster(kubeConfig, other)
+		require.Error(tt, err)
+		assert.Contains(tt, err.Error(), "clusters production-111111 and production-222222")
+		assert.Equal(tt, "https://ABCDEF.yl4.us-west-2.eks.amazonaws.com", kubeConfig.Clusters["production-usw2"].Server)
+	})
+
+	t.Run("renames clusters that swap names", func(tt *testing.T) {
+		other := model.Cluster{ID: "production-222222", Environment: "production"}
+		swapped := Naming{Context: "{{if eq .ID \"production-111111\"}}second{{else}}first{{end}}"}
+		original := Naming{Context: "{{if eq .ID \"production-111111\"}}first{{else}}second{{end}}"}
+
+		kubeConfig, err := original.New([]model.Cluster{cluster, other})
+		require.NoError(tt, err)
+
+		err = swapped.Merge(kubeConfig, []model.Cluster{cluster, other})
+		require.NoError(tt, err)
+		assert.Equal(tt, "iam-production-111111", kubeConfig.Contexts["second"].AuthInfo)
+		assert.Equal(tt, "iam-production-222222", kubeConfig.Contexts["first"].AuthInfo)
+	})
+
+	t.Run("errors on invalid templates", func(tt *testing.T) {
+		invalid := Naming{Context: "{{.Cluster}}"}
+		_, err := invalid.Names(cluster)
+		assert.Error(tt, err)
+		assert.Contains(tt, err.Error(), "unable to name context of cluster production-111111")
+
+		invalid = Naming{Cluster: "{{.ID"}
+		assert.Error(tt, invalid.Validate())
+	})
+}
@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+// Actions recorded in the audit log.
+const (
+	AuditClusterExpired = "cluster.expired"
+)
+
+// AuditEntry records a change made to a cluster by Pharos itself rather than
+// by a request, such as the deletion of an expired cluster.
+type AuditEntry struct {
+	ID          int64     `json:"id"`
+	ClusterID   string    `json:"cluster_id"`
+	Action      string    `json:"action"`
+	Actor       string    `json:"actor"`
+	Details     string    `json:"details,omitempty"`
+	DateCreated time.Time `json:"date_created"`
+}
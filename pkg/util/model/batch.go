@@ -0,0 +1,45 @@
+package model
+
+import "time"
+
+// Actions of the operations in a batch of cluster changes.
+const (
+	BatchCreate   = "create"
+	BatchDelete   = "delete"
+	BatchActivate = "activate"
+)
+
+// MaxBatchOperations is the most operations a single batch can contain.
+const MaxBatchOperations = 100
+
+// BatchOperation is a single operation in a batch of cluster changes. Create
+// operations describe the cluster to create, while delete and activate
+// operations only need its ID. If Version isn't 0, the cluster is only changed
+// if its resource version still matches.
+type BatchOperation struct {
+	Action               string     `json:"action"`
+	ID                   string     `json:"id"`
+	Environment          string     `json:"environment,omitempty"`
+	ServerURL            string     `json:"server_url,omitempty"`
+	ClusterAuthorityData string     `json:"cluster_authority_data,omitempty"`
+	ExpiresAt            *time.Time `json:"expires_at,omitempty"`
+	Version              int64      `json:"version,omitempty"`
+}
+
+// BatchResult is the result of a batch of cluster changes. The operations are
+// made in order in a single transaction, so either every one of them is
+// applied, or none are and Results ends with the operation that failed.
+type BatchResult struct {
+	Applied bool                   `json:"applied"`
+	Results []BatchOperationResult `json:"results"`
+}
+
+// BatchOperationResult is the result of a single operation in a batch. It has
+// the changed cluster if the operation succeeded, or the error it failed with.
+type BatchOperationResult struct {
+	Action     string   `json:"action"`
+	ID         string   `json:"id"`
+	StatusCode int      `json:"status_code"`
+	Cluster    *Cluster `json:"cluster,omitempty"`
+	Error      *Error   `json:"error,omitempty"`
+}
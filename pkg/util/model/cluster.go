@@ -1,6 +1,15 @@
 package model
 
-import "time"
+import (
+	"strings"
+	"time"
+)
+
+// Sources that clusters can be registered from.
+const (
+	SourceManual = "manual"
+	SourceEKS    = "eks"
+)
 
 // Cluster contains a single cluster object returned from
 // the pharos API server.
@@ -13,4 +22,39 @@ type Cluster struct {
 	Active               bool      `json:"active" sql:",notnull"`
 	DateCreated          time.Time `json:"date_created"`
 	DateModified         time.Time `json:"date_modified"`
+
+	// CAExpiresAt is the earliest expiry of the certificates in the cluster
+	// authority data. It is nil if the data doesn't contain any certificates.
+	CAExpiresAt *time.Time `json:"ca_expires_at"`
+
+	// ExpiresAt is when the cluster is deleted automatically, for clusters that
+	// are only meant to live for a while. It is nil for clusters that don't
+	// expire.
+	ExpiresAt *time.Time `json:"expires_at"`
+
+	// Source records how the cluster was registered. SourceRef identifies the
+	// cluster in that source, such as the ARN of an EKS cluster, and Missing is
+	// set when the cluster can no longer be found there.
+	Source            string `json:"source"`
+	SourceRef         string `json:"source_ref,omitempty"`
+	KubernetesVersion string `json:"kubernetes_version,omitempty"`
+	Missing           bool   `json:"missing" sql:",notnull"`
+
+	// ResourceVersion increases every time the cluster is created or changed.
+	// It is unique across all clusters.
+	ResourceVersion int64 `json:"resource_version"`
+
+	// Status is the most recent health check result for the cluster. It is
+	// only populated when listing clusters.
+	Status *ClusterStatus `json:"status,omitempty" sql:"-"`
+}
+
+// EnvironmentFromID returns the environment of a cluster ID of the form
+// "[environment]-[suffix]". IDs without a suffix are their own environment.
+func EnvironmentFromID(id string) string {
+	i := strings.LastIndex(id, "-")
+	if i <= 0 {
+		return id
+	}
+	return id[:i]
 }
@@ -0,0 +1,22 @@
+package model
+
+import "time"
+
+// Reachability statuses recorded by the pharos-api-server health checker.
+const (
+	StatusReachable   = "reachable"
+	StatusUnreachable = "unreachable"
+)
+
+// ClusterStatus contains the result of a single reachability check made
+// against the API server of a cluster.
+type ClusterStatus struct {
+	ID                int64      `json:"-"`
+	ClusterID         string     `json:"cluster_id"`
+	Status            string     `json:"status"`
+	LatencyMS         int64      `json:"latency_ms" sql:",notnull"`
+	KubernetesVersion string     `json:"kubernetes_version,omitempty"`
+	Error             string     `json:"error,omitempty"`
+	LastSeen          *time.Time `json:"last_seen"`
+	DateChecked       time.Time  `json:"date_checked"`
+}
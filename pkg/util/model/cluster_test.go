@@ -0,0 +1,23 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnvironmentFromID(t *testing.T) {
+	cases := []struct {
+		id, environment string
+	}{
+		{"sandbox-111111", "sandbox"},
+		{"production-6906ce", "production"},
+		{"platform-postmasters-777777", "platform-postmasters"},
+		{"sandbox", "sandbox"},
+		{"-111111", "-111111"},
+	}
+
+	for _, tc := range cases {
+		assert.Equal(t, tc.environment, EnvironmentFromID(tc.id), tc.id)
+	}
+}
@@ -0,0 +1,15 @@
+package model
+
+// DiscoveryResult describes the changes made, or that would be made during a
+// dry run, by a cluster discovery run.
+type DiscoveryResult struct {
+	DryRun    bool      `json:"dry_run"`
+	Created   []Cluster `json:"created"`
+	Updated   []Cluster `json:"updated"`
+	Unchanged []Cluster `json:"unchanged"`
+	Missing   []Cluster `json:"missing"`
+
+	// Errors contains the errors of sources that could not be queried. Clusters
+	// are never flagged as missing when any source fails.
+	Errors []string `json:"errors,omitempty"`
+}
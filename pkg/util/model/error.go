@@ -0,0 +1,58 @@
+package model
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Machine-readable codes of the errors returned by the Pharos API.
+const (
+	CodeClusterNotFound        = "cluster_not_found"
+	CodeClusterExists          = "cluster_exists"
+	CodeStatusNotFound         = "status_not_found"
+	CodeNoActiveCluster        = "no_active_cluster"
+	CodeMultipleActiveClusters = "multiple_active_clusters"
+	CodeActiveClusterExists    = "active_cluster_exists"
+	CodeWebhookNotFound        = "webhook_not_found"
+	CodeValidationFailed       = "validation_failed"
+	CodeUnauthorized           = "unauthorized"
+	CodeForbidden              = "forbidden"
+	CodeNotFound               = "not_found"
+	CodeConflict               = "conflict"
+	CodePreconditionFailed     = "precondition_failed"
+	CodeUnavailable            = "unavailable"
+	CodeInternal               = "internal_error"
+)
+
+// Error is an error returned by the Pharos API, inside an "error" object.
+type Error struct {
+	Code       string `json:"code"`
+	Message    string `json:"message"`
+	StatusCode int    `json:"status_code"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s (%d)", e.Message, e.StatusCode)
+}
+
+// CodeForStatus returns the code of an error with the given HTTP status that
+// doesn't have a more specific code.
+func CodeForStatus(status int) string {
+	switch status {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity, http.StatusUnsupportedMediaType:
+		return CodeValidationFailed
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusNotFound, http.StatusMethodNotAllowed:
+		return CodeNotFound
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusPreconditionFailed:
+		return CodePreconditionFailed
+	case http.StatusServiceUnavailable:
+		return CodeUnavailable
+	}
+	return CodeInternal
+}
@@ -0,0 +1,56 @@
+package model
+
+import "time"
+
+// Cluster lifecycle events that webhooks can subscribe to.
+const (
+	EventClusterCreated   = "cluster.created"
+	EventClusterDeleted   = "cluster.deleted"
+	EventClusterActivated = "cluster.activated"
+)
+
+// Events lists every event that webhooks can subscribe to.
+var Events = []string{EventClusterCreated, EventClusterDeleted, EventClusterActivated}
+
+// Webhook is a subscription to cluster lifecycle events. Events are POSTed to
+// its URL, signed with its secret.
+type Webhook struct {
+	ID          int64     `json:"id"`
+	URL         string    `json:"url"`
+	Events      []string  `json:"events" sql:",array"`
+	Secret      string    `json:"secret,omitempty"`
+	DateCreated time.Time `json:"date_created"`
+}
+
+// WebhookEvent is the payload delivered to webhooks.
+type WebhookEvent struct {
+	Event       string    `json:"event"`
+	Cluster     Cluster   `json:"cluster"`
+	DateCreated time.Time `json:"date_created"`
+}
+
+// WebhookDelivery is an event waiting to be delivered to a webhook. Failed
+// deliveries are retried at NextAttempt.
+type WebhookDelivery struct {
+	ID          int64     `json:"id"`
+	WebhookID   int64     `json:"webhook_id"`
+	Event       string    `json:"event"`
+	Payload     string    `json:"payload"`
+	Attempts    int       `json:"attempts" sql:",notnull"`
+	LastError   string    `json:"last_error,omitempty"`
+	NextAttempt time.Time `json:"next_attempt"`
+	DateCreated time.Time `json:"date_created"`
+}
+
+// WebhookDeadLetter is an event that could not be delivered to a webhook
+// after the maximum number of attempts.
+type WebhookDeadLetter struct {
+	ID          int64     `json:"id"`
+	WebhookID   int64     `json:"webhook_id"`
+	Event       string    `json:"event"`
+	Payload     string    `json:"payload"`
+	Attempts    int       `json:"attempts" sql:",notnull"`
+	LastError   string    `json:"last_error,omitempty"`
+	DateCreated time.Time `json:"date_created"`
+	DateFailed  time.Time `json:"date_failed"`
+}
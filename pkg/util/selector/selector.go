@@ -0,0 +1,94 @@
+// Package selector parses selectors, such as "environment=sandbox,active=true",
+// that pick a set of clusters by their attributes.
+package selector
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/lob/pharos/pkg/util/model"
+)
+
+// Selector maps cluster attributes to the values they must have.
+type Selector map[string]string
+
+// keys are the cluster attributes that can be selected on.
+var keys = map[string]bool{
+	"id":          true,
+	"environment": true,
+	"active":      true,
+	"source":      true,
+	"missing":     true,
+}
+
+// Parse parses a comma separated list of key=value requirements. The supported
+// keys are id, environment, active, source and missing.
+func Parse(s string) (Selector, error) {
+	sel := make(Selector)
+	for _, requirement := range strings.Split(s, ",") {
+		requirement = strings.TrimSpace(requirement)
+		if requirement == "" {
+			continue
+		}
+
+		parts := strings.SplitN(requirement, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid selector requirement %q, expected key=value", requirement)
+		}
+		key, value := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+		if !keys[key] {
+			return nil, fmt.Errorf("unsupported selector key %q", key)
+		}
+		if key == "active" || key == "missing" {
+			if _, err := strconv.ParseBool(value); err != nil {
+				return nil, fmt.Errorf("selector key %q must be true or false", key)
+			}
+		}
+		sel[key] = value
+	}
+
+	if len(sel) == 0 {
+		return nil, fmt.Errorf("selector %q is empty", s)
+	}
+	return sel, nil
+}
+
+// Matches reports whether a cluster meets every requirement of the selector.
+func (s Selector) Matches(cluster model.Cluster) bool {
+	for key, value := range s {
+		var actual string
+		switch key {
+		case "id":
+			actual = cluster.ID
+		case "environment":
+			actual = cluster.Environment
+		case "active":
+			actual = strconv.FormatBool(cluster.Active)
+		case "source":
+			actual = cluster.Source
+		case "missing":
+			actual = strconv.FormatBool(cluster.Missing)
+		}
+
+		if key == "active" || key == "missing" {
+			want, _ := strconv.ParseBool(value)
+			value = strconv.FormatBool(want)
+		}
+		if actual != value {
+			return false
+		}
+	}
+	return true
+}
+
+// Filter returns the clusters that match the selector.
+func (s Selector) Filter(clusters []model.Cluster) []model.Cluster {
+	matched := make([]model.Cluster, 0, len(clusters))
+	for _, cluster := range clusters {
+		if s.Matches(cluster) {
+			matched = append(matched, cluster)
+		}
+	}
+	return matched
+}
@@ -0,0 +1,59 @@
+package selector
+
+import (
+	"testing"
+
+	"github.com/lob/pharos/pkg/util/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	t.Run("parses requirements", func(tt *testing.T) {
+		sel, err := Parse("environment=sandbox, active=true")
+		assert.NoError(tt, err)
+		assert.Equal(tt, Selector{"environment": "sandbox", "active": "true"}, sel)
+	})
+
+	t.Run("errors with invalid selectors", func(tt *testing.T) {
+		cases := []struct {
+			selector, errorMessage string
+		}{
+			{"", "is empty"},
+			{"environment", "expected key=value"},
+			{"region=us-west-2", "unsupported selector key"},
+			{"active=yes", "must be true or false"},
+		}
+
+		for _, tc := range cases {
+			_, err := Parse(tc.selector)
+			assert.Error(tt, err)
+			assert.Contains(tt, err.Error(), tc.errorMessage)
+		}
+	})
+}
+
+func TestFilter(t *testing.T) {
+	clusters := []model.Cluster{
+		{ID: "sandbox-111111", Environment: "sandbox", Active: true, Source: model.SourceEKS},
+		{ID: "sandbox-222222", Environment: "sandbox", Source: model.SourceManual},
+		{ID: "staging-333333", Environment: "staging", Active: true, Source: model.SourceManual},
+	}
+
+	t.Run("filters clusters matching every requirement", func(tt *testing.T) {
+		sel, err := Parse("environment=sandbox,active=1")
+		require.NoError(tt, err)
+
+		matched := sel.Filter(clusters)
+		require.Len(tt, matched, 1)
+		assert.Equal(tt, "sandbox-111111", matched[0].ID)
+	})
+
+	t.Run("filters clusters by source", func(tt *testing.T) {
+		sel, err := Parse("source=manual")
+		require.NoError(tt, err)
+
+		matched := sel.Filter(clusters)
+		assert.Len(tt, matched, 2)
+	})
+}
@@ -0,0 +1,58 @@
+// Package version compares the release versions of Pharos, such as "v1.4.2",
+// so that the API can tell the CLI which of its releases are still supported.
+package version
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Headers the CLI and the API use to report their versions to each other.
+const (
+	HeaderCLIVersion        = "Pharos-CLI-Version"
+	HeaderMinimumCLIVersion = "Pharos-Minimum-CLI-Version"
+)
+
+// Version is a parsed major.minor.patch release version.
+type Version [3]int
+
+// Parse parses a version such as "1.4.2" or "v1.4", where missing parts are 0.
+// Pre-release and build suffixes, such as "-rc.1", are ignored.
+func Parse(s string) (Version, error) {
+	var v Version
+
+	trimmed := strings.TrimPrefix(strings.TrimSpace(s), "v")
+	if i := strings.IndexAny(trimmed, "-+"); i >= 0 {
+		trimmed = trimmed[:i]
+	}
+
+	parts := strings.Split(trimmed, ".")
+	if len(parts) > len(v) {
+		return v, fmt.Errorf("invalid version %q, expected major.minor.patch", s)
+	}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return v, fmt.Errorf("invalid version %q, expected major.minor.patch", s)
+		}
+		v[i] = n
+	}
+
+	return v, nil
+}
+
+// Less returns whether v is an earlier version than other.
+func (v Version) Less(other Version) bool {
+	for i := range v {
+		if v[i] != other[i] {
+			return v[i] < other[i]
+		}
+	}
+	return false
+}
+
+// String returns the version as major.minor.patch.
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d.%d", v[0], v[1], v[2])
+}
@@ -0,0 +1,46 @@
+package version
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	t.Run("parses versions", func(tt *testing.T) {
+		cases := []struct {
+			version string
+			parsed  Version
+		}{
+			{"1.4.2", Version{1, 4, 2}},
+			{"v1.4.2", Version{1, 4, 2}},
+			{"v2.1", Version{2, 1, 0}},
+			{"3", Version{3, 0, 0}},
+			{"1.5.0-rc.1", Version{1, 5, 0}},
+		}
+
+		for _, tc := range cases {
+			v, err := Parse(tc.version)
+			assert.NoError(tt, err)
+			assert.Equal(tt, tc.parsed, v)
+		}
+	})
+
+	t.Run("errors with invalid versions", func(tt *testing.T) {
+		for _, s := range []string{"", "latest", "1.2.3.4", "1.-2"} {
+			_, err := Parse(s)
+			assert.Error(tt, err)
+			assert.Contains(tt, err.Error(), "expected major.minor.patch")
+		}
+	})
+}
+
+func TestLess(t *testing.T) {
+	t.Run("compares versions part by part", func(tt *testing.T) {
+		assert.True(tt, Version{1, 4, 2}.Less(Version{1, 5, 0}))
+		assert.True(tt, Version{1, 9, 9}.Less(Version{2, 0, 0}))
+		assert.True(tt, Version{1, 4, 1}.Less(Version{1, 4, 2}))
+		assert.False(tt, Version{1, 4, 2}.Less(Version{1, 4, 2}))
+		assert.False(tt, Version{1, 10, 0}.Less(Version{1, 9, 0}))
+	})
+}